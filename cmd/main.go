@@ -21,9 +21,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
@@ -31,7 +33,10 @@ import (
 	"openseawave.com/rasbora/internal/data"
 	"openseawave.com/rasbora/internal/database"
 	"openseawave.com/rasbora/internal/filesystem"
+	"openseawave.com/rasbora/internal/health"
 	"openseawave.com/rasbora/internal/logger"
+	"openseawave.com/rasbora/internal/metrics"
+	"openseawave.com/rasbora/internal/tracing"
 	"openseawave.com/rasbora/internal/utilities"
 	"openseawave.com/rasbora/src/callbacks"
 	"openseawave.com/rasbora/src/heartbeat"
@@ -84,6 +89,13 @@ func main() {
 		"active_components": activeComponents,
 	})
 
+	initInternalMetrics(activeComponents)
+
+	initInternalNodeListener(activeComponents)
+
+	stopTracing := initInternalTracing()
+	defer stopTracing()
+
 	for _, component := range activeComponents {
 
 		//start callback manager component
@@ -340,10 +352,19 @@ func initInternalDatabaseConnection() {
 			os.Exit(1)
 		}
 
+		// blocking reads such as watching task progress use their own connections, so they never exhaust pool of rds.
+		blockingRds := redis.NewClient(&redis.Options{
+			Addr:     cfg.GetString("Database.Redis.Connection.Address"),
+			Password: cfg.GetString("Database.Redis.Connection.Password"),
+			DB:       cfg.GetInt("Database.Redis.Connection.DatabaseIndex"),
+			PoolSize: cfg.GetInt("Database.Redis.Connection.BlockingPoolSize"),
+		})
+
 		db = database.New(&database.RedisDatabaseManager{
-			Redis:  rds,
-			Config: cfg,
-			Logger: log,
+			Redis:         rds,
+			BlockingRedis: blockingRds,
+			Config:        cfg,
+			Logger:        log,
 		})
 
 		l.Success(
@@ -461,6 +482,111 @@ func initInternalFileSystem() {
 	os.Exit(1)
 }
 
+// initInternalMetrics register queue depth collector, queue depth is collected by task manager nodes.
+func initInternalMetrics(activeComponents []string) {
+	if utilities.InSlice(taskmanager.Name, activeComponents) {
+		if err := metrics.RegisterQueueDepth(
+			db,
+			cfg.GetString("Components.VideoTranscoding.Queue"),
+			cfg.GetString("Components.CallbackManager.Queue"),
+		); err != nil {
+			log.Warn(
+				"main.init.metrics",
+				fmt.Sprintf("cannot collect queue depth: %v", err.Error()),
+				nil,
+			)
+		}
+	}
+}
+
+// initInternalNodeListener serve health, readiness, version and metrics of this node on dedicated listener.
+func initInternalNodeListener(activeComponents []string) {
+	health.SetVersion(health.Version{
+		Version:   Version,
+		GitHash:   GitHash,
+		BuildTime: BuildTime,
+		Support:   Support,
+	})
+
+	if staleAfter := cfg.GetInt("NodeListener.LoopStaleAfter"); staleAfter > 0 {
+		health.MinLoopStaleAfter = time.Duration(staleAfter) * time.Second
+	}
+
+	health.AddCheck("redis", db.Ping)
+
+	if cfg.GetString("Filesystem.Type") == data.ObjectFileSystemType.String() {
+		health.AddCheck("object_storage", func(ctx context.Context) error {
+			minioClient, err := filesystem.NewObjectClient(*cfg)
+			if err != nil {
+				return err
+			}
+			_, err = minioClient.ListBuckets(ctx)
+			return err
+		})
+	}
+
+	if utilities.InSlice(videotranscoder.Name, activeComponents) {
+		health.AddCheck("ffmpeg", health.Executable(cfg.GetString("Components.VideoTranscoding.Engine.Ffmpeg.Executable")))
+	}
+
+	mux := http.NewServeMux()
+	health.Register(mux)
+	if cfg.GetBool("Metrics.Enabled") {
+		mux.Handle("/metrics", metrics.Handler())
+	}
+
+	address := cfg.GetString("NodeListener.Address")
+
+	go func() {
+		if err := http.ListenAndServe(address, mux); err != nil {
+			log.Error(
+				"main.init.node_listener",
+				fmt.Sprintf("node listener stopped: %v", err.Error()),
+				map[string]interface{}{
+					"node_listener_address": address,
+				},
+			)
+		}
+	}()
+
+	log.Success(
+		"main.init.node_listener",
+		"node listener has been started",
+		map[string]interface{}{
+			"node_listener_address": address,
+		},
+	)
+}
+
+// initInternalTracing export opentelemetry spans over otlp, returned func flushes spans not exported yet.
+func initInternalTracing() func() {
+	if !cfg.GetBool("Tracing.Enabled") {
+		return func() {}
+	}
+
+	shutdown, err := tracing.Start(ctx, cfg)
+	if err != nil {
+		log.Error(
+			"main.init.tracing",
+			fmt.Sprintf("cannot start tracing: %v", err.Error()),
+			nil,
+		)
+		return func() {}
+	}
+
+	log.Success(
+		"main.init.tracing",
+		"tracing has been started",
+		map[string]interface{}{
+			"tracing_endpoint": cfg.GetString("Tracing.Endpoint"),
+		},
+	)
+
+	return func() {
+		_ = shutdown(ctx)
+	}
+}
+
 // initInternalTaskManagerComponent  this func used to start task manager component.
 func initComponentTaskManager() {
 	log.Info(
@@ -480,9 +606,10 @@ func initComponentTaskManager() {
 		)
 
 		taskmanager.New(&taskmanager.RestfulTaskManager{
-			Config:   cfg,
-			Logger:   log,
-			Database: db,
+			Config:     cfg,
+			Logger:     log,
+			Database:   db,
+			FileSystem: fs,
 		}).StartTaskManager(ctx)
 
 		log.Success(
@@ -496,6 +623,34 @@ func initComponentTaskManager() {
 		return
 	}
 
+	if cfg.GetString("Components.TaskManagement.Active") == "Grpc" {
+
+		log.Info(
+			"main.init.task_manager_component",
+			"grpc protocol has been selected for task manager",
+			map[string]interface{}{
+				"protocol_type": "Grpc",
+			},
+		)
+
+		taskmanager.New(&taskmanager.GrpcTaskManager{
+			Config:     cfg,
+			Logger:     log,
+			Database:   db,
+			FileSystem: fs,
+		}).StartTaskManager(ctx)
+
+		log.Success(
+			"main.init.task_manager_component",
+			"has been started successfully",
+			map[string]interface{}{
+				"protocol_type": "Grpc",
+			},
+		)
+
+		return
+	}
+
 	log.Error(
 		"main.init.task_manager_component",
 		"There is no protocol founded in config file",
@@ -581,6 +736,62 @@ func initComponentCallbackManager() {
 				"callback_protocol_type": "http",
 			},
 		)
+
+		return
+	}
+
+	if cfg.GetString("Components.CallbackManager.Active") == "amqp" {
+
+		log.Info(
+			"main.init.callback_manager_component",
+			"configuring callback manager protocol",
+			map[string]interface{}{
+				"callback_protocol_type": "amqp",
+			},
+		)
+
+		callbacks.New(&callbacks.AmqpCallbackManager{
+			Config:   cfg,
+			Logger:   log,
+			Database: db,
+		}).StartCallbackManager(ctx)
+
+		log.Success(
+			"main.init.callback_manager_component",
+			"has been successfully started",
+			map[string]interface{}{
+				"callback_protocol_type": "amqp",
+			},
+		)
+
+		return
+	}
+
+	if cfg.GetString("Components.CallbackManager.Active") == "redis" {
+
+		log.Info(
+			"main.init.callback_manager_component",
+			"configuring callback manager protocol",
+			map[string]interface{}{
+				"callback_protocol_type": "redis",
+			},
+		)
+
+		callbacks.New(&callbacks.RedisCallbackManager{
+			Config:   cfg,
+			Logger:   log,
+			Database: db,
+		}).StartCallbackManager(ctx)
+
+		log.Success(
+			"main.init.callback_manager_component",
+			"has been successfully started",
+			map[string]interface{}{
+				"callback_protocol_type": "redis",
+			},
+		)
+
+		return
 	}
 
 	log.Error(
@@ -28,7 +28,9 @@ type Interface interface {
 	GetString(key string) string
 	GetBool(key string) bool
 	GetInt(key string) int
+	GetFloat64(key string) float64
 	GetIntSlice(key string) []int
+	UnmarshalKey(key string, rawVal interface{}) error
 }
 
 // New create config instance.
@@ -62,3 +64,13 @@ func (c *Config) GetBool(key string) bool {
 func (c *Config) GetInt(key string) int {
 	return c.configManager.GetInt(key)
 }
+
+// GetFloat64 get float value by config key
+func (c *Config) GetFloat64(key string) float64 {
+	return c.configManager.GetFloat64(key)
+}
+
+// UnmarshalKey decode config value by key into given struct
+func (c *Config) UnmarshalKey(key string, rawVal interface{}) error {
+	return c.configManager.UnmarshalKey(key, rawVal)
+}
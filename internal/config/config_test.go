@@ -62,6 +62,17 @@ func (m *MockConfigManager) GetInt(key string) int {
 	return 0
 }
 
+func (m *MockConfigManager) GetFloat64(key string) float64 {
+	if val, ok := m.data[key].(float64); ok {
+		return val
+	}
+	return 0
+}
+
+func (m *MockConfigManager) UnmarshalKey(key string, rawVal interface{}) error {
+	return nil
+}
+
 func TestConfig_GetIntSlice(t *testing.T) {
 	mockData := map[string]interface{}{
 		"intSliceKey": []int{1, 2, 3},
@@ -272,3 +283,15 @@ func ExampleConfig_GetInt() {
 	fmt.Println(result)
 	// Output: 42
 }
+
+func ExampleConfig_GetFloat64() {
+	mockData := map[string]interface{}{
+		"floatKey": 0.5,
+	}
+	mockConfigManager := &MockConfigManager{data: mockData}
+	config := New(mockConfigManager)
+
+	result := config.GetFloat64("floatKey")
+	fmt.Println(result)
+	// Output: 0.5
+}
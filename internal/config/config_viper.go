@@ -58,3 +58,13 @@ func (v *ViperConfigManager) GetBool(key string) bool {
 func (v *ViperConfigManager) GetInt(key string) int {
 	return v.Viper.GetInt(key)
 }
+
+// GetFloat64 get float value by config key
+func (v *ViperConfigManager) GetFloat64(key string) float64 {
+	return v.Viper.GetFloat64(key)
+}
+
+// UnmarshalKey decode config value by key into given struct
+func (v *ViperConfigManager) UnmarshalKey(key string, rawVal interface{}) error {
+	return v.Viper.UnmarshalKey(key, rawVal)
+}
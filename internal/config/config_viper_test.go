@@ -70,3 +70,27 @@ func TestViperConfigManager_GetInt(t *testing.T) {
 	expected := 42
 	assert.Equal(t, expected, result)
 }
+
+func TestViperConfigManager_GetFloat64(t *testing.T) {
+	// Initialize ViperConfigManager with a mock Viper instance
+	mockViper := viper.New()
+	mockViper.Set("key", 0.5)
+	configManager := &ViperConfigManager{Viper: mockViper}
+
+	// Test GetFloat64
+	result := configManager.GetFloat64("key")
+	expected := 0.5
+	assert.Equal(t, expected, result)
+}
+
+func TestViperConfigManager_UnmarshalKey(t *testing.T) {
+	// Initialize ViperConfigManager with a mock Viper instance
+	mockViper := viper.New()
+	mockViper.Set("key", []map[string]interface{}{{"name": "value"}})
+	configManager := &ViperConfigManager{Viper: mockViper}
+
+	// Test UnmarshalKey
+	var result []struct{ Name string }
+	assert.NoError(t, configManager.UnmarshalKey("key", &result))
+	assert.Equal(t, "value", result[0].Name)
+}
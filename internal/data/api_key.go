@@ -0,0 +1,88 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package data
+
+import "encoding/json"
+
+// ApiKeyScope holds instances
+type ApiKeyScope string
+
+const (
+	ApiKeyScopeCreate ApiKeyScope = "create"
+	ApiKeyScopeRead   ApiKeyScope = "read"
+	ApiKeyScopeCancel ApiKeyScope = "cancel"
+	ApiKeyScopeAdmin  ApiKeyScope = "admin"
+)
+
+// ApiKey holds instances
+type ApiKey struct {
+	// Unique identifier for the key, first part of the token.
+	ID string `json:"key_id"`
+
+	// Human readable name of the key owner.
+	Name string `json:"name" validate:"required"`
+
+	// Tenant owning tasks created with the key, empty means shared tenant.
+	// Only letters, digits, "_" and "-" are allowed, up to 64 characters.
+	Tenant string `json:"tenant,omitempty" validate:"omitempty,identifier,max=64"`
+
+	// Hex encoded sha256 of the key secret, the secret itself is never saved.
+	SecretHash string `json:"secret_hash,omitempty"`
+
+	// Scopes granted to the key.
+	Scopes []ApiKeyScope `json:"scopes" validate:"required,min=1,dive,oneof=create read cancel admin"`
+
+	// Handlers the key is allowed to use, empty means all handlers.
+	Handlers []string `json:"handlers"`
+
+	// Identifier of the key that created this key.
+	CreatedBy string `json:"created_by,omitempty"`
+
+	// Timestamp indicating when the key was created.
+	CreatedAt int64 `json:"created_at,omitempty"`
+
+	// Timestamp indicating when the key was revoked.
+	RevokedAt int64 `json:"revoked_at,omitempty"`
+}
+
+// HasScope check if key is granted the scope, admin is granted every scope.
+func (k ApiKey) HasScope(scope ApiKeyScope) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope || granted == ApiKeyScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsHandler check if key is allowed to use the handler.
+func (k ApiKey) AllowsHandler(handler string) bool {
+	if len(k.Handlers) == 0 {
+		return true
+	}
+	for _, allowed := range k.Handlers {
+		if allowed == handler {
+			return true
+		}
+	}
+	return false
+}
+
+func (k ApiKey) MarshalBinary() ([]byte, error) {
+	return json.Marshal(k)
+}
@@ -24,9 +24,12 @@ import (
 // Callback holds instances
 type Callback struct {
 	TaskId            interface{} `json:"task_id"`
+	Label             string      `json:"task_label"`
 	Priority          *float64    `json:"priority"`
 	Data              interface{} `json:"data"`
 	Error             bool        `json:"error"`
+	ErrorCode         ErrorCode   `json:"error_code,omitempty"`
+	Retryable         bool        `json:"retryable"`
 	Message           string      `json:"message"`
 	URL               string      `json:"url"`
 	VideoOutputFiles  []File      `json:"video_output_files"`
@@ -0,0 +1,49 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package data
+
+// ClusterWorker holds instances
+type ClusterWorker struct {
+	WorkerId      string   `json:"worker_id"`
+	WorkerType    string   `json:"worker_type"`
+	Node          string   `json:"-"`
+	LastSeen      int64    `json:"last_seen"`
+	Alive         bool     `json:"alive"`
+	CurrentTasks  []string `json:"current_tasks,omitempty"`
+	FinishedTasks int64    `json:"finished_tasks"`
+	// Radar latest entry sent by system radar worker, nil for other workers.
+	Radar *Radar `json:"-"`
+}
+
+// ClusterNode holds instances
+type ClusterNode struct {
+	Node         string          `json:"node"`
+	Alive        bool            `json:"alive"`
+	LastSeen     int64           `json:"last_seen"`
+	Components   []ClusterWorker `json:"components"`
+	Resources    *Radar          `json:"resources,omitempty"`
+	CurrentTasks []string        `json:"current_tasks"`
+	Throughput   NodeThroughput  `json:"throughput"`
+}
+
+// NodeThroughput holds instances
+type NodeThroughput struct {
+	FinishedTasks int64   `json:"finished_tasks"`
+	WindowSeconds int64   `json:"window_seconds"`
+	TasksPerHour  float64 `json:"tasks_per_hour"`
+}
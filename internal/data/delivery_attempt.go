@@ -0,0 +1,51 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package data
+
+import "encoding/json"
+
+// DeliveryAttempt holds instances
+type DeliveryAttempt struct {
+	// Number of the attempt, starting from one.
+	Attempt int `json:"attempt"`
+
+	// Worker who made the attempt.
+	WorkerId string `json:"worker_id"`
+
+	// Outcome of the attempt (succeeded, gone, retry_later, rejected, failed).
+	Outcome string `json:"outcome"`
+
+	// Status code returned by receiver, zero when receiver is unreachable.
+	StatusCode int `json:"status_code"`
+
+	// Time taken by receiver to respond (unit in milliseconds).
+	Latency int64 `json:"latency"`
+
+	// Response body returned by receiver, truncated.
+	ResponseBody string `json:"response_body,omitempty"`
+
+	// Error happened while sending.
+	Error string `json:"error,omitempty"`
+
+	// Timestamp indicating when the attempt was made.
+	AttemptedAt int64 `json:"attempted_at"`
+}
+
+func (da DeliveryAttempt) MarshalBinary() ([]byte, error) {
+	return json.Marshal(da)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package data
+
+import "errors"
+
+// ErrorCode holds instances
+type ErrorCode string
+
+const (
+	InvalidTaskErrorCode        ErrorCode = "INVALID_TASK"
+	InputNotFoundErrorCode      ErrorCode = "INPUT_NOT_FOUND"
+	InputUnreadableErrorCode    ErrorCode = "INPUT_UNREADABLE"
+	UnsupportedCodecErrorCode   ErrorCode = "UNSUPPORTED_CODEC"
+	HandlerUnknownErrorCode     ErrorCode = "HANDLER_UNKNOWN"
+	HandlerInvalidErrorCode     ErrorCode = "HANDLER_INVALID"
+	TranscodeFailedErrorCode    ErrorCode = "TRANSCODE_FAILED"
+	StorageUnavailableErrorCode ErrorCode = "STORAGE_UNAVAILABLE"
+	UploadFailedErrorCode       ErrorCode = "UPLOAD_FAILED"
+	ResourceExhaustedErrorCode  ErrorCode = "RESOURCE_EXHAUSTED"
+	TimeoutErrorCode            ErrorCode = "TIMEOUT"
+	StalledErrorCode            ErrorCode = "STALLED"
+	CancelledErrorCode          ErrorCode = "CANCELLED"
+	InternalErrorCode           ErrorCode = "INTERNAL"
+)
+
+// _permanentErrorCodes codes of failures that fail again when task is retried as it is.
+var _permanentErrorCodes = map[ErrorCode]bool{
+	InvalidTaskErrorCode:      true,
+	InputNotFoundErrorCode:    true,
+	InputUnreadableErrorCode:  true,
+	UnsupportedCodecErrorCode: true,
+	HandlerUnknownErrorCode:   true,
+	HandlerInvalidErrorCode:   true,
+	CancelledErrorCode:        true,
+}
+
+// String returns the string representation of ErrorCode.
+func (ec ErrorCode) String() string {
+	return string(ec)
+}
+
+// Retryable report whether task failed with this code may succeed when retried, unknown codes are retryable.
+func (ec ErrorCode) Retryable() bool {
+	return !_permanentErrorCodes[ec]
+}
+
+// TaskError holds instances
+type TaskError struct {
+	Code ErrorCode
+	Err  error
+}
+
+// NewTaskError wrap err with code sent in callback.
+func NewTaskError(code ErrorCode, err error) *TaskError {
+	return &TaskError{Code: code, Err: err}
+}
+
+// ClassifyError wrap err with code unless err already has a more specific code.
+func ClassifyError(code ErrorCode, err error) error {
+	if ErrorCodeOf(err) != "" {
+		return err
+	}
+	return NewTaskError(code, err)
+}
+
+func (te *TaskError) Error() string {
+	return te.Err.Error()
+}
+
+func (te *TaskError) Unwrap() error {
+	return te.Err
+}
+
+// ErrorCodeOf return code of first task error in err chain, empty when err has no code.
+func ErrorCodeOf(err error) ErrorCode {
+	var taskError *TaskError
+	if errors.As(err, &taskError) {
+		return taskError.Code
+	}
+	return ""
+}
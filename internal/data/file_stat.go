@@ -0,0 +1,27 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package data
+
+// FileStat holds instances
+type FileStat struct {
+	// Size of the file in bytes.
+	Size int64 `json:"size"`
+
+	// Timestamp indicating when the file was last modified.
+	ModifiedAt int64 `json:"modified_at"`
+}
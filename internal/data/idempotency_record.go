@@ -0,0 +1,36 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package data
+
+import "encoding/json"
+
+// IdempotencyRecord holds instances
+type IdempotencyRecord struct {
+	// Task created by the first request using the key.
+	TaskId string `json:"task_id"`
+
+	// Fingerprint of the first request body.
+	Fingerprint string `json:"fingerprint"`
+
+	// Timestamp indicating when the key was used first.
+	CreatedAt int64 `json:"created_at"`
+}
+
+func (r IdempotencyRecord) MarshalBinary() ([]byte, error) {
+	return json.Marshal(r)
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package data
+
+// PriorityChange holds instances
+type PriorityChange struct {
+	// Priority before the change.
+	PreviousPriority float64 `json:"previous_priority"`
+
+	// Priority after the change.
+	Priority float64 `json:"priority"`
+
+	// Api key id of who changed the priority.
+	ChangedBy string `json:"changed_by"`
+
+	// Timestamp indicating when the priority was changed.
+	ChangedAt int64 `json:"changed_at"`
+}
+
+// PriorityChangeRequest holds instances
+type PriorityChangeRequest struct {
+	// New priority of the task.
+	Priority *float64 `json:"task_priority" validate:"required"`
+}
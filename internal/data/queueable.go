@@ -27,6 +27,33 @@ type Queueable struct {
 	// Priority level assigned to the queue.
 	Priority float64 `json:"queue_item_priority,omitempty"`
 
+	// Tenant owning the item, items of each tenant wait in their own queue.
+	Tenant string `json:"queue_item_tenant,omitempty"`
+
+	// Timestamp when item should be moved to waiting queue, item is waiting immediately when not set.
+	RunAt int64 `json:"queue_item_run_at,omitempty"`
+
+	// Capabilities worker needs to process the item.
+	Capabilities []string `json:"queue_item_capabilities,omitempty"`
+
+	// Workers that should not take the item until given timestamp.
+	ExcludedWorkers map[string]int64 `json:"queue_item_excluded_workers,omitempty"`
+
+	// Timestamp when item was added to waiting queue, set when item is dequeued.
+	EnqueuedAt int64 `json:"queue_item_enqueued_at,omitempty"`
+
+	// Trace context of span that created the item, processing of item continues its trace.
+	TraceContext map[string]string `json:"queue_item_trace_context,omitempty"`
+
+	// Fingerprint of task as client created it, payload may be changed while item is processed.
+	Fingerprint string `json:"queue_item_fingerprint,omitempty"`
+
+	// Times item was sent back to waiting queue without counting a retry, like when worker has not enough disk space.
+	Requeues int `json:"queue_item_requeues,omitempty"`
+
+	// Code of last failure of the item.
+	ErrorCode ErrorCode `json:"queue_item_error_code,omitempty"`
+
 	// Payload to process when its dequeued.
 	Payload interface{} `json:"queue_item_payload,omitempty"`
 }
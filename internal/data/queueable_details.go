@@ -0,0 +1,51 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package data
+
+// QueueableDetails holds instances
+type QueueableDetails struct {
+	// Item saved in the queue.
+	Item Queueable `json:"item"`
+
+	// Current status of the item (delayed, waiting, working, finished, failed, cancelled).
+	Status string `json:"status"`
+
+	// Priority of waiting item after aging, lower is dequeued first.
+	EffectivePriority *float64 `json:"effective_priority,omitempty"`
+
+	// Worker currently processing the item.
+	Worker string `json:"worker,omitempty"`
+
+	// Last failure reason saved for the item.
+	Log string `json:"log,omitempty"`
+
+	// Code of last failure of the item, CANCELLED for cancelled items.
+	ErrorCode ErrorCode `json:"error_code,omitempty"`
+
+	// Whether item that failed with error code may succeed when retried, set only with error code.
+	Retryable *bool `json:"retryable,omitempty"`
+}
+
+// ProcessingEvent holds instances
+type ProcessingEvent struct {
+	// Unique identifier for the event inside processing stream.
+	ID string `json:"event_id"`
+
+	// Processing values sent by worker.
+	Values map[string]interface{} `json:"values"`
+}
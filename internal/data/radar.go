@@ -19,16 +19,62 @@ package data
 
 import "encoding/json"
 
+// RadarSchemaVersion version of radar entry schema, increased when fields change incompatibly.
+const RadarSchemaVersion = 2
+
 // Radar holds instances
 type Radar struct {
-	SystemRadarId     string      `json:"SystemRadarId"`
-	MemoryInfo        interface{} `json:"MemoryInfo"`
-	CpuInfo           interface{} `json:"CpuInfo"`
-	CpuUsageAll       interface{} `json:"CpuUsageAll"`
-	DiskUsage         interface{} `json:"DiskUsage"`
-	NetworkStat       interface{} `json:"NetworkStat"`
-	HostInfo          interface{} `json:"HostInfo"`
-	NetworkInterfaces interface{} `json:"NetworkInterfaces"`
+	SchemaVersion int          `json:"schema_version"`
+	SystemRadarId string       `json:"system_radar_id"`
+	ScannedAt     int64        `json:"scanned_at"`
+	Host          RadarHost    `json:"host"`
+	Cpu           RadarCpu     `json:"cpu"`
+	Memory        RadarMemory  `json:"memory"`
+	Disks         []RadarDisk  `json:"disks"`
+	Network       RadarNetwork `json:"network"`
+}
+
+// RadarHost holds instances
+type RadarHost struct {
+	Hostname        string `json:"hostname"`
+	Os              string `json:"os"`
+	Platform        string `json:"platform"`
+	PlatformVersion string `json:"platform_version"`
+	KernelVersion   string `json:"kernel_version"`
+	Uptime          uint64 `json:"uptime"`
+}
+
+// RadarCpu holds instances
+type RadarCpu struct {
+	Model string `json:"model"`
+	Cores int    `json:"cores"`
+	// Percent usage of each logical core.
+	Percent []float64 `json:"percent"`
+}
+
+// RadarMemory holds instances
+type RadarMemory struct {
+	Used      uint64 `json:"used"`
+	Available uint64 `json:"available"`
+	Total     uint64 `json:"total"`
+}
+
+// RadarDisk holds instances
+type RadarDisk struct {
+	Path  string `json:"path"`
+	Used  uint64 `json:"used"`
+	Free  uint64 `json:"free"`
+	Total uint64 `json:"total"`
+}
+
+// RadarNetwork holds instances
+type RadarNetwork struct {
+	// RxBytes and TxBytes counters of all interfaces except loopback.
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+	// RxBytesPerSecond and TxBytesPerSecond rates since previous scan, zero on first scan.
+	RxBytesPerSecond float64 `json:"rx_bytes_per_second"`
+	TxBytesPerSecond float64 `json:"tx_bytes_per_second"`
 }
 
 func (r Radar) MarshalBinary() ([]byte, error) {
@@ -27,9 +27,27 @@ type Task struct {
 	// Label for task.
 	Label string `json:"task_label" validate:"required"`
 
+	// Tenant owning the task, taken from the api key used to create it.
+	Tenant string `json:"tenant,omitempty"`
+
+	// Batch grouping the task, set when task created with batch endpoint.
+	BatchId string `json:"batch_id,omitempty"`
+
 	// Priority level assigned to the task.
 	Priority *float64 `json:"task_priority" validate:"required"`
 
+	// Changes of priority made while task was waiting.
+	PriorityChanges []PriorityChange `json:"priority_changes,omitempty"`
+
+	// Timestamp when task should start, task waits in delayed queue until then.
+	RunAt int64 `json:"run_at,omitempty" validate:"omitempty,min=0"`
+
+	// Seconds to wait before task starts, used when run_at is not set.
+	DelaySeconds int64 `json:"delay_seconds,omitempty" validate:"omitempty,min=0,excluded_with=RunAt"`
+
+	// Capabilities worker needs to process the task, capabilities implied by handler are added to them.
+	RequiredCapabilities []string `json:"required_capabilities,omitempty"`
+
 	// Callback struct holds details for a callback associated with the task.
 	Callback struct {
 		// URL to send callback.
@@ -0,0 +1,61 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package data
+
+// TaskBatch holds instances
+type TaskBatch struct {
+	// Group tasks under batch id, generated when empty.
+	Group bool `json:"group"`
+
+	// Unique identifier for the batch, setting it groups tasks.
+	// Only letters, digits, "_" and "-" are allowed, up to 64 characters.
+	ID string `json:"batch_id"`
+
+	// Tasks to create.
+	Tasks []Task `json:"tasks"`
+}
+
+// TaskBatchResult holds instances
+type TaskBatchResult struct {
+	// Position of the task in the batch.
+	Index int `json:"index"`
+
+	// Unique identifier of created task.
+	TaskId string `json:"task_id,omitempty"`
+
+	// Same task was created before, task id is id of the original task.
+	Replayed bool `json:"replayed,omitempty"`
+
+	// Reason task was not created.
+	Error string `json:"error,omitempty"`
+}
+
+// BatchProgress holds instances
+type BatchProgress struct {
+	// Unique identifier for the batch.
+	ID string `json:"batch_id"`
+
+	// Number of tasks in the batch.
+	Total int64 `json:"total"`
+
+	// Number of tasks in each status.
+	Statuses map[string]int64 `json:"statuses"`
+
+	// Percentage of tasks finished, failed or cancelled.
+	Percentage float64 `json:"percentage"`
+}
@@ -18,9 +18,26 @@
 package database
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"openseawave.com/rasbora/internal/data"
 )
 
+var (
+	// ErrNotFound returned when item does not exist in queue.
+	ErrNotFound = errors.New("item does not exist in queue")
+	// ErrNotCancellable returned when item is not waiting anymore.
+	ErrNotCancellable = errors.New("item cannot be cancelled in its current status")
+	// ErrNotWaiting returned when item cannot be updated because it is not waiting anymore.
+	ErrNotWaiting = errors.New("item is not waiting in queue")
+	// ErrAlreadyExists returned when item with same id already exists in queue.
+	ErrAlreadyExists = errors.New("item with same id already exists in queue")
+	// ErrActiveLimitReached returned when tenant of item already has max active items.
+	ErrActiveLimitReached = errors.New("tenant reached max active items")
+)
+
 // Database holds an instance.
 type Database struct {
 	databaseManager Interface
@@ -28,14 +45,39 @@ type Database struct {
 
 // Interface defines the methods that a database should implement.
 type Interface interface {
-	SendHeartbeat(workerId, workerType string) error
+	Ping(ctx context.Context) error
+	SendHeartbeat(node, workerId, workerType string) error
+	ClusterWorkers(queueName string, throughputWindow time.Duration, radarScanLimit int64) ([]data.ClusterWorker, error)
 	Enqueue(queueName string, item data.Queueable) error
-	Dequeue(queueName string, workerId string) (item data.Queueable, err error)
+	EnqueueUnique(queueName string, item data.Queueable, maxActive int64) error
+	EnqueueBatch(queueName string, items []data.Queueable, batch string, batchTTL time.Duration, maxActive int64) (existing []string, limited []string, err error)
+	BatchStatuses(queueName string, batch string) (map[string]int64, error)
+	QueueStatuses(queueName string) (map[string]int64, error)
+	Delay(queueName string, item data.Queueable, runAt time.Time) error
+	PromoteDelayed(queueName string) error
+	UpdateWaiting(queueName string, itemId string, update func(item *data.Queueable) error) (data.Queueable, error)
+	AgeWaiting(queueName string, ratePerSecond float64, maxWait time.Duration, maxWaitPriority float64) error
+	Requeue(queueName string, item data.Queueable, reason error, countRetry bool) error
+	Dequeue(queueName string, workerId string, capabilities []string) (item data.Queueable, err error)
 	Failed(queueName string, item data.Queueable, err error) error
 	Finished(queueName string, item data.Queueable) error
 	Processing(queueName string, data map[string]interface{}) error
+	ReadProcessing(queueName string, itemId string, lastEventId string, block time.Duration) ([]data.ProcessingEvent, error)
+	Details(queueName string, itemId string) (data.QueueableDetails, error)
+	List(queueName string, status string, tenant string, cursor uint64, count int64) (items []data.QueueableDetails, nextCursor uint64, err error)
+	Cancel(queueName string, itemId string) error
 	TotalRetry(queueName string, item data.Queueable) int
-	SendSystemRadarScannerData(data map[string]interface{}) error
+	SaveDeliveryAttempt(queueName string, item data.Queueable, attempt data.DeliveryAttempt, maxAttempts int64, ttl time.Duration) error
+	SendCallbackToStream(stream string, callback map[string]interface{}, maxLength int64) (id string, err error)
+	TenantUsage(queueName string, tenant string) (data.TenantUsage, error)
+	AddTenantTranscodeSeconds(queueName string, tenant string, seconds float64) error
+	ReserveIdempotencyKey(key string, record data.IdempotencyRecord, ttl time.Duration) (data.IdempotencyRecord, bool, error)
+	ReleaseIdempotencyKey(key string) error
+	TakeToken(bucket string, rate float64, burst int) (allowed bool, retryAfter time.Duration, err error)
+	SendAbuseLog(entry map[string]interface{}, maxLength int64) error
+	SaveApiKey(key data.ApiKey) error
+	GetApiKey(keyId string) (data.ApiKey, error)
+	SendSystemRadarScannerData(radar data.Radar) error
 	SendLogsToDatabase(log map[string]interface{}) error
 }
 
@@ -46,9 +88,19 @@ func New(databaseManager Interface) *Database {
 	}
 }
 
+// Ping check database is reachable.
+func (d *Database) Ping(ctx context.Context) error {
+	return d.databaseManager.Ping(ctx)
+}
+
 // SendHeartbeat send heartbeat to update cluster status.
-func (d *Database) SendHeartbeat(workerId, workerType string) error {
-	return d.databaseManager.SendHeartbeat(workerId, workerType)
+func (d *Database) SendHeartbeat(node, workerId, workerType string) error {
+	return d.databaseManager.SendHeartbeat(node, workerId, workerType)
+}
+
+// ClusterWorkers get workers sending heartbeat with their current tasks, finished tasks within window and latest radar entry.
+func (d *Database) ClusterWorkers(queueName string, throughputWindow time.Duration, radarScanLimit int64) ([]data.ClusterWorker, error) {
+	return d.databaseManager.ClusterWorkers(queueName, throughputWindow, radarScanLimit)
 }
 
 // Enqueue add item to waiting queue.
@@ -56,9 +108,54 @@ func (d *Database) Enqueue(queueName string, item data.Queueable) error {
 	return d.databaseManager.Enqueue(queueName, item)
 }
 
-// Dequeue fetch item from waiting queue.
-func (d *Database) Dequeue(queueName string, workerId string) (item data.Queueable, err error) {
-	return d.databaseManager.Dequeue(queueName, workerId)
+// EnqueueUnique add item to waiting queue only when no item with same id exists and its tenant has less than maxActive active items, zero maxActive means unlimited.
+func (d *Database) EnqueueUnique(queueName string, item data.Queueable, maxActive int64) error {
+	return d.databaseManager.EnqueueUnique(queueName, item, maxActive)
+}
+
+// EnqueueBatch add items to waiting queue atomically, items with id already used or whose tenant has maxActive active items are skipped and returned.
+func (d *Database) EnqueueBatch(queueName string, items []data.Queueable, batch string, batchTTL time.Duration, maxActive int64) (existing []string, limited []string, err error) {
+	return d.databaseManager.EnqueueBatch(queueName, items, batch, batchTTL, maxActive)
+}
+
+// BatchStatuses count items of batch in each status.
+func (d *Database) BatchStatuses(queueName string, batch string) (map[string]int64, error) {
+	return d.databaseManager.BatchStatuses(queueName, batch)
+}
+
+// QueueStatuses count items of queue in each status.
+func (d *Database) QueueStatuses(queueName string) (map[string]int64, error) {
+	return d.databaseManager.QueueStatuses(queueName)
+}
+
+// Delay add item to delayed queue, it will be moved to waiting queue at run time.
+func (d *Database) Delay(queueName string, item data.Queueable, runAt time.Time) error {
+	return d.databaseManager.Delay(queueName, item, runAt)
+}
+
+// UpdateWaiting change waiting or delayed item and its score in waiting queue atomically.
+func (d *Database) UpdateWaiting(queueName string, itemId string, update func(item *data.Queueable) error) (data.Queueable, error) {
+	return d.databaseManager.UpdateWaiting(queueName, itemId, update)
+}
+
+// AgeWaiting save aging rate of queue and give items waiting longer than max wait max wait priority, from where they keep aging.
+func (d *Database) AgeWaiting(queueName string, ratePerSecond float64, maxWait time.Duration, maxWaitPriority float64) error {
+	return d.databaseManager.AgeWaiting(queueName, ratePerSecond, maxWait, maxWaitPriority)
+}
+
+// PromoteDelayed move due items from delayed queue to waiting queue.
+func (d *Database) PromoteDelayed(queueName string) error {
+	return d.databaseManager.PromoteDelayed(queueName)
+}
+
+// Requeue send item back to waiting queue and save reason it was not processed, retry count is kept when countRetry is false.
+func (d *Database) Requeue(queueName string, item data.Queueable, reason error, countRetry bool) error {
+	return d.databaseManager.Requeue(queueName, item, reason, countRetry)
+}
+
+// Dequeue fetch item from waiting queue, items requiring capabilities missing from given ones are skipped.
+func (d *Database) Dequeue(queueName string, workerId string, capabilities []string) (item data.Queueable, err error) {
+	return d.databaseManager.Dequeue(queueName, workerId, capabilities)
 }
 
 // Failed change item status to failed.
@@ -76,14 +173,84 @@ func (d *Database) Processing(queueName string, data map[string]interface{}) err
 	return d.databaseManager.Processing(queueName, data)
 }
 
+// ReadProcessing read processing events sent after last event id, wait up to block duration for new events.
+func (d *Database) ReadProcessing(queueName string, itemId string, lastEventId string, block time.Duration) ([]data.ProcessingEvent, error) {
+	return d.databaseManager.ReadProcessing(queueName, itemId, lastEventId, block)
+}
+
+// Details get item with its current status.
+func (d *Database) Details(queueName string, itemId string) (data.QueueableDetails, error) {
+	return d.databaseManager.Details(queueName, itemId)
+}
+
+// List get page of items filtered by status and tenant, empty status or tenant means all items.
+func (d *Database) List(queueName string, status string, tenant string, cursor uint64, count int64) (items []data.QueueableDetails, nextCursor uint64, err error) {
+	return d.databaseManager.List(queueName, status, tenant, cursor, count)
+}
+
+// Cancel remove item from waiting or delayed queue and change its status to cancelled.
+func (d *Database) Cancel(queueName string, itemId string) error {
+	return d.databaseManager.Cancel(queueName, itemId)
+}
+
 // TotalRetry get total failed retry.
 func (d *Database) TotalRetry(queueName string, item data.Queueable) int {
 	return d.databaseManager.TotalRetry(queueName, item)
 }
 
+// SaveDeliveryAttempt save single delivery attempt in item attempts history, only last maxAttempts attempts are kept for ttl.
+func (d *Database) SaveDeliveryAttempt(queueName string, item data.Queueable, attempt data.DeliveryAttempt, maxAttempts int64, ttl time.Duration) error {
+	return d.databaseManager.SaveDeliveryAttempt(queueName, item, attempt, maxAttempts, ttl)
+}
+
+// SendCallbackToStream add callback to stream, stream trimmed to approximately max length when its more than zero.
+func (d *Database) SendCallbackToStream(stream string, callback map[string]interface{}, maxLength int64) (id string, err error) {
+	return d.databaseManager.SendCallbackToStream(stream, callback, maxLength)
+}
+
+// TenantUsage get tenant active tasks and transcoded seconds today.
+func (d *Database) TenantUsage(queueName string, tenant string) (data.TenantUsage, error) {
+	return d.databaseManager.TenantUsage(queueName, tenant)
+}
+
+// AddTenantTranscodeSeconds add transcoded input video seconds to tenant usage of today.
+func (d *Database) AddTenantTranscodeSeconds(queueName string, tenant string, seconds float64) error {
+	return d.databaseManager.AddTenantTranscodeSeconds(queueName, tenant, seconds)
+}
+
+// ReserveIdempotencyKey save record under idempotency key when key is not used, otherwise return saved record.
+func (d *Database) ReserveIdempotencyKey(key string, record data.IdempotencyRecord, ttl time.Duration) (data.IdempotencyRecord, bool, error) {
+	return d.databaseManager.ReserveIdempotencyKey(key, record, ttl)
+}
+
+// ReleaseIdempotencyKey remove idempotency key so it can be used again.
+func (d *Database) ReleaseIdempotencyKey(key string) error {
+	return d.databaseManager.ReleaseIdempotencyKey(key)
+}
+
+// TakeToken take one token from rate limit bucket, return how long to wait when bucket is empty.
+func (d *Database) TakeToken(bucket string, rate float64, burst int) (allowed bool, retryAfter time.Duration, err error) {
+	return d.databaseManager.TakeToken(bucket, rate, burst)
+}
+
+// SendAbuseLog add rejected request to abuse stream.
+func (d *Database) SendAbuseLog(entry map[string]interface{}, maxLength int64) error {
+	return d.databaseManager.SendAbuseLog(entry, maxLength)
+}
+
+// SaveApiKey create or update api key.
+func (d *Database) SaveApiKey(key data.ApiKey) error {
+	return d.databaseManager.SaveApiKey(key)
+}
+
+// GetApiKey get api key by its id.
+func (d *Database) GetApiKey(keyId string) (data.ApiKey, error) {
+	return d.databaseManager.GetApiKey(keyId)
+}
+
 // SendSystemRadarScannerData send system radar scanning data content full information about running node.
-func (d *Database) SendSystemRadarScannerData(data map[string]interface{}) error {
-	return d.databaseManager.SendSystemRadarScannerData(data)
+func (d *Database) SendSystemRadarScannerData(radar data.Radar) error {
+	return d.databaseManager.SendSystemRadarScannerData(radar)
 }
 
 // SendLogsToDatabase  save rasbora logs at database.
@@ -35,11 +35,281 @@ import (
 // Create a new context based on the Background context
 var ctx = context.Background()
 
+// tenantLua shared lua helpers to find tenant of item and its waiting queue.
+const tenantLua = `
+local function item_tenant(items, id)
+	local item = redis.call('HGET', items, id)
+	if item then
+		local tenant = cjson.decode(item)['queue_item_tenant']
+		if type(tenant) == 'string' then
+			return tenant
+		end
+	end
+	return ''
+end
+local function tenant_key(default, template, tenant)
+	if tenant == '' then
+		return default
+	end
+	return (string.gsub(template, '{{tenant}}', function() return tenant end))
+end
+`
+
+// agingLua shared lua helpers to score waiting items with aging rate saved with queue.
+// Score is priority improved by time item was enqueued since epoch, so older items get ahead without their score being changed.
+const agingLua = `
+local function item_priority(items, id)
+	local item = redis.call('HGET', items, id)
+	if item then
+		local priority = cjson.decode(item)['queue_item_priority']
+		if type(priority) == 'number' then
+			return priority
+		end
+	end
+	return 0
+end
+local function aging_settings(aging)
+	local settings = redis.call('HMGET', aging, 'rate', 'epoch')
+	return tonumber(settings[1]) or 0, tonumber(settings[2]) or 0
+end
+local function waiting_score(aging, priority, enqueued)
+	local rate, epoch = aging_settings(aging)
+	return priority + (tonumber(enqueued) - epoch) * rate
+end
+local function waiting_member(enqueued, id)
+	return string.format('%d', tonumber(enqueued)) .. ':' .. id
+end
+`
+
+// promoteDelayedScript move given due items from delayed queue to waiting queue of their tenant atomically.
+// Items removed from delayed queue meanwhile are skipped.
+// KEYS: delayed, items, status, tenants, enqueued, aging, then waiting queue of each item
+// ARGV: current time in milliseconds, then for each item: id, tenant
+var promoteDelayedScript = redis.NewScript(agingLua + `
+local promoted = 0
+for i = 2, #ARGV, 2 do
+	local id, tenant = ARGV[i], ARGV[i + 1]
+	if redis.call('ZSCORE', KEYS[1], id) then
+		local score = waiting_score(KEYS[6], item_priority(KEYS[2], id), ARGV[1])
+		redis.call('ZADD', KEYS[6 + i / 2], score, waiting_member(ARGV[1], id))
+		redis.call('ZADD', KEYS[5], ARGV[1], id)
+		redis.call('ZADD', KEYS[4], 'NX', 0, tenant)
+		redis.call('HSET', KEYS[3], id, 'waiting')
+		redis.call('ZREM', KEYS[1], id)
+		promoted = promoted + 1
+	end
+end
+return promoted
+`)
+
+// enqueueWaitingScript add item to waiting queue scored with aging rate saved with queue.
+// KEYS: waiting, enqueued, aging
+// ARGV: item id, priority, current time in milliseconds
+var enqueueWaitingScript = redis.NewScript(agingLua + `
+redis.call('ZADD', KEYS[1], waiting_score(KEYS[3], tonumber(ARGV[2]), ARGV[3]), waiting_member(ARGV[3], ARGV[1]))
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`)
+
+// enqueueBatchScript add items whose id is not used yet to waiting or delayed queue atomically.
+// Item is added only when its tenant has less than max active items, so slot of active item is reserved while item is saved.
+// KEYS: status, items, worker, retry, delayed, tenants, enqueued, batch, aging, then for each item: waiting, active of its tenant
+// ARGV: current time in milliseconds, batch ttl in milliseconds, max active items,
+// then for each item: id, item, tenant, priority, run at in milliseconds
+// Return: ids already used and ids of items whose tenant reached max active items.
+var enqueueBatchScript = redis.NewScript(agingLua + `
+local existing = {}
+local limited = {}
+local added = 0
+local max_active = tonumber(ARGV[3])
+for i = 4, #ARGV, 5 do
+	local id, tenant, run_at = ARGV[i], ARGV[i + 2], tonumber(ARGV[i + 4])
+	local key = 10 + (i - 4) / 5 * 2
+	local waiting, active = KEYS[key], KEYS[key + 1]
+	local delayed = run_at > tonumber(ARGV[1])
+	local status = 'waiting'
+	if delayed then
+		status = 'delayed'
+	end
+	if redis.call('HEXISTS', KEYS[1], id) == 1 then
+		table.insert(existing, id)
+	elseif active ~= '' and max_active > 0 and redis.call('SCARD', active) >= max_active then
+		table.insert(limited, id)
+	else
+		redis.call('HSET', KEYS[1], id, status)
+		if delayed then
+			redis.call('ZADD', KEYS[5], run_at, id)
+		else
+			local score = waiting_score(KEYS[9], tonumber(ARGV[i + 3]), ARGV[1])
+			redis.call('ZADD', waiting, score, waiting_member(ARGV[1], id))
+			redis.call('ZADD', KEYS[7], ARGV[1], id)
+			redis.call('ZADD', KEYS[6], 'NX', 0, tenant)
+		end
+		if active ~= '' then
+			redis.call('SADD', active, id)
+		end
+		redis.call('HSET', KEYS[2], id, ARGV[i + 1])
+		redis.call('HSET', KEYS[3], id, '')
+		redis.call('HINCRBY', KEYS[4], id, 1)
+		if KEYS[8] ~= '' then
+			redis.call('SADD', KEYS[8], id)
+		end
+		added = added + 1
+	end
+end
+if KEYS[8] ~= '' and added > 0 then
+	redis.call('PEXPIRE', KEYS[8], ARGV[2])
+end
+return {existing, limited}
+`)
+
+// setAgingScript save aging rate of queue, epoch restarts when rate changes and existing items must be rescored.
+// KEYS: aging
+// ARGV: rate per millisecond, current time in milliseconds, max wait in milliseconds
+// Return: 1 when rate changed, 0 otherwise.
+var setAgingScript = redis.NewScript(`
+local rate = redis.call('HGET', KEYS[1], 'rate') or '0'
+if tonumber(rate) == tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'rate', ARGV[1], 'epoch', ARGV[2], 'aged_until', tonumber(ARGV[2]) - tonumber(ARGV[3]))
+return 1
+`)
+
+// rescoreWaitingScript score given waiting members again with aging rate saved with queue, items waiting longer than max wait keep max wait priority.
+// KEYS: waiting, items, aging
+// ARGV: current time in milliseconds, max wait in milliseconds, max wait priority, members...
+var rescoreWaitingScript = redis.NewScript(agingLua + `
+local now = tonumber(ARGV[1])
+local max_wait = tonumber(ARGV[2])
+local rate, epoch = aging_settings(KEYS[3])
+local cap = tonumber(ARGV[3]) + (now - epoch) * rate
+for i = 4, #ARGV do
+	local enqueued, id = string.match(ARGV[i], '^(%d+):(.+)$')
+	if enqueued then
+		local score = waiting_score(KEYS[3], item_priority(KEYS[2], id), enqueued)
+		if max_wait > 0 and now - tonumber(enqueued) >= max_wait and score > cap then
+			score = cap
+		end
+		redis.call('ZADD', KEYS[1], 'XX', score, ARGV[i])
+	end
+end
+return #ARGV - 3
+`)
+
+// ageWaitingScript give given items becoming older than max wait since last run max wait priority, unless their score is already lower.
+// Items are skipped when another run aged them meanwhile, which is found from aged until saved with queue.
+// KEYS: aging, then waiting queue of each item
+// ARGV: current time in milliseconds, aged until in milliseconds, aged until read with items, max wait priority, members...
+var ageWaitingScript = redis.NewScript(agingLua + `
+if (redis.call('HGET', KEYS[1], 'aged_until') or '') ~= ARGV[3] then
+	return 0
+end
+local rate, epoch = aging_settings(KEYS[1])
+local cap = tonumber(ARGV[4]) + (tonumber(ARGV[1]) - epoch) * rate
+for i = 5, #ARGV do
+	redis.call('ZADD', KEYS[i - 3], 'XX', 'LT', cap, ARGV[i])
+end
+redis.call('HSET', KEYS[1], 'aged_until', ARGV[2])
+return #ARGV - 4
+`)
+
+// dequeueScript pop item with lowest score from waiting queue of tenant served least recently.
+// Items requiring capabilities the worker does not have, or excluding the worker for now, are skipped.
+// Only first items up to lookahead are checked.
+// KEYS: tenants, waiting, items, enqueued
+// ARGV: current time in milliseconds, tenant waiting template, lookahead, worker id, worker capabilities...
+var dequeueScript = redis.NewScript(tenantLua + `
+local capabilities = {}
+for i = 5, #ARGV do
+	capabilities[ARGV[i]] = true
+end
+local function capable(member)
+	local id = string.match(member, '^%d+:(.+)$')
+	local item = redis.call('HGET', KEYS[3], id)
+	if not item then
+		return true
+	end
+	local decoded = cjson.decode(item)
+	local excluded = decoded['queue_item_excluded_workers']
+	if type(excluded) == 'table' and type(excluded[ARGV[4]]) == 'number' and excluded[ARGV[4]] > tonumber(ARGV[1]) then
+		return false
+	end
+	local required = decoded['queue_item_capabilities']
+	if type(required) ~= 'table' then
+		return true
+	end
+	for _, capability in ipairs(required) do
+		if not capabilities[capability] then
+			return false
+		end
+	end
+	return true
+end
+local function pop(waiting)
+	local members = redis.call('ZRANGE', waiting, 0, tonumber(ARGV[3]) - 1)
+	for _, member in ipairs(members) do
+		if capable(member) then
+			redis.call('ZREM', waiting, member)
+			redis.call('ZREM', KEYS[4], string.match(member, '^%d+:(.+)$'))
+			return member
+		end
+	end
+	return false
+end
+local tenants = redis.call('ZRANGE', KEYS[1], 0, -1)
+for _, tenant in ipairs(tenants) do
+	local waiting = tenant_key(KEYS[2], ARGV[2], tenant)
+	local member = pop(waiting)
+	if member then
+		redis.call('ZADD', KEYS[1], ARGV[1], tenant)
+		return member
+	end
+	if redis.call('ZCARD', waiting) == 0 then
+		redis.call('ZREM', KEYS[1], tenant)
+	end
+end
+return pop(KEYS[2])
+`)
+
+// dequeueLookahead max waiting items checked in each queue for item worker is capable to process.
+const dequeueLookahead = 200
+
+// throughputRetention how long finished items are kept to measure worker throughput.
+const throughputRetention = 24 * time.Hour
+
+// takeTokenScript take one token from bucket refilled continuously at rate per second up to burst.
+// KEYS: bucket
+// ARGV: rate, burst, current time in milliseconds
+// Return: 1 and 0 when token taken, 0 and milliseconds until next token otherwise.
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated_at')
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate / 1000)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated_at', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, retry}
+`)
+
 // RedisDatabaseManager holds an instance
 type RedisDatabaseManager struct {
-	Redis  *redis.Client
-	Config *config.Config
-	Logger *logger.Logger
+	Redis *redis.Client
+	// BlockingRedis used only for blocking reads, so long waiting readers never hold connections of Redis, Redis is used when nil.
+	BlockingRedis *redis.Client
+	Config        *config.Config
+	Logger        *logger.Logger
 }
 
 // SendLogsToDatabase save rasbora logs at database.
@@ -56,37 +326,270 @@ func (rdm *RedisDatabaseManager) SendLogsToDatabase(log map[string]interface{})
 	return nil
 }
 
+// Ping check redis is reachable.
+func (rdm *RedisDatabaseManager) Ping(ctx context.Context) error {
+	return rdm.Redis.Ping(ctx).Err()
+}
+
 // SendHeartbeat send heartbeat to update cluster status.
-func (rdm *RedisDatabaseManager) SendHeartbeat(workerId, workerType string) error {
+func (rdm *RedisDatabaseManager) SendHeartbeat(node, workerId, workerType string) error {
 	clusterHeartbeatList := rdm.Config.GetString("Database.Redis.Structure.Cluster.Heartbeat")
+	clusterWorkers := rdm.Config.GetString("Database.Redis.Structure.Cluster.Workers")
 
 	workerWithType := fmt.Sprintf("%s:%s", workerType, workerId)
 
-	if zAddResult := rdm.Redis.ZAdd(
+	tx := rdm.Redis.TxPipeline()
+	tx.ZAdd(
 		ctx,
 		clusterHeartbeatList,
 		redis.Z{
 			Score:  float64(time.Now().UnixMilli()),
 			Member: workerWithType,
 		},
-	); zAddResult.Err() != nil {
-		return zAddResult.Err()
+	)
+	tx.HSet(ctx, clusterWorkers, workerWithType, node)
+
+	if _, err := tx.Exec(ctx); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// ClusterWorkers get workers sending heartbeat with their current tasks, finished tasks within window and latest radar entry.
+func (rdm *RedisDatabaseManager) ClusterWorkers(queueName string, throughputWindow time.Duration, radarScanLimit int64) ([]data.ClusterWorker, error) {
+	_, _, worker, _, _, _, _ := rdm._queueStructures(queueName)
+
+	pipe := rdm.Redis.Pipeline()
+	heartbeatCmd := pipe.ZRangeWithScores(ctx, rdm.Config.GetString("Database.Redis.Structure.Cluster.Heartbeat"), 0, -1)
+	nodesCmd := pipe.HGetAll(ctx, rdm.Config.GetString("Database.Redis.Structure.Cluster.Workers"))
+	tasksCmd := pipe.HGetAll(ctx, worker)
+	finishedCmd := pipe.ZRangeByScore(ctx, rdm._queueStructure(queueName, "Throughput"), &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().Add(-throughputWindow).UnixMilli(), 10),
+		Max: "+inf",
+	})
+	radarCmd := pipe.XRevRangeN(ctx, rdm.Config.GetString("Database.Redis.Structure.Cluster.Radar"), "+", "-", radarScanLimit)
+
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	currentTasks := map[string][]string{}
+	for taskId, workerId := range tasksCmd.Val() {
+		currentTasks[workerId] = append(currentTasks[workerId], taskId)
+	}
+
+	finishedTasks := map[string]int64{}
+	for _, member := range finishedCmd.Val() {
+		workerId, _, _ := strings.Cut(member, "|")
+		finishedTasks[workerId]++
+	}
+
+	// stream is read from newest entry, so first usable entry of each radar is its latest one.
+	radars := map[string]data.Radar{}
+	for _, message := range radarCmd.Val() {
+		radarId, _ := message.Values["system_radar_id"].(string)
+		if _, ok := radars[radarId]; ok {
+			continue
+		}
+
+		radar, ok, err := _radarFromStream(message.Values)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			radars[radarId] = radar
+		}
+	}
+
+	workers := make([]data.ClusterWorker, 0, len(heartbeatCmd.Val()))
+	for _, heartbeat := range heartbeatCmd.Val() {
+		member, _ := heartbeat.Member.(string)
+		workerType, workerId, _ := strings.Cut(member, ":")
+
+		clusterWorker := data.ClusterWorker{
+			WorkerId:      workerId,
+			WorkerType:    workerType,
+			Node:          nodesCmd.Val()[member],
+			LastSeen:      int64(heartbeat.Score),
+			CurrentTasks:  currentTasks[workerId],
+			FinishedTasks: finishedTasks[workerId],
+		}
+
+		if radar, ok := radars[workerId]; ok {
+			clusterWorker.Radar = &radar
+		}
+
+		workers = append(workers, clusterWorker)
+	}
+
+	return workers, nil
+}
+
 // Enqueue add item to waiting queue.
 func (rdm *RedisDatabaseManager) Enqueue(queueName string, item data.Queueable) error {
-	scoreWithID := fmt.Sprintf("%d:%s", time.Now().UnixMilli(), item.ID)
-	waiting, status, worker, _, retry, items, _ := rdm._queueStructures(queueName)
+	tx := rdm.Redis.TxPipeline()
+	rdm._enqueue(tx, queueName, item)
+
+	if _, err := tx.Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EnqueueUnique add item to waiting queue only when no item with same id exists and its tenant has less than maxActive active items.
+// Item is saved using batch script, so checks and saving are atomic.
+func (rdm *RedisDatabaseManager) EnqueueUnique(queueName string, item data.Queueable, maxActive int64) error {
+	existing, limited, err := rdm.EnqueueBatch(queueName, []data.Queueable{item}, "", 0, maxActive)
+	if err != nil {
+		return err
+	}
+
+	if len(existing) > 0 {
+		return ErrAlreadyExists
+	}
+
+	if len(limited) > 0 {
+		return ErrActiveLimitReached
+	}
+
+	return nil
+}
+
+// EnqueueBatch add items to waiting queue in single script, items with id already used or whose tenant has maxActive active items are skipped and returned.
+// Ids and active items are checked and items are added atomically, so item saved by another client meanwhile is never overwritten.
+func (rdm *RedisDatabaseManager) EnqueueBatch(queueName string, items []data.Queueable, batch string, batchTTL time.Duration, maxActive int64) (existing []string, limited []string, err error) {
+	_, status, worker, _, retry, itemsKey, _ := rdm._queueStructures(queueName)
+
+	if len(items) == 0 {
+		return nil, nil, nil
+	}
+
+	batchKey := ""
+	if batch != "" {
+		batchKey = strings.Replace(rdm._queueStructure(queueName, "Batch"), "{{batch}}", batch, 1)
+	}
+
+	now := time.Now().UnixMilli()
+	keys := []string{status, itemsKey, worker, retry, rdm._queueStructure(queueName, "Delayed"), rdm._queueStructure(queueName, "Tenants"), rdm._queueStructure(queueName, "Enqueued"), batchKey, rdm._queueStructure(queueName, "Aging")}
+	args := []interface{}{now, batchTTL.Milliseconds(), maxActive}
+	for _, item := range items {
+		itemAsJson, err := json.Marshal(item)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keys = append(keys, rdm._waitingKey(queueName, item.Tenant), rdm._activeKey(queueName, item.Tenant))
+		args = append(args, item.ID, itemAsJson, item.Tenant, item.Priority, item.RunAt)
+	}
+
+	result, err := enqueueBatchScript.Run(ctx, rdm.Redis, keys, args...).Slice()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return _stringSlice(result[0]), _stringSlice(result[1]), nil
+}
+
+// _stringSlice convert array returned by script to strings.
+func _stringSlice(value interface{}) []string {
+	values, _ := value.([]interface{})
+
+	var strs []string
+	for _, v := range values {
+		strs = append(strs, fmt.Sprint(v))
+	}
+
+	return strs
+}
+
+// BatchStatuses count items of batch in each status.
+func (rdm *RedisDatabaseManager) BatchStatuses(queueName string, batch string) (map[string]int64, error) {
+	_, status, _, _, _, _, _ := rdm._queueStructures(queueName)
+	batchKey := strings.Replace(rdm._queueStructure(queueName, "Batch"), "{{batch}}", batch, 1)
+
+	itemIds, err := rdm.Redis.SMembers(ctx, batchKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(itemIds) == 0 {
+		return nil, ErrNotFound
+	}
+
+	statuses, err := rdm.Redis.HMGet(ctx, status, itemIds...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{}
+	for _, itemStatus := range statuses {
+		if itemStatus, ok := itemStatus.(string); ok {
+			counts[itemStatus]++
+		}
+	}
+
+	return counts, nil
+}
+
+// QueueStatuses count items of queue in each status, status hash is scanned in pages to not block redis.
+func (rdm *RedisDatabaseManager) QueueStatuses(queueName string) (map[string]int64, error) {
+	_, status, _, _, _, _, _ := rdm._queueStructures(queueName)
+
+	counts := map[string]int64{}
+	var cursor uint64
+	for {
+		fields, nextCursor, err := rdm.Redis.HScan(ctx, status, cursor, "", 1000).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		// fields are returned as id and status pairs.
+		for i := 1; i < len(fields); i += 2 {
+			counts[fields[i]]++
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			return counts, nil
+		}
+	}
+}
+
+// _enqueue queue commands adding item to waiting queue, or to delayed queue when its run time is in the future.
+func (rdm *RedisDatabaseManager) _enqueue(pipe redis.Pipeliner, queueName string, item data.Queueable) {
+	if item.RunAt > time.Now().UnixMilli() {
+		rdm._delay(pipe, queueName, item, time.UnixMilli(item.RunAt))
+		return
+	}
+
+	_, status, worker, _, retry, items, _ := rdm._queueStructures(queueName)
+
+	// script is sent whole, pipeline cannot load it when it is not cached yet.
+	enqueueWaitingScript.Eval(
+		ctx,
+		pipe,
+		[]string{rdm._waitingKey(queueName, item.Tenant), rdm._queueStructure(queueName, "Enqueued"), rdm._queueStructure(queueName, "Aging")},
+		item.ID,
+		item.Priority,
+		time.Now().UnixMilli(),
+	)
+	pipe.ZAddNX(ctx, rdm._queueStructure(queueName, "Tenants"), redis.Z{Score: 0, Member: item.Tenant})
+	if item.Tenant != "" {
+		pipe.SAdd(ctx, rdm._tenantStructure(queueName, "TenantActive", item.Tenant), item.ID)
+	}
+	pipe.HSet(ctx, items, item.ID, item)
+	pipe.HSet(ctx, status, item.ID, "waiting")
+	pipe.HSet(ctx, worker, item.ID, nil)
+	pipe.HIncrBy(ctx, retry, item.ID, 1)
+}
+
+// Delay add item to delayed queue, it will be moved to waiting queue at run time.
+func (rdm *RedisDatabaseManager) Delay(queueName string, item data.Queueable, runAt time.Time) error {
 
 	tx := rdm.Redis.TxPipeline()
-	tx.ZAdd(ctx, waiting, redis.Z{Score: item.Priority, Member: scoreWithID})
-	tx.HSet(ctx, items, item.ID, item)
-	tx.HSet(ctx, status, item.ID, "waiting")
-	tx.HSet(ctx, worker, item.ID, nil)
-	tx.HIncrBy(ctx, retry, item.ID, 1)
+	rdm._delay(tx, queueName, item, runAt)
 
 	if _, err := tx.Exec(ctx); err != nil {
 		return err
@@ -95,8 +598,296 @@ func (rdm *RedisDatabaseManager) Enqueue(queueName string, item data.Queueable)
 	return nil
 }
 
-// Dequeue fetch item from waiting queue.
-func (rdm *RedisDatabaseManager) Dequeue(queueName string, workerId string) (item data.Queueable, err error) {
+// _delay queue commands adding item to delayed queue.
+func (rdm *RedisDatabaseManager) _delay(pipe redis.Pipeliner, queueName string, item data.Queueable, runAt time.Time) {
+	_, status, worker, _, retry, items, _ := rdm._queueStructures(queueName)
+	delayed := rdm._queueStructure(queueName, "Delayed")
+
+	pipe.ZAdd(ctx, delayed, redis.Z{Score: float64(runAt.UnixMilli()), Member: item.ID})
+	if item.Tenant != "" {
+		pipe.SAdd(ctx, rdm._tenantStructure(queueName, "TenantActive", item.Tenant), item.ID)
+	}
+	pipe.HSet(ctx, items, item.ID, item)
+	pipe.HSet(ctx, status, item.ID, "delayed")
+	pipe.HSet(ctx, worker, item.ID, nil)
+	pipe.HIncrBy(ctx, retry, item.ID, 1)
+}
+
+// PromoteDelayed move due items from delayed queue to waiting queue.
+// Due items and their tenants are read first, so all keys of script are passed to it.
+func (rdm *RedisDatabaseManager) PromoteDelayed(queueName string) error {
+	_, status, _, _, _, items, _ := rdm._queueStructures(queueName)
+	delayed := rdm._queueStructure(queueName, "Delayed")
+	now := time.Now().UnixMilli()
+
+	due, err := rdm.Redis.ZRangeByScore(ctx, delayed, &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(now, 10)}).Result()
+	if err != nil || len(due) == 0 {
+		return err
+	}
+
+	tenants, err := rdm._itemTenants(queueName, due)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{delayed, items, status, rdm._queueStructure(queueName, "Tenants"), rdm._queueStructure(queueName, "Enqueued"), rdm._queueStructure(queueName, "Aging")}
+	args := []interface{}{now}
+	for i, itemId := range due {
+		keys = append(keys, rdm._waitingKey(queueName, tenants[i]))
+		args = append(args, itemId, tenants[i])
+	}
+
+	return promoteDelayedScript.Run(ctx, rdm.Redis, keys, args...).Err()
+}
+
+// UpdateWaiting change waiting or delayed item and its score in waiting queue atomically.
+// Item is read and written inside watched transaction, which is retried when another client changes the queue meanwhile.
+func (rdm *RedisDatabaseManager) UpdateWaiting(queueName string, itemId string, update func(item *data.Queueable) error) (data.Queueable, error) {
+	_, status, _, _, _, items, _ := rdm._queueStructures(queueName)
+	delayed := rdm._queueStructure(queueName, "Delayed")
+
+	var updated data.Queueable
+
+	txFunc := func(tx *redis.Tx) error {
+		itemStatus, err := tx.HGet(ctx, status, itemId).Result()
+		if errors.Is(err, redis.Nil) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if itemStatus != "waiting" && itemStatus != "delayed" {
+			return ErrNotWaiting
+		}
+
+		itemAsJsonString, err := tx.HGet(ctx, items, itemId).Result()
+		if err != nil {
+			return err
+		}
+
+		var item data.Queueable
+		if err := json.Unmarshal([]byte(itemAsJsonString), &item); err != nil {
+			return err
+		}
+
+		// member of waiting item is built from the time item was enqueued.
+		var member string
+		if itemStatus == "waiting" {
+			enqueued := rdm._queueStructure(queueName, "Enqueued")
+			if err := tx.Watch(ctx, enqueued, rdm._waitingKey(queueName, item.Tenant)).Err(); err != nil {
+				return err
+			}
+
+			enqueuedAt, err := tx.ZScore(ctx, enqueued, itemId).Result()
+			if errors.Is(err, redis.Nil) {
+				return ErrNotWaiting
+			}
+			if err != nil {
+				return err
+			}
+
+			member = _waitingMember(int64(enqueuedAt), itemId)
+		}
+
+		aging := rdm._queueStructure(queueName, "Aging")
+		if err := tx.Watch(ctx, aging).Err(); err != nil {
+			return err
+		}
+		rate, epoch, err := rdm._agingSettings(tx, queueName)
+		if err != nil {
+			return err
+		}
+
+		if err := update(&item); err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, items, itemId, item)
+			if member != "" {
+				enqueuedAt, _ := strconv.ParseInt(strings.Split(member, ":")[0], 10, 64)
+				pipe.ZAddXX(ctx, rdm._waitingKey(queueName, item.Tenant), redis.Z{Score: waitingScore(item.Priority, enqueuedAt, rate, epoch), Member: member})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		updated = item
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		err := rdm.Redis.Watch(ctx, txFunc, status, items, delayed)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return updated, err
+	}
+
+	return updated, redis.TxFailedErr
+}
+
+// AgeWaiting save aging rate of queue and give items waiting longer than max wait max wait priority, from where they keep aging.
+// Aging by wait time needs no update, it is part of score items get when they are enqueued.
+// Waiting items are scored again only when rate changes, so all items of queue are always scored with same rate.
+func (rdm *RedisDatabaseManager) AgeWaiting(queueName string, ratePerSecond float64, maxWait time.Duration, maxWaitPriority float64) error {
+	aging := rdm._queueStructure(queueName, "Aging")
+	now := time.Now().UnixMilli()
+
+	changed, err := setAgingScript.Run(
+		ctx,
+		rdm.Redis,
+		[]string{aging},
+		strconv.FormatFloat(ratePerSecond/1000, 'g', -1, 64),
+		now,
+		maxWait.Milliseconds(),
+	).Bool()
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		if err := rdm._rescoreWaiting(queueName, now, maxWait, maxWaitPriority); err != nil {
+			return err
+		}
+	}
+
+	if maxWait <= 0 {
+		return nil
+	}
+
+	return rdm._ageOverdue(queueName, now, maxWait, maxWaitPriority)
+}
+
+// _ageOverdue give items becoming older than max wait since last run max wait priority.
+// Only items enqueued within that window are read from enqueued index, with their tenants, so all keys of script are passed to it.
+func (rdm *RedisDatabaseManager) _ageOverdue(queueName string, now int64, maxWait time.Duration, maxWaitPriority float64) error {
+	aging := rdm._queueStructure(queueName, "Aging")
+	agedUntil := now - maxWait.Milliseconds()
+
+	last, err := rdm.Redis.HGet(ctx, aging, "aged_until").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	from := "-inf"
+	if last != "" {
+		lastAgedUntil, _ := strconv.ParseFloat(last, 64)
+		if int64(lastAgedUntil) >= agedUntil {
+			return nil
+		}
+		from = fmt.Sprintf("(%d", int64(lastAgedUntil))
+	}
+
+	overdue, err := rdm.Redis.ZRangeByScoreWithScores(ctx, rdm._queueStructure(queueName, "Enqueued"), &redis.ZRangeBy{Min: from, Max: strconv.FormatInt(agedUntil, 10)}).Result()
+	if err != nil {
+		return err
+	}
+
+	itemIds := make([]string, len(overdue))
+	for i, z := range overdue {
+		itemIds[i] = fmt.Sprint(z.Member)
+	}
+
+	tenants, err := rdm._itemTenants(queueName, itemIds)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{aging}
+	args := []interface{}{now, agedUntil, last, maxWaitPriority}
+	for i, z := range overdue {
+		keys = append(keys, rdm._waitingKey(queueName, tenants[i]))
+		args = append(args, _waitingMember(int64(z.Score), itemIds[i]))
+	}
+
+	return ageWaitingScript.Run(ctx, rdm.Redis, keys, args...).Err()
+}
+
+// _rescoreWaiting score all waiting items of queue again with its saved aging rate.
+// Waiting queues are scanned in small pages, so redis is not blocked while large queues are scored.
+func (rdm *RedisDatabaseManager) _rescoreWaiting(queueName string, now int64, maxWait time.Duration, maxWaitPriority float64) error {
+	waiting, _, _, _, _, items, _ := rdm._queueStructures(queueName)
+	aging := rdm._queueStructure(queueName, "Aging")
+
+	tenants, err := rdm.Redis.ZRange(ctx, rdm._queueStructure(queueName, "Tenants"), 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	waitingKeys := []string{waiting}
+	for _, tenant := range tenants {
+		if tenant != "" {
+			waitingKeys = append(waitingKeys, rdm._waitingKey(queueName, tenant))
+		}
+	}
+
+	for _, waitingKey := range waitingKeys {
+		var cursor uint64
+		for {
+			members, nextCursor, err := rdm.Redis.ZScan(ctx, waitingKey, cursor, "", 200).Result()
+			if err != nil {
+				return err
+			}
+
+			// scan returns members followed by their scores.
+			args := []interface{}{now, maxWait.Milliseconds(), maxWaitPriority}
+			for i := 0; i < len(members); i += 2 {
+				args = append(args, members[i])
+			}
+
+			if len(args) > 3 {
+				if err := rescoreWaitingScript.Run(ctx, rdm.Redis, []string{waitingKey, items, aging}, args...).Err(); err != nil {
+					return err
+				}
+			}
+
+			cursor = nextCursor
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// cancelScript remove waiting or delayed item from its queue and change its status to cancelled atomically.
+// Waiting member is built from the time item was enqueued, keys of tenant are passed, so item tenant is checked to be the one they were built for.
+// KEYS: waiting, delayed, status, worker, items, enqueued, active
+// ARGV: item id, tenant
+var cancelScript = redis.NewScript(tenantLua + agingLua + `
+local status = redis.call('HGET', KEYS[3], ARGV[1])
+if not status then
+	return 'missing'
+end
+if item_tenant(KEYS[5], ARGV[1]) ~= ARGV[2] then
+	return 'changed'
+end
+if status == 'waiting' then
+	local enqueued = redis.call('ZSCORE', KEYS[6], ARGV[1])
+	if enqueued then
+		redis.call('ZREM', KEYS[1], waiting_member(enqueued, ARGV[1]))
+		redis.call('ZREM', KEYS[6], ARGV[1])
+	end
+elseif status == 'delayed' then
+	redis.call('ZREM', KEYS[2], ARGV[1])
+else
+	return status
+end
+if KEYS[7] ~= '' then
+	redis.call('SREM', KEYS[7], ARGV[1])
+end
+redis.call('HSET', KEYS[3], ARGV[1], 'cancelled')
+redis.call('HDEL', KEYS[4], ARGV[1])
+return 'cancelled'
+`)
+
+// Dequeue fetch item from waiting queue, items requiring capabilities missing from given ones are skipped.
+func (rdm *RedisDatabaseManager) Dequeue(queueName string, workerId string, capabilities []string) (item data.Queueable, err error) {
 	waiting, status, worker, _, _, items, _ := rdm._queueStructures(queueName)
 
 	// find stopped tasks and make them as failed
@@ -123,16 +914,27 @@ func (rdm *RedisDatabaseManager) Dequeue(queueName string, workerId string) (ite
 		}
 	}
 
-	ids, zPopMinError := rdm.Redis.ZPopMin(ctx, waiting, 1).Result()
-	if zPopMinError != nil {
-		return item, zPopMinError
+	args := []interface{}{time.Now().UnixMilli(), rdm._queueStructure(queueName, "TenantWaiting"), dequeueLookahead, workerId}
+	for _, capability := range capabilities {
+		args = append(args, capability)
 	}
 
-	if len(ids) <= 0 {
+	member, popError := dequeueScript.Run(
+		ctx,
+		rdm.Redis,
+		[]string{rdm._queueStructure(queueName, "Tenants"), waiting, items, rdm._queueStructure(queueName, "Enqueued")},
+		args...,
+	).Text()
+
+	if errors.Is(popError, redis.Nil) {
 		return item, errors.New("there is no items in waiting queue")
 	}
 
-	var itemID = strings.Split(ids[0].Member.(string), ":")[1]
+	if popError != nil {
+		return item, popError
+	}
+
+	var itemID = strings.Split(member, ":")[1]
 	itemAsJsonString, hGetError := rdm.Redis.HGet(ctx, items, itemID).Result()
 	if hGetError != nil {
 		return item, hGetError
@@ -143,6 +945,9 @@ func (rdm *RedisDatabaseManager) Dequeue(queueName string, workerId string) (ite
 		return item, jsonParserError
 	}
 
+	// member starts with time item was added to waiting queue.
+	item.EnqueuedAt, _ = strconv.ParseInt(strings.Split(member, ":")[0], 10, 64)
+
 	rdm.Redis.HSet(ctx, status, item.ID, "working")
 	rdm.Redis.HSet(ctx, worker, item.ID, workerId)
 
@@ -160,6 +965,30 @@ func (rdm *RedisDatabaseManager) Failed(queueName string, item data.Queueable, e
 	tx.HSet(ctx, status, item.ID, "failed")
 	tx.HSet(ctx, logs, item.ID, err.Error())
 	tx.HDel(ctx, worker, item.ID)
+	if item.Tenant != "" {
+		tx.SRem(ctx, rdm._tenantStructure(queueName, "TenantActive", item.Tenant), item.ID)
+	}
+
+	if _, err := tx.Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Requeue send item back to waiting queue and save reason it was not processed, retry count is kept when countRetry is false.
+func (rdm *RedisDatabaseManager) Requeue(queueName string, item data.Queueable, reason error, countRetry bool) error {
+	_, _, _, processing, retry, _, logs := rdm._queueStructures(queueName)
+
+	tx := rdm.Redis.TxPipeline()
+
+	tx.Del(ctx, fmt.Sprintf("%v:%v", processing, item.ID))
+	rdm._enqueue(tx, queueName, item)
+	if !countRetry {
+		// enqueue counts every time item is queued, item sent back without failing is not a retry.
+		tx.HIncrBy(ctx, retry, item.ID, -1)
+	}
+	tx.HSet(ctx, logs, item.ID, reason.Error())
 
 	if _, err := tx.Exec(ctx); err != nil {
 		return err
@@ -172,11 +1001,27 @@ func (rdm *RedisDatabaseManager) Failed(queueName string, item data.Queueable, e
 func (rdm *RedisDatabaseManager) Finished(queueName string, item data.Queueable) error {
 	_, status, worker, _, _, items, _ := rdm._queueStructures(queueName)
 
+	workerId, err := rdm.Redis.HGet(ctx, worker, item.ID).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
 	tx := rdm.Redis.TxPipeline()
 
+	// remember who finished the item to measure worker throughput.
+	if workerId != "" {
+		throughput := rdm._queueStructure(queueName, "Throughput")
+		now := time.Now()
+		tx.ZAdd(ctx, throughput, redis.Z{Score: float64(now.UnixMilli()), Member: workerId + "|" + item.ID})
+		tx.ZRemRangeByScore(ctx, throughput, "-inf", strconv.FormatInt(now.Add(-throughputRetention).UnixMilli(), 10))
+	}
+
 	tx.HSet(ctx, items, item.ID, item)
 	tx.HSet(ctx, status, item.ID, "finished")
 	tx.HDel(ctx, worker, item.ID)
+	if item.Tenant != "" {
+		tx.SRem(ctx, rdm._tenantStructure(queueName, "TenantActive", item.Tenant), item.ID)
+	}
 
 	if _, err := tx.Exec(ctx); err != nil {
 		return err
@@ -201,6 +1046,130 @@ func (rdm *RedisDatabaseManager) Processing(queueName string, data map[string]in
 	return nil
 }
 
+// ReadProcessing read processing events sent after last event id, wait up to block duration for new events.
+func (rdm *RedisDatabaseManager) ReadProcessing(queueName string, itemId string, lastEventId string, block time.Duration) ([]data.ProcessingEvent, error) {
+	_, _, _, processing, _, _, _ := rdm._queueStructures(queueName)
+
+	if lastEventId == "" {
+		lastEventId = "0"
+	}
+
+	streams, err := rdm._blockingRedis().XRead(ctx, &redis.XReadArgs{
+		Streams: []string{fmt.Sprintf("%v:%v", processing, itemId), lastEventId},
+		Block:   block,
+	}).Result()
+
+	if errors.Is(err, redis.Nil) {
+		return []data.ProcessingEvent{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var events []data.ProcessingEvent
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			events = append(events, data.ProcessingEvent{
+				ID:     message.ID,
+				Values: message.Values,
+			})
+		}
+	}
+
+	return events, nil
+}
+
+// Details get item with its current status.
+func (rdm *RedisDatabaseManager) Details(queueName string, itemId string) (data.QueueableDetails, error) {
+	details, err := rdm._details(queueName, []string{itemId})
+	if err != nil {
+		return data.QueueableDetails{}, err
+	}
+
+	if len(details) == 0 {
+		return data.QueueableDetails{}, ErrNotFound
+	}
+
+	return details[0], nil
+}
+
+// List get page of items filtered by status and tenant, empty status or tenant means all items.
+// Status hash is scanned until page has count items or scan is complete, so filtered pages are not returned short.
+func (rdm *RedisDatabaseManager) List(queueName string, status string, tenant string, cursor uint64, count int64) (items []data.QueueableDetails, nextCursor uint64, err error) {
+	_, statusKey, _, _, _, _, _ := rdm._queueStructures(queueName)
+
+	for {
+		pairs, scanCursor, err := rdm.Redis.HScan(ctx, statusKey, cursor, "", count).Result()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var itemIds []string
+		for i := 0; i+1 < len(pairs); i += 2 {
+			if status == "" || pairs[i+1] == status {
+				itemIds = append(itemIds, pairs[i])
+			}
+		}
+
+		details, err := rdm._details(queueName, itemIds)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, detail := range details {
+			if tenant == "" || detail.Item.Tenant == tenant {
+				items = append(items, detail)
+			}
+		}
+
+		cursor = scanCursor
+		if cursor == 0 || int64(len(items)) >= count {
+			return items, cursor, nil
+		}
+	}
+}
+
+// Cancel remove item from waiting or delayed queue and change its status to cancelled.
+// Tenant of item is read first, so all keys of script are passed to it, script is run again when item changed meanwhile.
+func (rdm *RedisDatabaseManager) Cancel(queueName string, itemId string) error {
+	_, status, worker, _, _, items, _ := rdm._queueStructures(queueName)
+	delayed := rdm._queueStructure(queueName, "Delayed")
+
+	result := "changed"
+	for i := 0; i < 3 && result == "changed"; i++ {
+		tenants, err := rdm._itemTenants(queueName, []string{itemId})
+		if err != nil {
+			return err
+		}
+
+		result, err = cancelScript.Run(
+			ctx,
+			rdm.Redis,
+			[]string{rdm._waitingKey(queueName, tenants[0]), delayed, status, worker, items, rdm._queueStructure(queueName, "Enqueued"), rdm._activeKey(queueName, tenants[0])},
+			itemId,
+			tenants[0],
+		).Text()
+		if err != nil {
+			return err
+		}
+	}
+
+	if result == "changed" {
+		return redis.TxFailedErr
+	}
+
+	if result == "missing" {
+		return ErrNotFound
+	}
+
+	if result != "cancelled" {
+		return ErrNotCancellable
+	}
+
+	return nil
+}
+
 // TotalRetry get total failed retry.
 func (rdm *RedisDatabaseManager) TotalRetry(queueName string, item data.Queueable) int {
 	_, _, _, _, retry, _, _ := rdm._queueStructures(queueName)
@@ -218,11 +1187,160 @@ func (rdm *RedisDatabaseManager) TotalRetry(queueName string, item data.Queueabl
 	return int(resP)
 }
 
+// SaveDeliveryAttempt save single delivery attempt in item attempts history.
+// Only last maxAttempts attempts are kept, and history expires ttl after last attempt, when they are more than zero.
+func (rdm *RedisDatabaseManager) SaveDeliveryAttempt(queueName string, item data.Queueable, attempt data.DeliveryAttempt, maxAttempts int64, ttl time.Duration) error {
+	attempts := fmt.Sprintf("%v:%v", rdm._queueStructure(queueName, "Attempts"), item.ID)
+
+	tx := rdm.Redis.TxPipeline()
+	tx.RPush(ctx, attempts, attempt)
+	if maxAttempts > 0 {
+		tx.LTrim(ctx, attempts, -maxAttempts, -1)
+	}
+	if ttl > 0 {
+		tx.Expire(ctx, attempts, ttl)
+	}
+
+	if _, err := tx.Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SendCallbackToStream add callback to stream, stream trimmed to approximately max length when its more than zero.
+func (rdm *RedisDatabaseManager) SendCallbackToStream(stream string, callback map[string]interface{}, maxLength int64) (id string, err error) {
+	return rdm.Redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: maxLength,
+		Approx: maxLength > 0,
+		Values: callback,
+	}).Result()
+}
+
+// TenantUsage get tenant active tasks and transcoded seconds today.
+func (rdm *RedisDatabaseManager) TenantUsage(queueName string, tenant string) (data.TenantUsage, error) {
+	pipe := rdm.Redis.Pipeline()
+	activeCmd := pipe.SCard(ctx, rdm._tenantStructure(queueName, "TenantActive", tenant))
+	usageCmd := pipe.Get(ctx, rdm._tenantUsageKey(queueName, tenant, time.Now()))
+
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return data.TenantUsage{}, err
+	}
+
+	usage := data.TenantUsage{ActiveTasks: activeCmd.Val()}
+	if usageCmd.Err() == nil {
+		usage.TranscodeSecondsToday, _ = usageCmd.Float64()
+	}
+
+	return usage, nil
+}
+
+// AddTenantTranscodeSeconds add transcoded input video seconds to tenant usage of today.
+func (rdm *RedisDatabaseManager) AddTenantTranscodeSeconds(queueName string, tenant string, seconds float64) error {
+	usageKey := rdm._tenantUsageKey(queueName, tenant, time.Now())
+
+	tx := rdm.Redis.TxPipeline()
+	tx.IncrByFloat(ctx, usageKey, seconds)
+	tx.Expire(ctx, usageKey, 48*time.Hour)
+
+	_, err := tx.Exec(ctx)
+	return err
+}
+
+// ReserveIdempotencyKey save record under idempotency key when key is not used, otherwise return saved record.
+func (rdm *RedisDatabaseManager) ReserveIdempotencyKey(key string, record data.IdempotencyRecord, ttl time.Duration) (data.IdempotencyRecord, bool, error) {
+	idempotencyKey := strings.Replace(rdm.Config.GetString("Database.Redis.Structure.Idempotency"), "{{key}}", key, 1)
+
+	reserved, err := rdm.Redis.SetNX(ctx, idempotencyKey, record, ttl).Result()
+	if err != nil {
+		return data.IdempotencyRecord{}, false, err
+	}
+
+	if reserved {
+		return record, true, nil
+	}
+
+	recordAsJsonString, err := rdm.Redis.Get(ctx, idempotencyKey).Result()
+
+	// key expired between both calls, try again.
+	if errors.Is(err, redis.Nil) {
+		return rdm.ReserveIdempotencyKey(key, record, ttl)
+	}
+
+	if err != nil {
+		return data.IdempotencyRecord{}, false, err
+	}
+
+	var saved data.IdempotencyRecord
+	if err := json.Unmarshal([]byte(recordAsJsonString), &saved); err != nil {
+		return data.IdempotencyRecord{}, false, err
+	}
+
+	return saved, false, nil
+}
+
+// ReleaseIdempotencyKey remove idempotency key so it can be used again.
+func (rdm *RedisDatabaseManager) ReleaseIdempotencyKey(key string) error {
+	return rdm.Redis.Del(ctx, strings.Replace(rdm.Config.GetString("Database.Redis.Structure.Idempotency"), "{{key}}", key, 1)).Err()
+}
+
+// TakeToken take one token from rate limit bucket, return how long to wait when bucket is empty.
+func (rdm *RedisDatabaseManager) TakeToken(bucket string, rate float64, burst int) (allowed bool, retryAfter time.Duration, err error) {
+	bucketKey := strings.Replace(rdm.Config.GetString("Database.Redis.Structure.SafeGuard.RateLimit"), "{{bucket}}", bucket, 1)
+
+	result, err := takeTokenScript.Run(ctx, rdm.Redis, []string{bucketKey}, rate, burst, time.Now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// SendAbuseLog add rejected request to abuse stream, stream trimmed to approximately max length when its more than zero.
+func (rdm *RedisDatabaseManager) SendAbuseLog(entry map[string]interface{}, maxLength int64) error {
+	return rdm.Redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: rdm.Config.GetString("Database.Redis.Structure.SafeGuard.Abuse"),
+		MaxLen: maxLength,
+		Approx: true,
+		Values: entry,
+	}).Err()
+}
+
+// SaveApiKey create or update api key.
+func (rdm *RedisDatabaseManager) SaveApiKey(key data.ApiKey) error {
+	return rdm.Redis.HSet(ctx, rdm.Config.GetString("Database.Redis.Structure.ControlPanel.Users"), key.ID, key).Err()
+}
+
+// GetApiKey get api key by its id.
+func (rdm *RedisDatabaseManager) GetApiKey(keyId string) (data.ApiKey, error) {
+	keyAsJsonString, err := rdm.Redis.HGet(ctx, rdm.Config.GetString("Database.Redis.Structure.ControlPanel.Users"), keyId).Result()
+
+	if errors.Is(err, redis.Nil) {
+		return data.ApiKey{}, ErrNotFound
+	}
+
+	if err != nil {
+		return data.ApiKey{}, err
+	}
+
+	var key data.ApiKey
+	if err := json.Unmarshal([]byte(keyAsJsonString), &key); err != nil {
+		return data.ApiKey{}, err
+	}
+
+	return key, nil
+}
+
 // SendSystemRadarScannerData send system radar scanning data content full information about running node.
-func (rdm *RedisDatabaseManager) SendSystemRadarScannerData(scanner map[string]interface{}) error {
+func (rdm *RedisDatabaseManager) SendSystemRadarScannerData(radar data.Radar) error {
 	res := rdm.Redis.XAdd(ctx, &redis.XAddArgs{
 		Stream: rdm.Config.GetString("Database.Redis.Structure.Cluster.Radar"),
-		Values: scanner,
+		Values: map[string]interface{}{
+			"schema_version":  radar.SchemaVersion,
+			"system_radar_id": radar.SystemRadarId,
+			"radar":           radar,
+		},
 	})
 
 	if res.Err() != nil {
@@ -232,6 +1350,96 @@ func (rdm *RedisDatabaseManager) SendSystemRadarScannerData(scanner map[string]i
 	return nil
 }
 
+// _radarFromStream decode radar entry, entries of other schema versions are reported as not usable.
+func _radarFromStream(values map[string]interface{}) (data.Radar, bool, error) {
+	if values["schema_version"] != strconv.Itoa(data.RadarSchemaVersion) {
+		return data.Radar{}, false, nil
+	}
+
+	radarAsJsonString, _ := values["radar"].(string)
+
+	var radar data.Radar
+	if err := json.Unmarshal([]byte(radarAsJsonString), &radar); err != nil {
+		return data.Radar{}, false, fmt.Errorf("cannot decode radar: %w", err)
+	}
+
+	return radar, true, nil
+}
+
+// _details read items with their status, missing items are skipped.
+func (rdm *RedisDatabaseManager) _details(queueName string, itemIds []string) ([]data.QueueableDetails, error) {
+	_, status, worker, _, _, items, logs := rdm._queueStructures(queueName)
+
+	if len(itemIds) == 0 {
+		return []data.QueueableDetails{}, nil
+	}
+
+	pipe := rdm.Redis.Pipeline()
+	itemsCmd := pipe.HMGet(ctx, items, itemIds...)
+	statusCmd := pipe.HMGet(ctx, status, itemIds...)
+	workerCmd := pipe.HMGet(ctx, worker, itemIds...)
+	logsCmd := pipe.HMGet(ctx, logs, itemIds...)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	var details []data.QueueableDetails
+	for i := range itemIds {
+		itemAsJsonString, ok := itemsCmd.Val()[i].(string)
+		if !ok {
+			continue
+		}
+
+		var item data.Queueable
+		if err := json.Unmarshal([]byte(itemAsJsonString), &item); err != nil {
+			return nil, err
+		}
+
+		detail := data.QueueableDetails{Item: item}
+		detail.Status, _ = statusCmd.Val()[i].(string)
+		detail.Worker, _ = workerCmd.Val()[i].(string)
+		detail.Log, _ = logsCmd.Val()[i].(string)
+
+		detail.ErrorCode = item.ErrorCode
+		if detail.Status == "cancelled" {
+			detail.ErrorCode = data.CancelledErrorCode
+		}
+		if detail.ErrorCode != "" {
+			retryable := detail.ErrorCode.Retryable()
+			detail.Retryable = &retryable
+		}
+
+		// score of waiting item is its priority after aging, offset by time passed since aging epoch.
+		if detail.Status == "waiting" {
+			score, err := rdm._waitingItemScore(queueName, item)
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return nil, err
+			}
+			if err == nil {
+				rate, epoch, err := rdm._agingSettings(rdm.Redis, queueName)
+				if err != nil {
+					return nil, err
+				}
+				effectivePriority := score - float64(time.Now().UnixMilli()-epoch)*rate
+				detail.EffectivePriority = &effectivePriority
+			}
+		}
+
+		details = append(details, detail)
+	}
+
+	return details, nil
+}
+
+// _blockingRedis client used for blocking reads.
+func (rdm *RedisDatabaseManager) _blockingRedis() *redis.Client {
+	if rdm.BlockingRedis != nil {
+		return rdm.BlockingRedis
+	}
+	return rdm.Redis
+}
+
 // _queueStructures shortcut to fetch all key names
 func (rdm *RedisDatabaseManager) _queueStructures(queueName string) (waiting, status, worker, processing, retry, items, logs string) {
 	waiting = strings.Replace(rdm.Config.GetString("Database.Redis.Structure.Queue.Waiting"), "{{name}}", queueName, 1)
@@ -243,3 +1451,101 @@ func (rdm *RedisDatabaseManager) _queueStructures(queueName string) (waiting, st
 	logs = strings.Replace(rdm.Config.GetString("Database.Redis.Structure.Queue.Logs"), "{{name}}", queueName, 1)
 	return
 }
+
+// _queueStructure shortcut to fetch single key name
+func (rdm *RedisDatabaseManager) _queueStructure(queueName, structure string) string {
+	return strings.Replace(rdm.Config.GetString("Database.Redis.Structure.Queue."+structure), "{{name}}", queueName, 1)
+}
+
+// _tenantStructure shortcut to fetch key name of tenant structure
+func (rdm *RedisDatabaseManager) _tenantStructure(queueName, structure, tenant string) string {
+	return strings.Replace(rdm._queueStructure(queueName, structure), "{{tenant}}", tenant, 1)
+}
+
+// _waitingKey waiting queue of tenant, items without tenant wait in shared waiting queue.
+func (rdm *RedisDatabaseManager) _waitingKey(queueName, tenant string) string {
+	if tenant == "" {
+		waiting, _, _, _, _, _, _ := rdm._queueStructures(queueName)
+		return waiting
+	}
+	return rdm._tenantStructure(queueName, "TenantWaiting", tenant)
+}
+
+// _activeKey active items of tenant, it is empty for items without tenant.
+func (rdm *RedisDatabaseManager) _activeKey(queueName, tenant string) string {
+	if tenant == "" {
+		return ""
+	}
+	return rdm._tenantStructure(queueName, "TenantActive", tenant)
+}
+
+// _itemTenants read tenant of each item, it is empty for missing items and items without tenant.
+func (rdm *RedisDatabaseManager) _itemTenants(queueName string, itemIds []string) ([]string, error) {
+	tenants := make([]string, len(itemIds))
+	if len(itemIds) == 0 {
+		return tenants, nil
+	}
+
+	_, _, _, _, _, items, _ := rdm._queueStructures(queueName)
+	values, err := rdm.Redis.HMGet(ctx, items, itemIds...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		itemAsJsonString, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		var item data.Queueable
+		if err := json.Unmarshal([]byte(itemAsJsonString), &item); err == nil {
+			tenants[i] = item.Tenant
+		}
+	}
+
+	return tenants, nil
+}
+
+// _agingSettings read aging rate per millisecond and epoch saved with queue, rate is zero until aging is enabled.
+func (rdm *RedisDatabaseManager) _agingSettings(c redis.Cmdable, queueName string) (rate float64, epoch int64, err error) {
+	settings, err := c.HMGet(ctx, rdm._queueStructure(queueName, "Aging"), "rate", "epoch").Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if value, ok := settings[0].(string); ok {
+		rate, _ = strconv.ParseFloat(value, 64)
+	}
+	if value, ok := settings[1].(string); ok {
+		epoch, _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	return rate, epoch, nil
+}
+
+// waitingScore score of item in waiting queue, items with lower score are dequeued first.
+// Enqueued time is added by aging rate, so item waiting longer gets ahead of newer items without its score being changed.
+func waitingScore(priority float64, enqueuedAt int64, rate float64, epoch int64) float64 {
+	return priority + float64(enqueuedAt-epoch)*rate
+}
+
+// _waitingMember member of item in waiting queue, it starts with time item was enqueued.
+func _waitingMember(enqueuedAt int64, itemId string) string {
+	return fmt.Sprintf("%d:%s", enqueuedAt, itemId)
+}
+
+// _waitingItemScore score of waiting item, its member is found from enqueued time saved by item id.
+func (rdm *RedisDatabaseManager) _waitingItemScore(queueName string, item data.Queueable) (float64, error) {
+	enqueuedAt, err := rdm.Redis.ZScore(ctx, rdm._queueStructure(queueName, "Enqueued"), item.ID).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	return rdm.Redis.ZScore(ctx, rdm._waitingKey(queueName, item.Tenant), _waitingMember(int64(enqueuedAt), item.ID)).Result()
+}
+
+// _tenantUsageKey key holding tenant usage of the day (UTC).
+func (rdm *RedisDatabaseManager) _tenantUsageKey(queueName, tenant string, day time.Time) string {
+	return strings.Replace(rdm._tenantStructure(queueName, "TenantUsage", tenant), "{{date}}", day.UTC().Format("2006-01-02"), 1)
+}
@@ -0,0 +1,573 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+)
+
+// newTestRedisDatabaseManager create database manager backed by in memory redis and repository config.
+func newTestRedisDatabaseManager(t *testing.T) *RedisDatabaseManager {
+	v := viper.New()
+	v.SetConfigFile("../../config.yaml")
+	assert.NoError(t, v.ReadInConfig())
+
+	return &RedisDatabaseManager{
+		Redis:  redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}),
+		Config: config.New(&config.ViperConfigManager{Viper: v}),
+	}
+}
+
+func TestRedisDatabaseManager_DequeueFairShare(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	for i, tenant := range []string{"a", "a", "a", "b", "", "b"} {
+		assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: string(rune('0' + i)), Priority: 1, Tenant: tenant}))
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	var order []string
+	for {
+		item, err := rdm.Dequeue("queue", "worker", nil)
+		if err != nil {
+			break
+		}
+		order = append(order, item.ID)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	assert.Equal(t, []string{"4", "0", "3", "1", "5", "2"}, order)
+}
+
+func TestRedisDatabaseManager_TenantUsage(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "1", Tenant: "a"}))
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "2", Tenant: "a"}))
+	assert.NoError(t, rdm.Delay("queue", data.Queueable{ID: "3", Tenant: "a"}, time.Now().Add(time.Hour)))
+	assert.NoError(t, rdm.AddTenantTranscodeSeconds("queue", "a", 90.5))
+
+	usage, err := rdm.TenantUsage("queue", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, data.TenantUsage{ActiveTasks: 3, TranscodeSecondsToday: 90.5}, usage)
+
+	item, err := rdm.Dequeue("queue", "worker", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, rdm.Finished("queue", item))
+	assert.NoError(t, rdm.Cancel("queue", "2"))
+	assert.NoError(t, rdm.Cancel("queue", "3"))
+
+	usage, err = rdm.TenantUsage("queue", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), usage.ActiveTasks)
+}
+
+func TestRedisDatabaseManager_PromoteDelayedTenant(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.Delay("queue", data.Queueable{ID: "1", Tenant: "b"}, time.Now().Add(-time.Second)))
+	assert.NoError(t, rdm.PromoteDelayed("queue"))
+
+	item, err := rdm.Dequeue("queue", "worker", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", item.ID)
+	assert.Equal(t, "b", item.Tenant)
+}
+
+func TestRedisDatabaseManager_CancelWaiting(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "1", Tenant: "a"}))
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "2", Tenant: "a"}))
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "3"}))
+	assert.NoError(t, rdm.Cancel("queue", "1"))
+	assert.NoError(t, rdm.Cancel("queue", "3"))
+	assert.ErrorIs(t, rdm.Cancel("queue", "missing"), ErrNotFound)
+
+	usage, err := rdm.TenantUsage("queue", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), usage.ActiveTasks)
+
+	item, err := rdm.Dequeue("queue", "worker", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", item.ID)
+
+	_, err = rdm.Dequeue("queue", "worker", nil)
+	assert.Error(t, err)
+}
+
+func TestRedisDatabaseManager_EnqueueUnique(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.EnqueueUnique("queue", data.Queueable{ID: "1"}, 0))
+	assert.ErrorIs(t, rdm.EnqueueUnique("queue", data.Queueable{ID: "1"}, 0), ErrAlreadyExists)
+}
+
+func TestRedisDatabaseManager_ReserveIdempotencyKey(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	record, reserved, err := rdm.ReserveIdempotencyKey("key", data.IdempotencyRecord{TaskId: "1", Fingerprint: "a"}, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, "1", record.TaskId)
+
+	record, reserved, err = rdm.ReserveIdempotencyKey("key", data.IdempotencyRecord{TaskId: "2", Fingerprint: "b"}, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, data.IdempotencyRecord{TaskId: "1", Fingerprint: "a"}, record)
+
+	assert.NoError(t, rdm.ReleaseIdempotencyKey("key"))
+
+	_, reserved, err = rdm.ReserveIdempotencyKey("key", data.IdempotencyRecord{TaskId: "2"}, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+}
+
+func TestRedisDatabaseManager_TakeToken(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	for i := 0; i < 3; i++ {
+		allowed, _, err := rdm.TakeToken("ip:127.0.0.1", 1, 3)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, retryAfter, err := rdm.TakeToken("ip:127.0.0.1", 1, 3)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Greater(t, retryAfter, time.Duration(0))
+	assert.LessOrEqual(t, retryAfter, time.Second)
+
+	allowed, _, err = rdm.TakeToken("ip:127.0.0.2", 1, 3)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestRedisDatabaseManager_EnqueueBatch(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "1"}))
+
+	existing, _, err := rdm.EnqueueBatch("queue", []data.Queueable{{ID: "1"}, {ID: "2"}, {ID: "3"}}, "batch", time.Hour, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, existing)
+
+	_, err = rdm.Dequeue("queue", "worker", nil)
+	assert.NoError(t, err)
+
+	statuses, err := rdm.BatchStatuses("queue", "batch")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), statuses["waiting"]+statuses["processing"])
+
+	_, err = rdm.BatchStatuses("queue", "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// repeated id in same batch is kept only once, tenant and scheduled items go to their queues.
+	existing, _, err = rdm.EnqueueBatch("queue", []data.Queueable{
+		{ID: "4", Tenant: "a"},
+		{ID: "4", Tenant: "a"},
+		{ID: "5", RunAt: time.Now().Add(time.Hour).UnixMilli()},
+	}, "", time.Hour, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4"}, existing)
+
+	details, err := rdm.Details("queue", "4")
+	assert.NoError(t, err)
+	assert.Equal(t, "waiting", details.Status)
+	assert.Equal(t, "a", details.Item.Tenant)
+
+	details, err = rdm.Details("queue", "5")
+	assert.NoError(t, err)
+	assert.Equal(t, "delayed", details.Status)
+}
+
+func TestRedisDatabaseManager_EnqueueMaxActive(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.EnqueueUnique("queue", data.Queueable{ID: "1", Tenant: "a"}, 2))
+	assert.NoError(t, rdm.EnqueueUnique("queue", data.Queueable{ID: "2", Tenant: "a", RunAt: time.Now().Add(time.Hour).UnixMilli()}, 2))
+
+	// existing id is reported before limit, so same item can be sent again.
+	assert.ErrorIs(t, rdm.EnqueueUnique("queue", data.Queueable{ID: "1", Tenant: "a"}, 2), ErrAlreadyExists)
+	assert.ErrorIs(t, rdm.EnqueueUnique("queue", data.Queueable{ID: "3", Tenant: "a"}, 2), ErrActiveLimitReached)
+	assert.NoError(t, rdm.EnqueueUnique("queue", data.Queueable{ID: "4", Tenant: "b"}, 2))
+	assert.NoError(t, rdm.EnqueueUnique("queue", data.Queueable{ID: "5"}, 2))
+
+	// slots are reserved one by one inside batch.
+	existing, limited, err := rdm.EnqueueBatch("queue", []data.Queueable{{ID: "6", Tenant: "b"}, {ID: "7", Tenant: "b"}, {ID: "4", Tenant: "b"}}, "", time.Hour, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4"}, existing)
+	assert.Equal(t, []string{"7"}, limited)
+
+	_, err = rdm.Details("queue", "3")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	usage, err := rdm.TenantUsage("queue", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), usage.ActiveTasks)
+}
+
+func TestRedisDatabaseManager_EnqueueScheduled(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	runAt := time.Now().Add(50 * time.Millisecond).UnixMilli()
+	assert.NoError(t, rdm.EnqueueUnique("queue", data.Queueable{ID: "1", RunAt: runAt}, 0))
+
+	assert.NoError(t, rdm.PromoteDelayed("queue"))
+	_, err := rdm.Dequeue("queue", "worker", nil)
+	assert.Error(t, err)
+
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, rdm.PromoteDelayed("queue"))
+
+	item, err := rdm.Dequeue("queue", "worker", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", item.ID)
+}
+
+func TestRedisDatabaseManager_AgeWaiting(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	// rate is saved with queue, so it applies to items enqueued by any node.
+	assert.NoError(t, rdm.AgeWaiting("queue", 500, 0, 0))
+
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "old", Priority: 10}))
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "tenant", Priority: 10, Tenant: "a"}))
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "new", Priority: 5}))
+
+	// waiting improves priority without any aging run.
+	details, err := rdm.Details("queue", "old")
+	assert.NoError(t, err)
+	assert.Less(t, *details.EffectivePriority, 0.0)
+
+	details, err = rdm.Details("queue", "tenant")
+	assert.NoError(t, err)
+	assert.Less(t, *details.EffectivePriority, 0.0)
+
+	item, err := rdm.Dequeue("queue", "worker", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", item.ID)
+
+	cappedAt := time.Now()
+	assert.NoError(t, rdm.AgeWaiting("queue", 500, 20*time.Millisecond, -100))
+
+	// capped item keeps aging after it is capped, by 0.5 for every millisecond.
+	details, err = rdm.Details("queue", "tenant")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, *details.EffectivePriority, -99.0)
+	assert.GreaterOrEqual(t, *details.EffectivePriority, -101-float64(time.Since(cappedAt).Milliseconds())*0.5)
+
+	details, err = rdm.Details("queue", "new")
+	assert.NoError(t, err)
+	assert.Greater(t, *details.EffectivePriority, -10.0)
+
+	// disabling aging scores waiting items again with their priority only.
+	assert.NoError(t, rdm.AgeWaiting("queue", 0, 0, 0))
+
+	details, err = rdm.Details("queue", "tenant")
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, *details.EffectivePriority)
+
+	details, err = rdm.Details("queue", "new")
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, *details.EffectivePriority)
+
+	// enabling it again scores old and new items with same rate.
+	assert.NoError(t, rdm.AgeWaiting("queue", 500, 0, 0))
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "newest", Priority: 5}))
+
+	tenant, err := rdm.Details("queue", "tenant")
+	assert.NoError(t, err)
+	newest, err := rdm.Details("queue", "newest")
+	assert.NoError(t, err)
+	assert.Less(t, *tenant.EffectivePriority, *newest.EffectivePriority)
+}
+
+func TestRedisDatabaseManager_UpdateWaiting(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "1", Priority: 10, Tenant: "a"}))
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "2", Priority: 5, Tenant: "a"}))
+	assert.NoError(t, rdm.Delay("queue", data.Queueable{ID: "3", Priority: 5}, time.Now().Add(time.Hour)))
+
+	setPriority := func(item *data.Queueable) error {
+		item.Priority = 1
+		return nil
+	}
+
+	updated, err := rdm.UpdateWaiting("queue", "1", setPriority)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, updated.Priority)
+
+	details, err := rdm.Details("queue", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, details.Item.Priority)
+	assert.Equal(t, 1.0, *details.EffectivePriority)
+
+	_, err = rdm.UpdateWaiting("queue", "3", setPriority)
+	assert.NoError(t, err)
+
+	item, err := rdm.Dequeue("queue", "worker", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", item.ID)
+
+	_, err = rdm.UpdateWaiting("queue", "1", setPriority)
+	assert.ErrorIs(t, err, ErrNotWaiting)
+
+	_, err = rdm.UpdateWaiting("queue", "missing", setPriority)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRedisDatabaseManager_DequeueCapabilities(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "gpu", Priority: 1, Tenant: "a", Capabilities: []string{"hevc", "nvenc"}}))
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "cpu", Priority: 2, Tenant: "a"}))
+
+	item, err := rdm.Dequeue("queue", "cpu-worker", []string{"hevc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "cpu", item.ID)
+
+	_, err = rdm.Dequeue("queue", "cpu-worker", []string{"hevc"})
+	assert.Error(t, err)
+
+	item, err = rdm.Dequeue("queue", "gpu-worker", []string{"hevc", "nvenc", "high-mem"})
+	assert.NoError(t, err)
+	assert.Equal(t, "gpu", item.ID)
+}
+
+func TestRedisDatabaseManager_RequeueExcludedWorker(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "1"}))
+	item, err := rdm.Dequeue("queue", "small-node", nil)
+	assert.NoError(t, err)
+
+	item.ExcludedWorkers = map[string]int64{"small-node": time.Now().Add(time.Hour).UnixMilli()}
+	assert.NoError(t, rdm.Requeue("queue", item, errors.New("not enough disk space"), false))
+	assert.Equal(t, 1, rdm.TotalRetry("queue", item))
+
+	details, err := rdm.Details("queue", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "waiting", details.Status)
+	assert.Equal(t, "not enough disk space", details.Log)
+
+	_, err = rdm.Dequeue("queue", "small-node", nil)
+	assert.Error(t, err)
+
+	item, err = rdm.Dequeue("queue", "large-node", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", item.ID)
+
+	// failed item sent back is a retry.
+	assert.NoError(t, rdm.Requeue("queue", item, errors.New("transcode failed"), true))
+	assert.Equal(t, 2, rdm.TotalRetry("queue", item))
+}
+
+func TestRedisDatabaseManager_DetailsErrorCode(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "1"}))
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "2"}))
+
+	item, err := rdm.Dequeue("queue", "worker", nil)
+	assert.NoError(t, err)
+	item.ErrorCode = data.InputUnreadableErrorCode
+	assert.NoError(t, rdm.Failed("queue", item, errors.New("moov atom not found")))
+	assert.NoError(t, rdm.Cancel("queue", "2"))
+
+	failed, err := rdm.Details("queue", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, data.InputUnreadableErrorCode, failed.ErrorCode)
+	assert.False(t, *failed.Retryable)
+
+	cancelled, err := rdm.Details("queue", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, data.CancelledErrorCode, cancelled.ErrorCode)
+	assert.False(t, *cancelled.Retryable)
+}
+
+func TestRedisDatabaseManager_QueueStatuses(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	for _, id := range []string{"1", "2", "3"} {
+		assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: id}))
+	}
+	assert.NoError(t, rdm.Delay("queue", data.Queueable{ID: "4"}, time.Now().Add(time.Hour)))
+
+	item, err := rdm.Dequeue("queue", "worker", nil)
+	assert.NoError(t, err)
+	assert.NotZero(t, item.EnqueuedAt)
+	assert.NoError(t, rdm.Finished("queue", item))
+	assert.NoError(t, rdm.Cancel("queue", "2"))
+
+	statuses, err := rdm.QueueStatuses("queue")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"waiting": 1, "delayed": 1, "finished": 1, "cancelled": 1}, statuses)
+}
+
+func TestRedisDatabaseManager_ClusterWorkers(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	assert.NoError(t, rdm.SendHeartbeat("node-a", "transcoder-a", "VideoTranscoding"))
+	assert.NoError(t, rdm.SendHeartbeat("node-a", "radar-a", "SystemRadar"))
+
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "1"}))
+	assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: "2"}))
+	item, err := rdm.Dequeue("queue", "transcoder-a", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, rdm.Finished("queue", item))
+	working, err := rdm.Dequeue("queue", "transcoder-a", nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, rdm.SendSystemRadarScannerData(data.Radar{SchemaVersion: data.RadarSchemaVersion, SystemRadarId: "radar-a", Cpu: data.RadarCpu{Percent: []float64{10, 20}}}))
+	assert.NoError(t, rdm.SendSystemRadarScannerData(data.Radar{SchemaVersion: data.RadarSchemaVersion, SystemRadarId: "radar-a", Cpu: data.RadarCpu{Percent: []float64{30, 40}}}))
+	// entry of older schema sent by node not upgraded yet is skipped.
+	assert.NoError(t, rdm.Redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: rdm.Config.GetString("Database.Redis.Structure.Cluster.Radar"),
+		Values: map[string]interface{}{"SystemRadarId": "radar-a", "CpuUsageAll": "[50,60]"},
+	}).Err())
+
+	workers, err := rdm.ClusterWorkers("queue", time.Hour, 100)
+	assert.NoError(t, err)
+	assert.Len(t, workers, 2)
+
+	byType := map[string]data.ClusterWorker{}
+	for _, worker := range workers {
+		assert.Equal(t, "node-a", worker.Node)
+		assert.NotZero(t, worker.LastSeen)
+		byType[worker.WorkerType] = worker
+	}
+
+	transcoder := byType["VideoTranscoding"]
+	assert.Equal(t, []string{working.ID}, transcoder.CurrentTasks)
+	assert.Equal(t, int64(1), transcoder.FinishedTasks)
+	assert.Nil(t, transcoder.Radar)
+
+	radar := byType["SystemRadar"]
+	if assert.NotNil(t, radar.Radar) {
+		assert.Equal(t, []float64{30, 40}, radar.Radar.Cpu.Percent)
+	}
+}
+
+func TestRedisDatabaseManager_ListTenant(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	for i := 0; i < 30; i++ {
+		tenant := "b"
+		if i%10 == 0 {
+			tenant = "a"
+		}
+		assert.NoError(t, rdm.Enqueue("queue", data.Queueable{ID: fmt.Sprintf("%d", i), Tenant: tenant}))
+	}
+
+	// pages of tenant are filled even when most scanned items belong to other tenants.
+	var ids []string
+	var cursor uint64
+	for {
+		items, nextCursor, err := rdm.List("queue", "waiting", "a", cursor, 2)
+		assert.NoError(t, err)
+		for _, item := range items {
+			assert.Equal(t, "a", item.Item.Tenant)
+			ids = append(ids, item.Item.ID)
+		}
+		if nextCursor != 0 {
+			assert.GreaterOrEqual(t, len(items), 2)
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+	assert.ElementsMatch(t, []string{"0", "10", "20"}, ids)
+
+	items, _, err := rdm.List("queue", "", "", 0, 100)
+	assert.NoError(t, err)
+	assert.Len(t, items, 30)
+}
+
+func TestRedisDatabaseManager_ReadProcessingUsesBlockingClient(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+	rdm.BlockingRedis = rdm.Redis
+	// every command sent with main client fails, so read must go through blocking client.
+	rdm.Redis = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+
+	_, _, _, processing, _, _, _ := rdm._queueStructures("queue")
+	assert.NoError(t, rdm.BlockingRedis.XAdd(ctx, &redis.XAddArgs{Stream: processing + ":1", Values: map[string]interface{}{"progress": "10"}}).Err())
+
+	events, err := rdm.ReadProcessing("queue", "1", "", 10*time.Millisecond)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "10", events[0].Values["progress"])
+	}
+}
+
+func TestRedisDatabaseManager_SendCallbackToStream(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+
+	for i := 0; i < 5; i++ {
+		id, err := rdm.SendCallbackToStream("callbacks", map[string]interface{}{"task_id": i}, 3)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, id)
+	}
+
+	length, err := rdm.Redis.XLen(ctx, "callbacks").Result()
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, length, int64(3))
+
+	// zero max length keeps every entry.
+	for i := 0; i < 5; i++ {
+		_, err := rdm.SendCallbackToStream("unlimited", map[string]interface{}{"task_id": i}, 0)
+		assert.NoError(t, err)
+	}
+
+	length, err = rdm.Redis.XLen(ctx, "unlimited").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), length)
+}
+
+func TestRedisDatabaseManager_SaveDeliveryAttempt(t *testing.T) {
+	rdm := newTestRedisDatabaseManager(t)
+	item := data.Queueable{ID: "callback-1"}
+
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, rdm.SaveDeliveryAttempt("queue", item, data.DeliveryAttempt{Attempt: i}, 3, time.Hour))
+	}
+
+	attempts := rdm._queueStructure("queue", "Attempts") + ":callback-1"
+	saved, err := rdm.Redis.LRange(ctx, attempts, 0, -1).Result()
+	assert.NoError(t, err)
+	assert.Len(t, saved, 3)
+	assert.Contains(t, saved[0], `"attempt":3`)
+
+	ttl, err := rdm.Redis.TTL(ctx, attempts).Result()
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), ttl.Seconds(), 1)
+}
@@ -18,6 +18,12 @@
 package filesystem
 
 import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/minio/minio-go/v7"
+	"openseawave.com/rasbora/internal/config"
 	"openseawave.com/rasbora/internal/data"
 )
 
@@ -32,6 +38,7 @@ type Interface interface {
 	RemoveAll(path data.File) error
 	GetFile(file data.File, saveAt data.File) error
 	PutFile(file data.File, saveAt data.File) error
+	Stat(file data.File) (data.FileStat, error)
 }
 
 // NewFileSystem create new file system instance.
@@ -60,3 +67,38 @@ func (f *FileSystem) GetFile(file data.File, saveAt data.File) error {
 func (f *FileSystem) PutFile(file data.File, saveAt data.File) error {
 	return f.fileManager.PutFile(file, saveAt)
 }
+
+// Stat get file size and modification time
+func (f *FileSystem) Stat(file data.File) (data.FileStat, error) {
+	return f.fileManager.Stat(file)
+}
+
+// ForType create file system of type chosen by task, instead of the one selected in config.
+func ForType(fileSystemType data.FileSystemType, cfg config.Config) (*FileSystem, error) {
+	switch fileSystemType {
+	case data.LocalFileSystemType:
+		return NewFileSystem(&LocalFileSystem{}), nil
+	case data.ObjectFileSystemType:
+		objectClient, err := NewObjectClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewFileSystem(&ObjectFileSystem{Minio: objectClient}), nil
+	}
+
+	return nil, fmt.Errorf("unknown filesystem type: %v", fileSystemType)
+}
+
+// IsNotFound report whether err means file or its folder does not exist in file system.
+func IsNotFound(err error) bool {
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket":
+		return true
+	}
+
+	return false
+}
@@ -72,3 +72,16 @@ func (lfs *LocalFileSystem) GetFile(file data.File, saveAt data.File) error {
 func (lfs *LocalFileSystem) PutFile(file data.File, saveAt data.File) error {
 	return os.Rename(file.FullPath(), saveAt.FullPath())
 }
+
+// Stat get file size and modification time.
+func (lfs *LocalFileSystem) Stat(file data.File) (data.FileStat, error) {
+	info, err := os.Stat(file.FullPath())
+	if err != nil {
+		return data.FileStat{}, err
+	}
+
+	return data.FileStat{
+		Size:       info.Size(),
+		ModifiedAt: info.ModTime().UnixMilli(),
+	}, nil
+}
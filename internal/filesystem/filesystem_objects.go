@@ -108,3 +108,21 @@ func (ofs *ObjectFileSystem) PutFile(localFile data.File, saveAsObject data.File
 	)
 	return err
 }
+
+// Stat get object size and modification time.
+func (ofs *ObjectFileSystem) Stat(object data.File) (data.FileStat, error) {
+	info, err := ofs.Minio.StatObject(
+		ctx,
+		object.FilePath,
+		object.FileName,
+		minio.StatObjectOptions{},
+	)
+	if err != nil {
+		return data.FileStat{}, err
+	}
+
+	return data.FileStat{
+		Size:       info.Size,
+		ModifiedAt: info.LastModified.UnixMilli(),
+	}, nil
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check return error when dependency node needs is not usable.
+type Check func(ctx context.Context) error
+
+// Version holds instances
+type Version struct {
+	Version   string `json:"version"`
+	GitHash   string `json:"git_hash"`
+	BuildTime string `json:"build_time"`
+	Support   string `json:"support"`
+}
+
+// Readiness holds instances
+type Readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// MinLoopStaleAfter shortest time without beat after loop is considered stuck.
+var MinLoopStaleAfter = 5 * time.Minute
+
+// CheckTimeout time allowed for each readiness check.
+var CheckTimeout = 5 * time.Second
+
+var (
+	_mutex   sync.Mutex
+	_version Version
+	_checks  = map[string]Check{}
+	_loops   = map[string]*loop{}
+)
+
+// loop holds last beat of component loop.
+type loop struct {
+	staleAfter time.Duration
+	lastBeat   time.Time
+}
+
+// SetVersion set version served at /version.
+func SetVersion(version Version) {
+	_mutex.Lock()
+	defer _mutex.Unlock()
+	_version = version
+}
+
+// AddCheck add readiness check, check with same name is replaced.
+func AddCheck(name string, check Check) {
+	_mutex.Lock()
+	defer _mutex.Unlock()
+	_checks[name] = check
+}
+
+// WatchLoop make readiness depend on loop beating at least every three intervals, and never less than MinLoopStaleAfter.
+func WatchLoop(name string, interval time.Duration) {
+	staleAfter := 3 * interval
+	if staleAfter < MinLoopStaleAfter {
+		staleAfter = MinLoopStaleAfter
+	}
+
+	_mutex.Lock()
+	defer _mutex.Unlock()
+	_loops[name] = &loop{staleAfter: staleAfter, lastBeat: time.Now()}
+}
+
+// Beat record loop is alive.
+func Beat(name string) {
+	_mutex.Lock()
+	defer _mutex.Unlock()
+	if l, ok := _loops[name]; ok {
+		l.lastBeat = time.Now()
+	}
+}
+
+// Ready run all readiness checks, node is ready when every check passes and every watched loop is alive.
+func Ready(ctx context.Context) Readiness {
+	_mutex.Lock()
+	checks := make(map[string]Check, len(_checks))
+	for name, check := range _checks {
+		checks[name] = check
+	}
+	readiness := Readiness{Ready: true, Checks: map[string]string{}}
+	for name, l := range _loops {
+		readiness.Checks["loop:"+name] = "ok"
+		if since := time.Since(l.lastBeat); since > l.staleAfter {
+			readiness.Ready = false
+			readiness.Checks["loop:"+name] = fmt.Sprintf("no beat since %v", since.Round(time.Second))
+		}
+	}
+	_mutex.Unlock()
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+		err := checks[name](checkCtx)
+		cancel()
+
+		readiness.Checks[name] = "ok"
+		if err != nil {
+			readiness.Ready = false
+			readiness.Checks[name] = err.Error()
+		}
+	}
+
+	return readiness
+}
+
+// Executable check file at path, or found in PATH, exists and can be executed.
+func Executable(path string) Check {
+	return func(ctx context.Context) error {
+		_, err := exec.LookPath(path)
+		return err
+	}
+}
+
+// Handler serve /healthz, /readyz and /version.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	Register(mux)
+	return mux
+}
+
+// Register add /healthz, /readyz and /version to mux.
+func Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_writeJson(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		readiness := Ready(r.Context())
+		status := http.StatusOK
+		if !readiness.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		_writeJson(w, status, readiness)
+	})
+
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		_mutex.Lock()
+		version := _version
+		_mutex.Unlock()
+		_writeJson(w, http.StatusOK, version)
+	})
+}
+
+// _writeJson write value as json response.
+func _writeJson(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
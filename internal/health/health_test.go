@@ -0,0 +1,116 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// resetHealth remove checks and loops added by other tests.
+func resetHealth(t *testing.T) {
+	_mutex.Lock()
+	defer _mutex.Unlock()
+	_checks = map[string]Check{}
+	_loops = map[string]*loop{}
+	_version = Version{}
+}
+
+func TestReady(t *testing.T) {
+	resetHealth(t)
+
+	AddCheck("redis", func(ctx context.Context) error { return nil })
+	WatchLoop("VideoTranscoding", time.Second)
+
+	readiness := Ready(context.Background())
+	assert.True(t, readiness.Ready)
+	assert.Equal(t, map[string]string{"redis": "ok", "loop:VideoTranscoding": "ok"}, readiness.Checks)
+
+	AddCheck("ffmpeg", func(ctx context.Context) error { return errors.New("ffmpeg not found") })
+
+	readiness = Ready(context.Background())
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, "ffmpeg not found", readiness.Checks["ffmpeg"])
+	assert.Equal(t, "ok", readiness.Checks["redis"])
+}
+
+func TestReady_StaleLoop(t *testing.T) {
+	resetHealth(t)
+
+	WatchLoop("SystemRadar", time.Minute)
+	_loops["SystemRadar"].lastBeat = time.Now().Add(-time.Hour)
+
+	readiness := Ready(context.Background())
+	assert.False(t, readiness.Ready)
+	assert.Contains(t, readiness.Checks["loop:SystemRadar"], "no beat since")
+
+	Beat("SystemRadar")
+	assert.True(t, Ready(context.Background()).Ready)
+}
+
+func TestWatchLoop_StaleAfter(t *testing.T) {
+	resetHealth(t)
+
+	WatchLoop("short", time.Second)
+	WatchLoop("long", time.Hour)
+
+	assert.Equal(t, MinLoopStaleAfter, _loops["short"].staleAfter)
+	assert.Equal(t, 3*time.Hour, _loops["long"].staleAfter)
+}
+
+func TestHandler(t *testing.T) {
+	resetHealth(t)
+	SetVersion(Version{Version: "1.2.3", GitHash: "abc", BuildTime: "now", Support: "Community Edition"})
+
+	handler := Handler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	AddCheck("redis", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	var readiness Readiness
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &readiness))
+	assert.Equal(t, "connection refused", readiness.Checks["redis"])
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"version":"1.2.3","git_hash":"abc","build_time":"now","support":"Community Edition"}`, recorder.Body.String())
+}
+
+func TestExecutable(t *testing.T) {
+	assert.NoError(t, Executable("sh")(context.Background()))
+	assert.Error(t, Executable("/nonexistent/ffmpeg")(context.Background()))
+}
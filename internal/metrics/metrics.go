@@ -0,0 +1,137 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Registry holds all rasbora metrics, exposed by /metrics endpoint.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// TasksStarted count tasks taken by video transcoder.
+	TasksStarted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rasbora_tasks_started_total",
+		Help: "Tasks taken by video transcoder.",
+	}, []string{"handler"})
+
+	// TasksFinished count tasks transcoded without any problems.
+	TasksFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rasbora_tasks_finished_total",
+		Help: "Tasks transcoded without any problems.",
+	}, []string{"handler"})
+
+	// TasksFailed count failed task attempts, retried or not.
+	TasksFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rasbora_tasks_failed_total",
+		Help: "Failed task attempts by error code, retried or not.",
+	}, []string{"handler", "error_code"})
+
+	// TranscodeDuration observe time ffmpeg took to transcode input video.
+	TranscodeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rasbora_transcode_duration_seconds",
+		Help:    "Time ffmpeg took to transcode input video.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 14),
+	}, []string{"handler"})
+
+	// TranscodeSpeed observe input video duration divided by time taken to transcode it.
+	TranscodeSpeed = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rasbora_transcode_realtime_speed",
+		Help:    "Input video duration divided by time taken to transcode it.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 16, 32, 64},
+	}, []string{"handler"})
+
+	// DequeueWait observe time items waited in waiting queue before taken by worker.
+	DequeueWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rasbora_dequeue_wait_seconds",
+		Help:    "Time items waited in waiting queue before taken by worker.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 3, 12),
+	}, []string{"queue"})
+
+	// BytesDownloaded count bytes of input videos copied to workspace.
+	BytesDownloaded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rasbora_bytes_downloaded_total",
+		Help: "Bytes of input videos copied to workspace.",
+	}, []string{"filesystem"})
+
+	// BytesUploaded count bytes of output videos moved to main file system.
+	BytesUploaded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rasbora_bytes_uploaded_total",
+		Help: "Bytes of output videos moved to main file system.",
+	}, []string{"filesystem"})
+
+	// CallbackDeliveries count callback delivery attempts by outcome.
+	CallbackDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rasbora_callback_deliveries_total",
+		Help: "Callback delivery attempts by outcome.",
+	}, []string{"protocol", "outcome"})
+
+	// CallbackLatency observe time receivers took to accept callbacks.
+	CallbackLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rasbora_callback_delivery_latency_seconds",
+		Help:    "Time receivers took to respond to callback delivery.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"protocol", "outcome"})
+
+	// SystemCpuUsage cpu usage percentage of each core read by system radar.
+	SystemCpuUsage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rasbora_system_cpu_usage_percent",
+		Help: "Cpu usage percentage of each core read by system radar.",
+	}, []string{"radar", "cpu"})
+
+	// SystemMemory memory of node in bytes read by system radar.
+	SystemMemory = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rasbora_system_memory_bytes",
+		Help: "Memory of node read by system radar, by state (total, used, available).",
+	}, []string{"radar", "state"})
+
+	// SystemDisk disk space in bytes read by system radar.
+	SystemDisk = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rasbora_system_disk_bytes",
+		Help: "Disk space read by system radar, by state (total, used, free).",
+	}, []string{"radar", "path", "state"})
+
+	// SystemNetwork bytes sent and received by node since boot read by system radar.
+	SystemNetwork = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rasbora_system_network_bytes",
+		Help: "Bytes sent and received by node since boot read by system radar.",
+	}, []string{"radar", "direction"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		TasksStarted,
+		TasksFinished,
+		TasksFailed,
+		TranscodeDuration,
+		TranscodeSpeed,
+		DequeueWait,
+		BytesDownloaded,
+		BytesUploaded,
+		CallbackDeliveries,
+		CallbackLatency,
+		SystemCpuUsage,
+		SystemMemory,
+		SystemDisk,
+		SystemNetwork,
+	)
+}
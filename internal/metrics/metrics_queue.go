@@ -0,0 +1,62 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"openseawave.com/rasbora/internal/database"
+)
+
+// queueDepthDesc describe number of items of queue in each status.
+var queueDepthDesc = prometheus.NewDesc(
+	"rasbora_queue_items",
+	"Items of queue in each status (delayed, waiting, working, finished, failed, cancelled).",
+	[]string{"queue", "status"},
+	nil,
+)
+
+// QueueDepthCollector holds an instance
+type QueueDepthCollector struct {
+	Database *database.Database
+	Queues   []string
+}
+
+// RegisterQueueDepth start collecting depth of queues from database on each scrape.
+func RegisterQueueDepth(db *database.Database, queues ...string) error {
+	return Registry.Register(&QueueDepthCollector{Database: db, Queues: queues})
+}
+
+// Describe implements prometheus.Collector.
+func (qdc *QueueDepthCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- queueDepthDesc
+}
+
+// Collect implements prometheus.Collector.
+func (qdc *QueueDepthCollector) Collect(metrics chan<- prometheus.Metric) {
+	for _, queue := range qdc.Queues {
+		statuses, err := qdc.Database.QueueStatuses(queue)
+		if err != nil {
+			metrics <- prometheus.NewInvalidMetric(queueDepthDesc, err)
+			continue
+		}
+
+		for status, total := range statuses {
+			metrics <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(total), queue, status)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/database"
+)
+
+type fakeStatusesDatabase struct {
+	database.Interface
+	statuses map[string]map[string]int64
+}
+
+func (f *fakeStatusesDatabase) QueueStatuses(queueName string) (map[string]int64, error) {
+	return f.statuses[queueName], nil
+}
+
+func TestQueueDepthCollector(t *testing.T) {
+	collector := &QueueDepthCollector{
+		Database: database.New(&fakeStatusesDatabase{statuses: map[string]map[string]int64{
+			"video_transcoder": {"waiting": 3, "failed": 1},
+			"callback_manager": {"finished": 7},
+		}}),
+		Queues: []string{"video_transcoder", "callback_manager"},
+	}
+
+	expected := `
+# HELP rasbora_queue_items Items of queue in each status (delayed, waiting, working, finished, failed, cancelled).
+# TYPE rasbora_queue_items gauge
+rasbora_queue_items{queue="callback_manager",status="finished"} 7
+rasbora_queue_items{queue="video_transcoder",status="failed"} 1
+rasbora_queue_items{queue="video_transcoder",status="waiting"} 3
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"openseawave.com/rasbora/internal/config"
+)
+
+// Name of rasbora tracer and its service.
+const Name = "rasbora"
+
+// Tracer create rasbora spans, spans are dropped until tracing is started.
+func Tracer() trace.Tracer {
+	return otel.Tracer(Name)
+}
+
+// Start export spans over otlp grpc based on configuration settings, returned func flushes and stops exporting.
+func Start(ctx context.Context, cfg *config.Config) (shutdown func(context.Context) error, err error) {
+	options := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(cfg.GetString("Tracing.Endpoint")),
+	}
+	if cfg.GetBool("Tracing.Insecure") {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewProvider(exporter, cfg.GetFloat64("Tracing.SampleRatio"))
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewProvider create tracer provider sending spans to exporter, sample ratio applies to new traces only.
+func NewProvider(exporter sdktrace.SpanExporter, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(Name))),
+	)
+}
+
+// _propagator write and read trace context using w3c trace context headers.
+var _propagator = propagation.TraceContext{}
+
+// Inject return trace context of ctx to carry inside queue item, nil when ctx has no span.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	_propagator.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract return ctx continuing trace context carried inside queue item.
+func Extract(ctx context.Context, traceContext map[string]string) context.Context {
+	return _propagator.Extract(ctx, propagation.MapCarrier(traceContext))
+}
+
+// Fail record err on span and mark span as failed.
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// End mark span as failed when err is not nil and end span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		Fail(span, err)
+	}
+	span.End()
+}
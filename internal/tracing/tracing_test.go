@@ -0,0 +1,55 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInjectExtract(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(exporter, 1)
+	tracer := provider.Tracer(Name)
+
+	assert.Nil(t, Inject(context.Background()))
+
+	// task manager creates task and carries its trace context inside queue item.
+	createCtx, create := tracer.Start(context.Background(), "task.create")
+	traceContext := Inject(createCtx)
+	create.End()
+	assert.Contains(t, traceContext, "traceparent")
+
+	// transcoder continues trace read from queue item.
+	_, process := tracer.Start(Extract(context.Background(), traceContext), "task.process")
+	End(process, errors.New("ffmpeg failed"))
+
+	assert.NoError(t, provider.ForceFlush(context.Background()))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, spans[0].SpanContext.TraceID(), spans[1].SpanContext.TraceID())
+	assert.Equal(t, spans[0].SpanContext.SpanID(), spans[1].Parent.SpanID())
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Equal(t, "ffmpeg failed", spans[1].Status.Description)
+}
@@ -0,0 +1,309 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package callbacks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/logger"
+)
+
+// AmqpCallbackManager use amqp exchange to publish callbacks.
+type AmqpCallbackManager struct {
+	Config      *config.Config
+	Logger      *logger.Logger
+	Database    *database.Database
+	_queue      *callbackQueue
+	_connection *amqp.Connection
+	_channels   map[string]*amqpPublisher
+	_open       func() (*amqpPublisher, error)
+	_mutex      sync.Mutex
+}
+
+// amqpChannel is part of amqp channel used to publish callbacks.
+type amqpChannel interface {
+	GetNextPublishSeqNo() uint64
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	IsClosed() bool
+	Close() error
+}
+
+// amqpPublisher is worker channel with confirmations and returned messages of its publishes.
+type amqpPublisher struct {
+	channel  amqpChannel
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+}
+
+// StartCallbackManager start callback workers who listen for new callbacks
+func (acm *AmqpCallbackManager) StartCallbackManager(ctx context.Context) {
+
+	acm._queue = newCallbackQueue(acm.Config, acm.Logger, acm.Database, "amqp_callback_manager")
+
+	// every worker use its own channel, channels are not safe to share between publishers.
+	acm._channels = map[string]*amqpPublisher{}
+
+	acm._queue.startWorkers(ctx, acm._publish)
+
+	acm._close()
+}
+
+// _publish trying to publish callback and wait for broker confirmation.
+func (acm *AmqpCallbackManager) _publish(workerId string, item data.Queueable, callbackPayload *data.Callback, callbackAsJsonBytes []byte) {
+
+	attempt := data.DeliveryAttempt{
+		Attempt:     acm._queue.totalRetry(item),
+		WorkerId:    workerId,
+		AttemptedAt: time.Now().UnixMilli(),
+	}
+
+	publisher, err := acm._channel(workerId)
+	if err != nil {
+		acm.Logger.Error(
+			"amqp_callback_manager.publish",
+			fmt.Sprintf("callback cannot send broker unreachable: %v", err.Error()),
+			map[string]interface{}{
+				"callback_id":        item.ID,
+				"callback_worker_id": workerId,
+			},
+		)
+		attempt.Outcome = deliveryFailed.String()
+		attempt.Error = err.Error()
+		acm._queue.saveDeliveryAttempt(workerId, item, attempt)
+		acm._queue.failed(workerId, item, err, 0)
+		return
+	}
+
+	exchange := acm.Config.GetString("Components.CallbackManager.Amqp.Exchange")
+	routingKey := renderCallbackTemplate(acm.Config.GetString("Components.CallbackManager.Amqp.RoutingKey"), callbackPayload)
+	confirmTimeout := time.Duration(acm.Config.GetInt("Components.CallbackManager.Amqp.ConfirmTimeout")) * time.Second
+
+	publishCtx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	//publish callback and wait until broker confirm it
+	err = publisher.publish(publishCtx, exchange, routingKey, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    item.ID,
+		Timestamp:    time.Now(),
+		Body:         callbackAsJsonBytes,
+	})
+
+	attempt.Latency = time.Now().UnixMilli() - attempt.AttemptedAt
+
+	if err != nil {
+		acm.Logger.Error(
+			"amqp_callback_manager.publish",
+			fmt.Sprintf("callback cannot be published: %v", err.Error()),
+			map[string]interface{}{
+				"callback_id":          item.ID,
+				"callback_worker_id":   workerId,
+				"callback_exchange":    exchange,
+				"callback_routing_key": routingKey,
+			},
+		)
+		// channel state is unknown after failed publish, open new one for next callback.
+		acm._closeChannel(workerId)
+		attempt.Outcome = deliveryFailed.String()
+		attempt.Error = err.Error()
+		acm._queue.saveDeliveryAttempt(workerId, item, attempt)
+		acm._queue.failed(workerId, item, err, 0)
+		return
+	}
+
+	attempt.Outcome = deliverySucceeded.String()
+	acm._queue.saveDeliveryAttempt(workerId, item, attempt)
+
+	acm.Logger.Success(
+		"amqp_callback_manager.publish",
+		"callback successfully published",
+		map[string]interface{}{
+			"callback_id":          item.ID,
+			"callback_worker_id":   workerId,
+			"callback_exchange":    exchange,
+			"callback_routing_key": routingKey,
+		},
+	)
+
+	acm._queue.success(item)
+}
+
+// _channel get worker channel in confirm mode, connection and channel opened again when closed.
+func (acm *AmqpCallbackManager) _channel(workerId string) (*amqpPublisher, error) {
+	acm._mutex.Lock()
+	defer acm._mutex.Unlock()
+
+	if publisher, ok := acm._channels[workerId]; ok && !publisher.channel.IsClosed() {
+		return publisher, nil
+	}
+
+	open := acm._open
+	if open == nil {
+		open = acm._openChannel
+	}
+
+	publisher, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	acm._channels[workerId] = publisher
+
+	return publisher, nil
+}
+
+// _openChannel open channel in confirm mode and declare exchange, connection is opened again when closed.
+func (acm *AmqpCallbackManager) _openChannel() (*amqpPublisher, error) {
+	if acm._connection == nil || acm._connection.IsClosed() {
+		connection, err := amqp.Dial(acm.Config.GetString("Components.CallbackManager.Amqp.URL"))
+		if err != nil {
+			return nil, err
+		}
+		acm._connection = connection
+	}
+
+	channel, err := acm._connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		_ = channel.Close()
+		return nil, err
+	}
+
+	// declare exchange only when its type is configured, otherwise exchange should already exist.
+	if exchangeType := acm.Config.GetString("Components.CallbackManager.Amqp.ExchangeType"); exchangeType != "" {
+		if err := channel.ExchangeDeclare(
+			acm.Config.GetString("Components.CallbackManager.Amqp.Exchange"),
+			exchangeType,
+			true,
+			false,
+			false,
+			false,
+			nil,
+		); err != nil {
+			_ = channel.Close()
+			return nil, err
+		}
+	}
+
+	// channels are buffered, only one callback is published at a time on worker channel.
+	return &amqpPublisher{
+		channel:  channel,
+		confirms: channel.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		returns:  channel.NotifyReturn(make(chan amqp.Return, 1)),
+	}, nil
+}
+
+// _closeChannel close worker channel.
+func (acm *AmqpCallbackManager) _closeChannel(workerId string) {
+	acm._mutex.Lock()
+	defer acm._mutex.Unlock()
+
+	if publisher, ok := acm._channels[workerId]; ok {
+		_ = publisher.channel.Close()
+		delete(acm._channels, workerId)
+	}
+}
+
+// _close close all channels and broker connection.
+func (acm *AmqpCallbackManager) _close() {
+	acm._mutex.Lock()
+	defer acm._mutex.Unlock()
+
+	for workerId, publisher := range acm._channels {
+		_ = publisher.channel.Close()
+		delete(acm._channels, workerId)
+	}
+
+	if acm._connection != nil {
+		_ = acm._connection.Close()
+	}
+}
+
+// publish publish message as mandatory and wait until broker confirm it.
+// Message returned by broker because no queue is bound to its routing key is failed publish.
+func (ap *amqpPublisher) publish(ctx context.Context, exchange, routingKey string, message amqp.Publishing) error {
+	deliveryTag := ap.channel.GetNextPublishSeqNo()
+
+	if err := ap.channel.PublishWithContext(ctx, exchange, routingKey, true, false, message); err != nil {
+		return err
+	}
+
+	var returned *amqp.Return
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ret, ok := <-ap.returns:
+			if !ok {
+				return amqp.ErrClosed
+			}
+			if ret.MessageId == message.MessageId {
+				returned = &ret
+			}
+		case confirmation, ok := <-ap.confirms:
+			if !ok {
+				return amqp.ErrClosed
+			}
+
+			// confirmation of message published before this one.
+			if confirmation.DeliveryTag < deliveryTag {
+				continue
+			}
+
+			// broker send returned message before confirmation of same message.
+			select {
+			case ret, ok := <-ap.returns:
+				if ok && ret.MessageId == message.MessageId {
+					returned = &ret
+				}
+			default:
+			}
+
+			if returned != nil {
+				return fmt.Errorf("broker returned the callback: %d %v", returned.ReplyCode, returned.ReplyText)
+			}
+
+			if !confirmation.Ack {
+				return errors.New("broker did not acknowledge the callback")
+			}
+
+			return nil
+		}
+	}
+}
+
+// renderCallbackTemplate replace placeholders inside template with callback values.
+func renderCallbackTemplate(template string, callback *data.Callback) string {
+	return strings.NewReplacer(
+		"{{label}}", callback.Label,
+		"{{task_id}}", fmt.Sprintf("%v", callback.TaskId),
+	).Replace(template)
+}
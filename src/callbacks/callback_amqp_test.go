@@ -0,0 +1,224 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package callbacks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/logger"
+)
+
+// fakeAmqpChannel is in-process broker answering every publish with same outcome.
+type fakeAmqpChannel struct {
+	outcome   string
+	confirms  chan amqp.Confirmation
+	returns   chan amqp.Return
+	published []amqp.Publishing
+	mandatory []bool
+	sequence  uint64
+	closed    bool
+}
+
+func (f *fakeAmqpChannel) GetNextPublishSeqNo() uint64 {
+	return f.sequence + 1
+}
+
+func (f *fakeAmqpChannel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if f.outcome == "error" {
+		return errors.New("channel is not open")
+	}
+
+	f.sequence++
+	f.published = append(f.published, msg)
+	f.mandatory = append(f.mandatory, mandatory)
+
+	switch f.outcome {
+	case "ack":
+		f.confirms <- amqp.Confirmation{DeliveryTag: f.sequence, Ack: true}
+	case "nack":
+		f.confirms <- amqp.Confirmation{DeliveryTag: f.sequence, Ack: false}
+	case "return":
+		// broker return unroutable message only when it is mandatory, then ack it.
+		if mandatory {
+			f.returns <- amqp.Return{MessageId: msg.MessageId, ReplyCode: 312, ReplyText: "NO_ROUTE"}
+		}
+		f.confirms <- amqp.Confirmation{DeliveryTag: f.sequence, Ack: true}
+	}
+
+	return nil
+}
+
+func (f *fakeAmqpChannel) IsClosed() bool {
+	return f.closed
+}
+
+func (f *fakeAmqpChannel) Close() error {
+	f.closed = true
+	return nil
+}
+
+// fakeCallbackDatabase record what happened to callbacks.
+type fakeCallbackDatabase struct {
+	database.Interface
+	attempts []data.DeliveryAttempt
+	finished []string
+	retried  []string
+	failed   []string
+	retries  int
+}
+
+func (f *fakeCallbackDatabase) SaveDeliveryAttempt(queueName string, item data.Queueable, attempt data.DeliveryAttempt, maxAttempts int64, ttl time.Duration) error {
+	f.attempts = append(f.attempts, attempt)
+	return nil
+}
+
+func (f *fakeCallbackDatabase) TotalRetry(queueName string, item data.Queueable) int {
+	return f.retries
+}
+
+func (f *fakeCallbackDatabase) Finished(queueName string, item data.Queueable) error {
+	f.finished = append(f.finished, item.ID)
+	return nil
+}
+
+func (f *fakeCallbackDatabase) Enqueue(queueName string, item data.Queueable) error {
+	f.retried = append(f.retried, item.ID)
+	return nil
+}
+
+func (f *fakeCallbackDatabase) Failed(queueName string, item data.Queueable, err error) error {
+	f.failed = append(f.failed, item.ID)
+	return nil
+}
+
+func newTestCallbackConfig(settings map[string]interface{}) *config.Config {
+	v := viper.New()
+	v.Set("Components.CallbackManager.Queue", "callbacks")
+	v.Set("Components.CallbackManager.MakeAsFailedAfterRetry", 3)
+	v.Set("Components.CallbackManager.Amqp.Exchange", "rasbora.callbacks")
+	v.Set("Components.CallbackManager.Amqp.RoutingKey", "rasbora.callback.{{label}}")
+	v.Set("Components.CallbackManager.Amqp.ConfirmTimeout", 1)
+	v.Set("Components.CallbackManager.Redis.Stream", "rasbora:callbacks")
+	v.Set("Components.CallbackManager.Redis.MaxLength", 2)
+	for key, value := range settings {
+		v.Set(key, value)
+	}
+	return config.New(&config.ViperConfigManager{Viper: v})
+}
+
+func TestRenderCallbackTemplate(t *testing.T) {
+	callback := &data.Callback{
+		TaskId: "f1c1e6a0",
+		Label:  "movies",
+	}
+
+	assert.Equal(t, "rasbora.callback.movies", renderCallbackTemplate("rasbora.callback.{{label}}", callback))
+	assert.Equal(t, "movies.f1c1e6a0", renderCallbackTemplate("{{label}}.{{task_id}}", callback))
+	assert.Equal(t, "rasbora.callbacks", renderCallbackTemplate("rasbora.callbacks", callback))
+}
+
+func TestAmqpCallbackManager_Publish(t *testing.T) {
+	cases := []struct {
+		name      string
+		outcome   string
+		succeeded bool
+		err       string
+	}{
+		{name: "confirmed", outcome: "ack", succeeded: true},
+		{name: "not acknowledged", outcome: "nack", err: "broker did not acknowledge the callback"},
+		{name: "returned", outcome: "return", err: "broker returned the callback: 312 NO_ROUTE"},
+		{name: "not confirmed", outcome: "timeout", err: context.DeadlineExceeded.Error()},
+		{name: "publish failed", outcome: "error", err: "channel is not open"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestCallbackConfig(nil)
+			db := &fakeCallbackDatabase{}
+
+			var opened []*fakeAmqpChannel
+			acm := &AmqpCallbackManager{
+				Config:    cfg,
+				Logger:    logger.NewWithConfig(logger.Options{}),
+				_queue:    newCallbackQueue(cfg, logger.NewWithConfig(logger.Options{}), database.New(db), "amqp_callback_manager"),
+				_channels: map[string]*amqpPublisher{},
+				_open: func() (*amqpPublisher, error) {
+					channel := &fakeAmqpChannel{
+						outcome:  tc.outcome,
+						confirms: make(chan amqp.Confirmation, 1),
+						returns:  make(chan amqp.Return, 1),
+					}
+					opened = append(opened, channel)
+					return &amqpPublisher{channel: channel, confirms: channel.confirms, returns: channel.returns}, nil
+				},
+			}
+
+			item := data.Queueable{ID: "callback-1"}
+			acm._publish("worker-1", item, &data.Callback{TaskId: "task-1", Label: "movies"}, []byte(`{}`))
+
+			assert.Len(t, opened, 1)
+			if tc.outcome != "error" {
+				assert.Equal(t, []bool{true}, opened[0].mandatory)
+				assert.Equal(t, "callback-1", opened[0].published[0].MessageId)
+			}
+
+			assert.Len(t, db.attempts, 1)
+			if tc.succeeded {
+				assert.Equal(t, deliverySucceeded.String(), db.attempts[0].Outcome)
+				assert.Equal(t, []string{"callback-1"}, db.finished)
+				assert.Empty(t, db.retried)
+				assert.False(t, opened[0].closed)
+				return
+			}
+
+			assert.Equal(t, deliveryFailed.String(), db.attempts[0].Outcome)
+			assert.Equal(t, tc.err, db.attempts[0].Error)
+			assert.Empty(t, db.finished)
+			assert.Equal(t, []string{"callback-1"}, db.retried)
+
+			// failed channel is closed and next callback use new one.
+			assert.True(t, opened[0].closed)
+			acm._publish("worker-1", item, &data.Callback{TaskId: "task-1", Label: "movies"}, []byte(`{}`))
+			assert.Len(t, opened, 2)
+		})
+	}
+}
+
+func TestAmqpPublisher_IgnoreReturnOfOtherMessage(t *testing.T) {
+	channel := &fakeAmqpChannel{confirms: make(chan amqp.Confirmation, 2), returns: make(chan amqp.Return, 1)}
+	publisher := &amqpPublisher{channel: channel, confirms: channel.confirms, returns: channel.returns}
+
+	// late confirmation and return of message published before are left on channel.
+	channel.returns <- amqp.Return{MessageId: "callback-0", ReplyCode: 312, ReplyText: "NO_ROUTE"}
+	channel.confirms <- amqp.Confirmation{DeliveryTag: 0, Ack: false}
+	channel.outcome = "ack"
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, publisher.publish(ctx, "rasbora.callbacks", "rasbora.callback.movies", amqp.Publishing{MessageId: "callback-1"}))
+}
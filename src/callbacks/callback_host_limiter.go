@@ -0,0 +1,67 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package callbacks
+
+import "sync"
+
+// hostLimiter limits how many callbacks can be delivered at the same time to one destination host.
+type hostLimiter struct {
+	limit int
+	mutex sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// newHostLimiter create new host limiter, limit less than or equal zero means unlimited.
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit: limit,
+		slots: map[string]chan struct{}{},
+	}
+}
+
+// acquire wait until there is a free delivery slot for host.
+func (hl *hostLimiter) acquire(host string) {
+	if hl.limit <= 0 {
+		return
+	}
+
+	hl._hostSlots(host) <- struct{}{}
+}
+
+// release give back delivery slot for host.
+func (hl *hostLimiter) release(host string) {
+	if hl.limit <= 0 {
+		return
+	}
+
+	<-hl._hostSlots(host)
+}
+
+// _hostSlots get or create delivery slots for host.
+func (hl *hostLimiter) _hostSlots(host string) chan struct{} {
+	hl.mutex.Lock()
+	defer hl.mutex.Unlock()
+
+	slots, ok := hl.slots[host]
+	if !ok {
+		slots = make(chan struct{}, hl.limit)
+		hl.slots[host] = slots
+	}
+
+	return slots
+}
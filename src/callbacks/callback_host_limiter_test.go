@@ -0,0 +1,88 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package callbacks
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostLimiter_LimitsConcurrencyPerHost(t *testing.T) {
+	limiter := newHostLimiter(2)
+
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.acquire("receiver.example.com")
+			defer limiter.release("receiver.example.com")
+
+			current := atomic.AddInt32(&running, 1)
+			for {
+				seen := atomic.LoadInt32(&maxRunning)
+				if current <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int32(2), maxRunning)
+}
+
+func TestHostLimiter_HostsAreIndependent(t *testing.T) {
+	limiter := newHostLimiter(1)
+
+	limiter.acquire("first.example.com")
+
+	done := make(chan struct{})
+	go func() {
+		limiter.acquire("second.example.com")
+		limiter.release("second.example.com")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("second host should not wait for first host slot")
+	}
+
+	limiter.release("first.example.com")
+}
+
+func TestHostLimiter_Unlimited(t *testing.T) {
+	limiter := newHostLimiter(0)
+
+	for i := 0; i < 100; i++ {
+		limiter.acquire("receiver.example.com")
+	}
+
+	assert.Empty(t, limiter.slots)
+}
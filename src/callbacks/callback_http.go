@@ -20,8 +20,6 @@ package callbacks
 import (
 	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -35,161 +33,78 @@ import (
 
 // HttpCallbackManager use http to send callbacks.
 type HttpCallbackManager struct {
-	Config           *config.Config
-	Logger           *logger.Logger
-	Database         *database.Database
-	workerId         string
-	_queueName       string
-	_queueable       *data.Queueable
-	_callbackPayload *data.Callback
+	Config       *config.Config
+	Logger       *logger.Logger
+	Database     *database.Database
+	_queue       *callbackQueue
+	_httpClient  *http.Client
+	_hostLimiter *hostLimiter
 }
 
-// StartCallbackManager start callback worker who listen for new callbacks
+// StartCallbackManager start callback workers who listen for new callbacks
 func (hcm *HttpCallbackManager) StartCallbackManager(ctx context.Context) {
 
-	// get callback manager queue name
-	hcm._queueName = hcm.Config.GetString("Components.CallbackManager.Queue")
+	hcm._queue = newCallbackQueue(hcm.Config, hcm.Logger, hcm.Database, "http_callback_manager")
 
-	// get callback manager worker id
-	hcm.workerId = hcm.Config.GetString("Components.CallbackManager.UniqueID")
-
-	// get callback manager time interval used to check for new callback.
-	checkNewCallbackInterval := hcm.Config.GetInt("Components.CallbackManager.CheckNewCallbackInterval")
-
-	hcm.Logger.Info(
-		"http_callback_manager",
-		"try to init callback manager worker",
-		map[string]interface{}{
-			"callback_worker_id": hcm.workerId,
-		},
-	)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			time.Sleep(time.Duration(checkNewCallbackInterval) * time.Second)
-
-			callback, err := hcm.Database.Dequeue(hcm._queueName, hcm.workerId)
-
-			if err != nil {
-				hcm.Logger.Debug(
-					"http_callback_manager",
-					"callbacks queue is empty",
-					map[string]interface{}{
-						"callback_worker_id": hcm.workerId,
-					},
-				)
-				continue
-			}
+	// shared http client keeps connections alive between callbacks.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = hcm.Config.GetInt("Components.CallbackManager.Http.MaxIdleConnectionsPerHost")
+	hcm._httpClient = &http.Client{
+		Timeout:   time.Duration(hcm.Config.GetInt("Components.CallbackManager.Http.SendingTimeout")) * time.Second,
+		Transport: transport,
+	}
 
-			hcm.Logger.Success(
-				"http_callback_manager",
-				"preparing new callback to send",
-				map[string]interface{}{
-					"callback_id":        callback.ID,
-					"callback_worker_id": hcm.workerId,
-				},
-			)
+	// limit concurrent callbacks sent to the same destination host.
+	hcm._hostLimiter = newHostLimiter(hcm.Config.GetInt("Components.CallbackManager.Http.MaxConcurrentPerHost"))
 
-			hcm._send(callback)
-		}
-	}
+	hcm._queue.startWorkers(ctx, hcm._send)
 }
 
 // _send trying to send callback
-func (hcm *HttpCallbackManager) _send(item data.Queueable) {
-	hcm._queueable = &item
-
-	//recover from panic
-	defer func() {
-		if r := recover(); r != nil {
-			jsonData, _ := json.Marshal(r)
-			hcm._failed(errors.New(string(jsonData)))
-			hcm.Logger.Error(
-				"http_callback_manager.send",
-				fmt.Sprintf("we got panic: %v", string(jsonData)),
-				map[string]interface{}{
-					"callback_id":        hcm._queueable.ID,
-					"callback_worker_id": hcm.workerId,
-				},
-			)
-		}
-	}()
-
-	// get callback payload from queue item.
-	callbackAsJsonBytes, errJ := json.Marshal(item.Payload)
-	if errJ != nil {
-		hcm.Logger.Error(
-			"http_callback_manager.send",
-			fmt.Sprintf("cannot cast queueable to json string: %v", errJ.Error()),
-			map[string]interface{}{
-				"callback_id":        hcm._queueable.ID,
-				"callback_worker_id": hcm.workerId,
-			},
-		)
-		hcm._failed(errJ)
-		return
-	}
-
-	// set callback payload
-	errU := json.Unmarshal(callbackAsJsonBytes, &hcm._callbackPayload)
-	if errU != nil {
-		hcm.Logger.Error(
-			"http_callback_manager.send",
-			fmt.Sprintf("cannot cast queueable to callback struct: %v", errJ.Error()),
-			map[string]interface{}{
-				"callback_id":        hcm._queueable.ID,
-				"callback_worker_id": hcm.workerId,
-			},
-		)
-		hcm._failed(errU)
-		return
-	}
-
-	hcm.Logger.Debug(
-		"http_callback_manager.send",
-		"preparing callback",
-		map[string]interface{}{
-			"callback_id":        hcm._queueable.ID,
-			"callback_worker_id": hcm.workerId,
-			"callback_data":      &hcm._callbackPayload,
-		},
-	)
+func (hcm *HttpCallbackManager) _send(workerId string, item data.Queueable, callbackPayload *data.Callback, callbackAsJsonBytes []byte) {
 
 	//generate post request with callback endpoint and payload
-	req, err := http.NewRequest("POST", hcm._callbackPayload.URL, bytes.NewBuffer(callbackAsJsonBytes))
+	req, err := http.NewRequest("POST", callbackPayload.URL, bytes.NewBuffer(callbackAsJsonBytes))
 	if err != nil {
 		hcm.Logger.Error(
 			"http_callback_manager.send",
 			fmt.Sprintf("cannot create http request: %v", err.Error()),
 			map[string]interface{}{
-				"callback_id":        hcm._queueable.ID,
-				"callback_worker_id": hcm.workerId,
+				"callback_id":        item.ID,
+				"callback_worker_id": workerId,
 			},
 		)
-		hcm._failed(errU)
+		hcm._queue.failed(workerId, item, err, 0)
 		return
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	//trying to send callback throw http post request
-	sendTimeout := hcm.Config.GetInt("Components.CallbackManager.Http.SendingTimeout")
-	client := &http.Client{
-		Timeout: time.Duration(sendTimeout) * time.Second,
+	//wait for free delivery slot on receiver host
+	hcm._hostLimiter.acquire(req.URL.Host)
+	defer hcm._hostLimiter.release(req.URL.Host)
+
+	attempt := data.DeliveryAttempt{
+		Attempt:     hcm._queue.totalRetry(item),
+		WorkerId:    workerId,
+		AttemptedAt: time.Now().UnixMilli(),
 	}
-	response, err := client.Do(req)
+
+	//trying to send callback throw http post request
+	response, err := hcm._httpClient.Do(req)
+	attempt.Latency = time.Now().UnixMilli() - attempt.AttemptedAt
 	if err != nil {
 		hcm.Logger.Error(
 			"http_callback_manager.send",
 			fmt.Sprintf("callback cannot send receiver unreachable: %v", err.Error()),
 			map[string]interface{}{
-				"callback_id":        hcm._queueable.ID,
-				"callback_worker_id": hcm.workerId,
+				"callback_id":        item.ID,
+				"callback_worker_id": workerId,
 			},
 		)
-		hcm._failed(err)
+		attempt.Outcome = deliveryFailed.String()
+		attempt.Error = err.Error()
+		hcm._queue.saveDeliveryAttempt(workerId, item, attempt)
+		hcm._queue.failed(workerId, item, err, 0)
 		return
 	}
 
@@ -197,42 +112,64 @@ func (hcm *HttpCallbackManager) _send(item data.Queueable) {
 		_ = Body.Close()
 	}(response.Body)
 
-	//read response from receiver
-	bodyData, err := io.ReadAll(response.Body)
+	attempt.StatusCode = response.StatusCode
+
+	//read response from receiver, only part saved in attempts history is read
+	maxStoredBody := hcm.Config.GetInt("Components.CallbackManager.Http.MaxAttemptResponseBody")
+	var body io.Reader = response.Body
+	if maxStoredBody > 0 {
+		body = io.LimitReader(response.Body, int64(maxStoredBody)+1)
+	}
+	bodyData, err := io.ReadAll(body)
 	if err != nil {
 		hcm.Logger.Error(
 			"http_callback_manager.send",
 			fmt.Sprintf("cannot read receiver response body: %v", err.Error()),
 			map[string]interface{}{
-				"callback_id":        hcm._queueable.ID,
-				"callback_worker_id": hcm.workerId,
+				"callback_id":        item.ID,
+				"callback_worker_id": workerId,
 			},
 		)
-		hcm._failed(err)
+		attempt.Outcome = deliveryFailed.String()
+		attempt.Error = err.Error()
+		hcm._queue.saveDeliveryAttempt(workerId, item, attempt)
+		hcm._queue.failed(workerId, item, err, 0)
 		return
 	}
 
+	attempt.ResponseBody = truncateResponseBody(bodyData, maxStoredBody)
+
 	hcm.Logger.Debug(
 		"http_callback_manager.send",
 		"receiver response with data",
 		map[string]interface{}{
-			"callback_id":        hcm._queueable.ID,
-			"callback_worker_id": hcm.workerId,
+			"callback_id":        item.ID,
+			"callback_worker_id": workerId,
 			"callback_response":  string(bodyData),
 		},
 	)
 
+	outcome := classifyDeliveryResponse(
+		response.StatusCode,
+		hcm.Config.GetIntSlice("Components.CallbackManager.Http.GoneStatusCodes"),
+		hcm.Config.GetIntSlice("Components.CallbackManager.Http.RetryLaterStatusCodes"),
+	)
+
+	attempt.Outcome = outcome.String()
+	hcm._queue.saveDeliveryAttempt(workerId, item, attempt)
+
 	//receiver handle the callback without issues
-	if response.StatusCode == 200 {
+	if outcome == deliverySucceeded {
 		hcm.Logger.Success(
 			"http_callback_manager.send",
 			"callback successfully sent",
 			map[string]interface{}{
-				"callback_id":        hcm._queueable.ID,
-				"callback_worker_id": hcm.workerId,
+				"callback_id":                   item.ID,
+				"callback_worker_id":            workerId,
+				"callback_response_status_code": response.StatusCode,
 			},
 		)
-		hcm._success()
+		hcm._queue.success(item)
 		return
 	}
 
@@ -241,54 +178,26 @@ func (hcm *HttpCallbackManager) _send(item data.Queueable) {
 		"http_callback_manager.send",
 		"callback receiver response with error",
 		map[string]interface{}{
-			"callback_id":                   hcm._queueable.ID,
-			"callback_worker_id":            hcm.workerId,
+			"callback_id":                   item.ID,
+			"callback_worker_id":            workerId,
 			"callback_response":             string(bodyData),
 			"callback_response_status_code": response.StatusCode,
+			"callback_delivery_outcome":     outcome.String(),
 		},
 	)
 
-	//callback fail to send or receive
-	hcm._failed(errors.New(string(bodyData)))
-}
-
-// _failed handle failed callback
-func (hcm *HttpCallbackManager) _failed(err error) {
-
-	//get retry config for callback manager
-	retryCount := hcm.Database.TotalRetry(hcm._queueName, *hcm._queueable)
-	retryLimit := hcm.Config.GetInt("Components.CallbackManager.MakeAsFailedAfterRetry")
-
-	//make it fail when arrive to retry limit
-	if retryCount >= retryLimit {
-		hcm.Logger.Debug(
-			"http_callback_manager.failed",
-			"failed to send callback after too many retries",
-			map[string]interface{}{
-				"callback_id":          hcm._queueable.ID,
-				"callback_worker_id":   hcm.workerId,
-				"callback_retry_count": retryCount,
-				"callback_max_retry":   retryLimit,
-			},
-		)
-		_ = hcm.Database.Failed(hcm._queueName, *hcm._queueable, err)
-		return
+	receiverError := fmt.Errorf("receiver response with status code %d: %s", response.StatusCode, attempt.ResponseBody)
+
+	switch outcome {
+	case deliveryGone, deliveryRejected:
+		//retrying will not help, make callback as failed now
+		hcm._queue.failedPermanently(workerId, item, receiverError)
+	case deliveryRetryLater:
+		//receiver is busy, wait as long as receiver asked before retry
+		defaultRetryAfter := time.Duration(hcm.Config.GetInt("Components.CallbackManager.Http.DefaultRetryAfter")) * time.Second
+		hcm._queue.failed(workerId, item, receiverError, parseRetryAfter(response.Header.Get("Retry-After"), time.Now(), defaultRetryAfter))
+	default:
+		//callback fail to send or receive
+		hcm._queue.failed(workerId, item, receiverError, 0)
 	}
-
-	hcm.Logger.Debug(
-		"http_callback_manager.failed",
-		"return callback to waiting queue again to retry send callback one more time",
-		map[string]interface{}{
-			"callback_id":          hcm._queueable.ID,
-			"callback_worker_id":   hcm.workerId,
-			"callback_retry_count": retryCount,
-			"callback_max_retry":   retryLimit,
-		},
-	)
-	_ = hcm.Database.Enqueue(hcm._queueName, *hcm._queueable)
-}
-
-// _success handle successful callback
-func (hcm *HttpCallbackManager) _success() {
-	_ = hcm.Database.Finished(hcm._queueName, *hcm._queueable)
 }
@@ -0,0 +1,103 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package callbacks
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"openseawave.com/rasbora/internal/utilities"
+)
+
+// deliveryOutcome describe what should happen to callback after delivery attempt.
+type deliveryOutcome string
+
+const (
+	// receiver accepted the callback.
+	deliverySucceeded deliveryOutcome = "succeeded"
+	// receiver is permanently gone, callback should not be retried.
+	deliveryGone deliveryOutcome = "gone"
+	// receiver is busy, callback should be retried later.
+	deliveryRetryLater deliveryOutcome = "retry_later"
+	// receiver rejected the callback, retrying will not help.
+	deliveryRejected deliveryOutcome = "rejected"
+	// receiver failed or unreachable, callback should be retried.
+	deliveryFailed deliveryOutcome = "failed"
+)
+
+// String returns the string representation of deliveryOutcome.
+func (do deliveryOutcome) String() string {
+	return string(do)
+}
+
+// classifyDeliveryResponse decide delivery outcome based on receiver status code.
+func classifyDeliveryResponse(statusCode int, goneStatusCodes, retryLaterStatusCodes []int) deliveryOutcome {
+	if utilities.InSlice(statusCode, goneStatusCodes) {
+		return deliveryGone
+	}
+
+	if utilities.InSlice(statusCode, retryLaterStatusCodes) {
+		return deliveryRetryLater
+	}
+
+	if statusCode >= 200 && statusCode < 300 {
+		return deliverySucceeded
+	}
+
+	if statusCode >= 400 && statusCode < 500 {
+		return deliveryRejected
+	}
+
+	return deliveryFailed
+}
+
+// parseRetryAfter read "Retry-After" header value as seconds or http date, fallback used when header is missing or invalid.
+func parseRetryAfter(value string, now time.Time, fallback time.Duration) time.Duration {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return fallback
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return fallback
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if retryAt, err := http.ParseTime(value); err == nil {
+		if delay := retryAt.Sub(now); delay > 0 {
+			return delay
+		}
+		return 0
+	}
+
+	return fallback
+}
+
+// truncateResponseBody cut receiver response body to max size.
+func truncateResponseBody(body []byte, maxSize int) string {
+	if maxSize <= 0 || len(body) <= maxSize {
+		return string(body)
+	}
+
+	return string(body[:maxSize])
+}
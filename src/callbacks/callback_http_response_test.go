@@ -0,0 +1,71 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package callbacks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyDeliveryResponse(t *testing.T) {
+	gone := []int{410}
+	retryLater := []int{429, 503}
+
+	cases := map[int]deliveryOutcome{
+		200: deliverySucceeded,
+		201: deliverySucceeded,
+		202: deliverySucceeded,
+		204: deliverySucceeded,
+		410: deliveryGone,
+		429: deliveryRetryLater,
+		503: deliveryRetryLater,
+		400: deliveryRejected,
+		404: deliveryRejected,
+		500: deliveryFailed,
+		502: deliveryFailed,
+		302: deliveryFailed,
+	}
+
+	for statusCode, expected := range cases {
+		assert.Equal(t, expected, classifyDeliveryResponse(statusCode, gone, retryLater), "status code %d", statusCode)
+	}
+}
+
+func TestClassifyDeliveryResponse_ConfiguredGoneCodes(t *testing.T) {
+	assert.Equal(t, deliveryGone, classifyDeliveryResponse(404, []int{404, 410}, []int{429}))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	fallback := 30 * time.Second
+
+	assert.Equal(t, 120*time.Second, parseRetryAfter("120", now, fallback))
+	assert.Equal(t, fallback, parseRetryAfter("", now, fallback))
+	assert.Equal(t, fallback, parseRetryAfter("soon", now, fallback))
+	assert.Equal(t, fallback, parseRetryAfter("-5", now, fallback))
+	assert.Equal(t, 90*time.Second, parseRetryAfter("Mon, 01 Jan 2024 10:01:30 GMT", now, fallback))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("Mon, 01 Jan 2024 09:00:00 GMT", now, fallback))
+}
+
+func TestTruncateResponseBody(t *testing.T) {
+	assert.Equal(t, "hello", truncateResponseBody([]byte("hello"), 10))
+	assert.Equal(t, "hel", truncateResponseBody([]byte("hello"), 3))
+	assert.Equal(t, "hello", truncateResponseBody([]byte("hello"), 0))
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package callbacks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/logger"
+)
+
+func TestHttpCallbackManager_ResponseBodyLimit(t *testing.T) {
+	// receiver answer with much larger body than saved in attempts history.
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 1024; i++ {
+			if _, err := w.Write([]byte(strings.Repeat("a", 1024))); err != nil {
+				return
+			}
+		}
+	}))
+	defer receiver.Close()
+
+	db := &fakeCallbackDatabase{}
+	cfg := newTestCallbackConfig(map[string]interface{}{
+		"Components.CallbackManager.Http.MaxAttemptResponseBody": 16,
+	})
+	hcm := &HttpCallbackManager{
+		Config:       cfg,
+		Logger:       logger.NewWithConfig(logger.Options{}),
+		Database:     database.New(db),
+		_queue:       newCallbackQueue(cfg, logger.NewWithConfig(logger.Options{}), database.New(db), "http_callback_manager"),
+		_httpClient:  receiver.Client(),
+		_hostLimiter: newHostLimiter(0),
+	}
+
+	hcm._send("worker-1", data.Queueable{ID: "callback-1"}, &data.Callback{URL: receiver.URL}, []byte(`{"task_id":"task-1"}`))
+
+	assert.Equal(t, []string{"callback-1"}, db.finished)
+	if assert.Len(t, db.attempts, 1) {
+		assert.Equal(t, strings.Repeat("a", 16), db.attempts[0].ResponseBody)
+	}
+}
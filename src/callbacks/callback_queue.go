@@ -0,0 +1,333 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package callbacks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/health"
+	"openseawave.com/rasbora/internal/logger"
+	"openseawave.com/rasbora/internal/metrics"
+	"openseawave.com/rasbora/internal/tracing"
+)
+
+// deliverFunc deliver single callback to receiver.
+type deliverFunc func(workerId string, item data.Queueable, callback *data.Callback, callbackAsJsonBytes []byte)
+
+// callbackQueue holds the queue side of callback delivery shared by all callback protocols.
+type callbackQueue struct {
+	config    *config.Config
+	logger    *logger.Logger
+	database  *database.Database
+	label     string
+	queueName string
+	workerId  string
+}
+
+// newCallbackQueue create callback queue for callback manager, label is used as logger label.
+func newCallbackQueue(cfg *config.Config, log *logger.Logger, db *database.Database, label string) *callbackQueue {
+	return &callbackQueue{
+		config:    cfg,
+		logger:    log,
+		database:  db,
+		label:     label,
+		queueName: cfg.GetString("Components.CallbackManager.Queue"),
+		workerId:  cfg.GetString("Components.CallbackManager.UniqueID"),
+	}
+}
+
+// startWorkers start delivery workers and wait until they stop.
+func (cq *callbackQueue) startWorkers(ctx context.Context, deliver deliverFunc) {
+
+	// get callback manager time interval used to check for new callback when queue is empty.
+	checkNewCallbackInterval := cq.config.GetInt("Components.CallbackManager.CheckNewCallbackInterval")
+
+	// get number of workers delivering callbacks concurrently.
+	totalWorkers := cq.config.GetInt("Components.CallbackManager.Workers")
+	if totalWorkers <= 0 {
+		totalWorkers = 1
+	}
+
+	cq.logger.Info(
+		cq.label,
+		"try to init callback manager workers",
+		map[string]interface{}{
+			"callback_worker_id":     cq.workerId,
+			"callback_total_workers": totalWorkers,
+		},
+	)
+
+	health.WatchLoop(Name, time.Duration(checkNewCallbackInterval)*time.Second)
+
+	var waitGroup sync.WaitGroup
+
+	for i := 1; i <= totalWorkers; i++ {
+		waitGroup.Add(1)
+		go func(deliveryWorkerId string) {
+			defer waitGroup.Done()
+			cq._startDeliveryWorker(ctx, deliveryWorkerId, checkNewCallbackInterval, deliver)
+		}(fmt.Sprintf("%v-%d", cq.workerId, i))
+	}
+
+	waitGroup.Wait()
+}
+
+// _startDeliveryWorker keep sending callbacks while the queue is not empty, and wait only when it is empty.
+func (cq *callbackQueue) _startDeliveryWorker(ctx context.Context, workerId string, checkNewCallbackInterval int, deliver deliverFunc) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			health.Beat(Name)
+
+			// move callbacks waiting for retry time to waiting queue.
+			if err := cq.database.PromoteDelayed(cq.queueName); err != nil {
+				cq.logger.Warn(
+					cq.label,
+					fmt.Sprintf("cannot promote delayed callbacks: %v", err.Error()),
+					map[string]interface{}{
+						"callback_worker_id": workerId,
+					},
+				)
+			}
+
+			callback, err := cq.database.Dequeue(cq.queueName, workerId, nil)
+
+			if err != nil {
+				cq.logger.Debug(
+					cq.label,
+					"callbacks queue is empty",
+					map[string]interface{}{
+						"callback_worker_id": workerId,
+					},
+				)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Duration(checkNewCallbackInterval) * time.Second):
+				}
+				continue
+			}
+
+			if callback.EnqueuedAt > 0 {
+				metrics.DequeueWait.WithLabelValues(cq.queueName).Observe(time.Since(time.UnixMilli(callback.EnqueuedAt)).Seconds())
+			}
+
+			cq.logger.Success(
+				cq.label,
+				"preparing new callback to send",
+				map[string]interface{}{
+					"callback_id":        callback.ID,
+					"callback_worker_id": workerId,
+				},
+			)
+
+			cq._deliver(workerId, callback, deliver)
+		}
+	}
+}
+
+// _deliver read callback payload from queue item and pass it to deliver func.
+func (cq *callbackQueue) _deliver(workerId string, item data.Queueable, deliver deliverFunc) {
+
+	var callbackPayload *data.Callback
+
+	// continue trace of task callback belongs to.
+	_, span := tracing.Tracer().Start(
+		tracing.Extract(context.Background(), item.TraceContext),
+		"callback.deliver",
+		trace.WithAttributes(
+			attribute.String("callback.id", item.ID),
+			attribute.String("callback.protocol", strings.TrimSuffix(cq.label, "_callback_manager")),
+		),
+	)
+	defer span.End()
+
+	//recover from panic
+	defer func() {
+		if r := recover(); r != nil {
+			jsonData, _ := json.Marshal(r)
+			cq.failed(workerId, item, errors.New(string(jsonData)), 0)
+			cq.logger.Error(
+				cq.label+".send",
+				fmt.Sprintf("we got panic: %v", string(jsonData)),
+				map[string]interface{}{
+					"callback_id":        item.ID,
+					"callback_worker_id": workerId,
+				},
+			)
+		}
+	}()
+
+	// get callback payload from queue item.
+	callbackAsJsonBytes, errJ := json.Marshal(item.Payload)
+	if errJ != nil {
+		cq.logger.Error(
+			cq.label+".send",
+			fmt.Sprintf("cannot cast queueable to json string: %v", errJ.Error()),
+			map[string]interface{}{
+				"callback_id":        item.ID,
+				"callback_worker_id": workerId,
+			},
+		)
+		tracing.Fail(span, errJ)
+		cq.failed(workerId, item, errJ, 0)
+		return
+	}
+
+	// set callback payload
+	errU := json.Unmarshal(callbackAsJsonBytes, &callbackPayload)
+	if errU != nil {
+		cq.logger.Error(
+			cq.label+".send",
+			fmt.Sprintf("cannot cast queueable to callback struct: %v", errU.Error()),
+			map[string]interface{}{
+				"callback_id":        item.ID,
+				"callback_worker_id": workerId,
+			},
+		)
+		tracing.Fail(span, errU)
+		cq.failed(workerId, item, errU, 0)
+		return
+	}
+
+	cq.logger.Debug(
+		cq.label+".send",
+		"preparing callback",
+		map[string]interface{}{
+			"callback_id":        item.ID,
+			"callback_worker_id": workerId,
+			"callback_data":      callbackPayload,
+		},
+	)
+
+	deliver(workerId, item, callbackPayload, callbackAsJsonBytes)
+}
+
+// saveDeliveryAttempt add delivery attempt to callback attempts history.
+func (cq *callbackQueue) saveDeliveryAttempt(workerId string, item data.Queueable, attempt data.DeliveryAttempt) {
+	protocol := strings.TrimSuffix(cq.label, "_callback_manager")
+	metrics.CallbackDeliveries.WithLabelValues(protocol, attempt.Outcome).Inc()
+	metrics.CallbackLatency.WithLabelValues(protocol, attempt.Outcome).Observe(float64(attempt.Latency) / 1000)
+
+	// history holds attempts up to retry limit, and is removed some time after last attempt.
+	maxAttempts := int64(cq.config.GetInt("Components.CallbackManager.MakeAsFailedAfterRetry"))
+	attemptsTTL := time.Duration(cq.config.GetInt("Components.CallbackManager.AttemptsTTL")) * time.Second
+	if attemptsTTL <= 0 {
+		attemptsTTL = 7 * 24 * time.Hour
+	}
+
+	if err := cq.database.SaveDeliveryAttempt(cq.queueName, item, attempt, maxAttempts, attemptsTTL); err != nil {
+		cq.logger.Warn(
+			cq.label+".save_delivery_attempt",
+			fmt.Sprintf("cannot save callback delivery attempt: %v", err.Error()),
+			map[string]interface{}{
+				"callback_id":        item.ID,
+				"callback_worker_id": workerId,
+			},
+		)
+	}
+}
+
+// totalRetry get how many times callback has been queued.
+func (cq *callbackQueue) totalRetry(item data.Queueable) int {
+	return cq.database.TotalRetry(cq.queueName, item)
+}
+
+// failed handle failed callback, callback retried after retryAfter when its more than zero.
+func (cq *callbackQueue) failed(workerId string, item data.Queueable, err error, retryAfter time.Duration) {
+
+	//get retry config for callback manager
+	retryCount := cq.database.TotalRetry(cq.queueName, item)
+	retryLimit := cq.config.GetInt("Components.CallbackManager.MakeAsFailedAfterRetry")
+
+	//make it fail when arrive to retry limit
+	if retryCount >= retryLimit {
+		cq.logger.Debug(
+			cq.label+".failed",
+			"failed to send callback after too many retries",
+			map[string]interface{}{
+				"callback_id":          item.ID,
+				"callback_worker_id":   workerId,
+				"callback_retry_count": retryCount,
+				"callback_max_retry":   retryLimit,
+			},
+		)
+		_ = cq.database.Failed(cq.queueName, item, err)
+		return
+	}
+
+	if retryAfter > 0 {
+		cq.logger.Debug(
+			cq.label+".failed",
+			"receiver is busy, callback will be retried later",
+			map[string]interface{}{
+				"callback_id":          item.ID,
+				"callback_worker_id":   workerId,
+				"callback_retry_count": retryCount,
+				"callback_max_retry":   retryLimit,
+				"callback_retry_after": retryAfter.String(),
+			},
+		)
+		_ = cq.database.Delay(cq.queueName, item, time.Now().Add(retryAfter))
+		return
+	}
+
+	cq.logger.Debug(
+		cq.label+".failed",
+		"return callback to waiting queue again to retry send callback one more time",
+		map[string]interface{}{
+			"callback_id":          item.ID,
+			"callback_worker_id":   workerId,
+			"callback_retry_count": retryCount,
+			"callback_max_retry":   retryLimit,
+		},
+	)
+	_ = cq.database.Enqueue(cq.queueName, item)
+}
+
+// failedPermanently make callback as failed without retry.
+func (cq *callbackQueue) failedPermanently(workerId string, item data.Queueable, err error) {
+	cq.logger.Debug(
+		cq.label+".failed_permanently",
+		"receiver will not accept this callback, make it failed without retry",
+		map[string]interface{}{
+			"callback_id":        item.ID,
+			"callback_worker_id": workerId,
+		},
+	)
+	_ = cq.database.Failed(cq.queueName, item, err)
+}
+
+// success handle successful callback
+func (cq *callbackQueue) success(item data.Queueable) {
+	_ = cq.database.Finished(cq.queueName, item)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package callbacks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/logger"
+)
+
+// RedisCallbackManager use redis streams to publish callbacks, consumers read them using consumer groups.
+type RedisCallbackManager struct {
+	Config   *config.Config
+	Logger   *logger.Logger
+	Database *database.Database
+	_queue   *callbackQueue
+}
+
+// StartCallbackManager start callback workers who listen for new callbacks
+func (rcm *RedisCallbackManager) StartCallbackManager(ctx context.Context) {
+
+	rcm._queue = newCallbackQueue(rcm.Config, rcm.Logger, rcm.Database, "redis_callback_manager")
+
+	rcm._queue.startWorkers(ctx, rcm._publish)
+}
+
+// _publish trying to add callback to stream.
+func (rcm *RedisCallbackManager) _publish(workerId string, item data.Queueable, callbackPayload *data.Callback, callbackAsJsonBytes []byte) {
+
+	stream := rcm._stream(callbackPayload)
+
+	attempt := data.DeliveryAttempt{
+		Attempt:     rcm._queue.totalRetry(item),
+		WorkerId:    workerId,
+		AttemptedAt: time.Now().UnixMilli(),
+	}
+
+	entryId, err := rcm.Database.SendCallbackToStream(
+		stream,
+		map[string]interface{}{
+			"task_id":    fmt.Sprintf("%v", callbackPayload.TaskId),
+			"task_label": callbackPayload.Label,
+			"error":      callbackPayload.Error,
+			"callback":   string(callbackAsJsonBytes),
+		},
+		int64(rcm.Config.GetInt("Components.CallbackManager.Redis.MaxLength")),
+	)
+
+	attempt.Latency = time.Now().UnixMilli() - attempt.AttemptedAt
+
+	if err != nil {
+		rcm.Logger.Error(
+			"redis_callback_manager.publish",
+			fmt.Sprintf("callback cannot be added to stream: %v", err.Error()),
+			map[string]interface{}{
+				"callback_id":        item.ID,
+				"callback_worker_id": workerId,
+				"callback_stream":    stream,
+			},
+		)
+		attempt.Outcome = deliveryFailed.String()
+		attempt.Error = err.Error()
+		rcm._queue.saveDeliveryAttempt(workerId, item, attempt)
+		rcm._queue.failed(workerId, item, err, 0)
+		return
+	}
+
+	attempt.Outcome = deliverySucceeded.String()
+	attempt.ResponseBody = entryId
+	rcm._queue.saveDeliveryAttempt(workerId, item, attempt)
+
+	rcm.Logger.Success(
+		"redis_callback_manager.publish",
+		"callback successfully added to stream",
+		map[string]interface{}{
+			"callback_id":        item.ID,
+			"callback_worker_id": workerId,
+			"callback_stream":    stream,
+			"callback_entry_id":  entryId,
+		},
+	)
+
+	rcm._queue.success(item)
+}
+
+// _stream select stream for callback, label stream is used when its configured and task has label.
+func (rcm *RedisCallbackManager) _stream(callback *data.Callback) string {
+	labelStream := rcm.Config.GetString("Components.CallbackManager.Redis.LabelStream")
+
+	if labelStream != "" && callback.Label != "" {
+		return renderCallbackTemplate(labelStream, callback)
+	}
+
+	return renderCallbackTemplate(rcm.Config.GetString("Components.CallbackManager.Redis.Stream"), callback)
+}
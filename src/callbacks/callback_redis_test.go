@@ -0,0 +1,153 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package callbacks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/logger"
+)
+
+// fakeStreamDatabase keep streams in memory and trim them like redis does.
+type fakeStreamDatabase struct {
+	fakeCallbackDatabase
+	streams    map[string][]map[string]interface{}
+	maxLengths []int64
+	err        error
+}
+
+func (f *fakeStreamDatabase) SendCallbackToStream(stream string, callback map[string]interface{}, maxLength int64) (string, error) {
+	f.maxLengths = append(f.maxLengths, maxLength)
+	if f.err != nil {
+		return "", f.err
+	}
+
+	f.streams[stream] = append(f.streams[stream], callback)
+	if maxLength > 0 && int64(len(f.streams[stream])) > maxLength {
+		f.streams[stream] = f.streams[stream][int64(len(f.streams[stream]))-maxLength:]
+	}
+
+	return "1700000000000-0", nil
+}
+
+func newTestRedisCallbackManager(cfg *config.Config, db *fakeStreamDatabase) *RedisCallbackManager {
+	return &RedisCallbackManager{
+		Config:   cfg,
+		Logger:   logger.NewWithConfig(logger.Options{}),
+		Database: database.New(db),
+		_queue:   newCallbackQueue(cfg, logger.NewWithConfig(logger.Options{}), database.New(db), "redis_callback_manager"),
+	}
+}
+
+func TestRedisCallbackManager_Stream(t *testing.T) {
+	cases := []struct {
+		name        string
+		stream      string
+		labelStream string
+		label       string
+		expected    string
+	}{
+		{name: "single stream", stream: "rasbora:callbacks", label: "movies", expected: "rasbora:callbacks"},
+		{name: "label stream", stream: "rasbora:callbacks", labelStream: "rasbora:callbacks:{{label}}", label: "movies", expected: "rasbora:callbacks:movies"},
+		{name: "task without label", stream: "rasbora:callbacks", labelStream: "rasbora:callbacks:{{label}}", expected: "rasbora:callbacks"},
+		{name: "stream per task", stream: "rasbora:callbacks:{{task_id}}", expected: "rasbora:callbacks:task-1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestCallbackConfig(map[string]interface{}{
+				"Components.CallbackManager.Redis.Stream":      tc.stream,
+				"Components.CallbackManager.Redis.LabelStream": tc.labelStream,
+			})
+			rcm := newTestRedisCallbackManager(cfg, &fakeStreamDatabase{streams: map[string][]map[string]interface{}{}})
+
+			assert.Equal(t, tc.expected, rcm._stream(&data.Callback{TaskId: "task-1", Label: tc.label}))
+		})
+	}
+}
+
+func TestRedisCallbackManager_Publish(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		retries  int
+		finished []string
+		retried  []string
+		failed   []string
+	}{
+		{name: "added to stream", finished: []string{"callback-1"}},
+		{name: "stream unreachable", err: errors.New("connection refused"), retried: []string{"callback-1"}},
+		{name: "stream unreachable after retry limit", err: errors.New("connection refused"), retries: 3, failed: []string{"callback-1"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &fakeStreamDatabase{streams: map[string][]map[string]interface{}{}, err: tc.err}
+			db.retries = tc.retries
+			rcm := newTestRedisCallbackManager(newTestCallbackConfig(nil), db)
+
+			item := data.Queueable{ID: "callback-1"}
+			rcm._publish("worker-1", item, &data.Callback{TaskId: "task-1", Label: "movies"}, []byte(`{"task_id":"task-1"}`))
+
+			assert.Equal(t, tc.finished, db.finished)
+			assert.Equal(t, tc.retried, db.retried)
+			assert.Equal(t, tc.failed, db.failed)
+
+			if assert.Len(t, db.attempts, 1) {
+				assert.Equal(t, "worker-1", db.attempts[0].WorkerId)
+				assert.Equal(t, tc.retries, db.attempts[0].Attempt)
+			}
+
+			if tc.err != nil {
+				assert.Equal(t, deliveryFailed.String(), db.attempts[0].Outcome)
+				assert.Equal(t, tc.err.Error(), db.attempts[0].Error)
+				assert.Empty(t, db.streams)
+				return
+			}
+
+			assert.Equal(t, deliverySucceeded.String(), db.attempts[0].Outcome)
+			assert.Equal(t, "1700000000000-0", db.attempts[0].ResponseBody)
+			assert.Equal(t, []map[string]interface{}{{
+				"task_id":    "task-1",
+				"task_label": "movies",
+				"error":      false,
+				"callback":   `{"task_id":"task-1"}`,
+			}}, db.streams["rasbora:callbacks"])
+		})
+	}
+}
+
+func TestRedisCallbackManager_MaxLength(t *testing.T) {
+	db := &fakeStreamDatabase{streams: map[string][]map[string]interface{}{}}
+	rcm := newTestRedisCallbackManager(newTestCallbackConfig(nil), db)
+
+	for _, taskId := range []string{"task-1", "task-2", "task-3"} {
+		rcm._publish("worker-1", data.Queueable{ID: taskId}, &data.Callback{TaskId: taskId}, []byte(`{}`))
+	}
+
+	assert.Equal(t, []int64{2, 2, 2}, db.maxLengths)
+	if assert.Len(t, db.streams["rasbora:callbacks"], 2) {
+		assert.Equal(t, "task-2", db.streams["rasbora:callbacks"][0]["task_id"])
+		assert.Equal(t, "task-3", db.streams["rasbora:callbacks"][1]["task_id"])
+	}
+}
@@ -20,6 +20,7 @@ package heartbeat
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"openseawave.com/rasbora/internal/config"
@@ -58,6 +59,9 @@ func (hb *Heartbeat) Start(ctx context.Context) {
 	// get send heartbeat interval in seconds
 	heartbeatSendInterval := hb.Config.GetInt("Heartbeat.SendInterval")
 
+	// get name of node this worker runs on
+	node := hb._node()
+
 	hb.Logger.Info(
 		"heartbeat",
 		"new heartbeat has been started to update cluster status",
@@ -73,7 +77,7 @@ func (hb *Heartbeat) Start(ctx context.Context) {
 			return
 		default:
 
-			if err := hb.Database.SendHeartbeat(hb.WorkerId, hb.WorkerType); err != nil {
+			if err := hb.Database.SendHeartbeat(node, hb.WorkerId, hb.WorkerType); err != nil {
 				hb.Logger.Warn(
 					"heartbeat",
 					fmt.Sprintf("cannot send heartbeat: %v", err.Error()),
@@ -98,3 +102,17 @@ func (hb *Heartbeat) Start(ctx context.Context) {
 		}
 	}
 }
+
+// _node name of node from config, or hostname when it is not set.
+func (hb *Heartbeat) _node() string {
+	if node := hb.Config.GetString("Heartbeat.Node"); node != "" {
+		return node
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return hb.WorkerId
+	}
+
+	return hostname
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package safeguard
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/logger"
+)
+
+// Name used as identifier.
+const Name = "SafeGuard"
+
+// Reasons saved in abuse log when request is rejected.
+const (
+	ReasonIpRateLimited     = "ip_rate_limited"
+	ReasonApiKeyRateLimited = "api_key_rate_limited"
+	ReasonBodyTooLarge      = "body_too_large"
+	ReasonTooManyArgs       = "too_many_args"
+	ReasonUnauthenticated   = "unauthenticated"
+)
+
+// ErrNoTrustedProxies returned when proxy header is set without proxies allowed to send it.
+var ErrNoTrustedProxies = errors.New("proxy header is set but trusted proxies are not configured")
+
+// SafeGuard holds an instance.
+type SafeGuard struct {
+	Config   *config.Config
+	Logger   *logger.Logger
+	Database *database.Database
+	enabled  bool
+}
+
+// NewSafeGuard make new safeguard, limits counters are saved in database so all replicas share them.
+func NewSafeGuard(cfg *config.Config, log *logger.Logger, db *database.Database) *SafeGuard {
+	return &SafeGuard{
+		Config:   cfg,
+		Logger:   log,
+		Database: db,
+		enabled:  cfg.GetBool("Components.TaskManagement.SafeGuard.Enabled"),
+	}
+}
+
+// AllowIp take token from rate limit bucket of client ip.
+func (sg *SafeGuard) AllowIp(ip string) (bool, time.Duration) {
+	return sg._allow("PerIp", "ip:"+ip)
+}
+
+// AllowApiKey take token from rate limit bucket of api key.
+func (sg *SafeGuard) AllowApiKey(keyId string) (bool, time.Duration) {
+	return sg._allow("PerApiKey", "key:"+keyId)
+}
+
+// ProxyHeader header holding real client ip and proxies trusted to send it, header is ignored on requests of other clients.
+func (sg *SafeGuard) ProxyHeader() (string, []string, error) {
+	header := sg.Config.GetString("Components.TaskManagement.SafeGuard.ProxyHeader")
+	trustedProxies := sg.Config.GetStringSlice("Components.TaskManagement.SafeGuard.TrustedProxies")
+
+	// any client could choose its ip and bucket when header is read from every request.
+	if header != "" && len(trustedProxies) == 0 {
+		return "", nil, ErrNoTrustedProxies
+	}
+
+	return header, trustedProxies, nil
+}
+
+// MaxBodySize max request body size in bytes, zero means default limit of server.
+func (sg *SafeGuard) MaxBodySize() int {
+	if !sg.enabled {
+		return 0
+	}
+	return sg.Config.GetInt("Components.TaskManagement.SafeGuard.MaxBodySize")
+}
+
+// MaxBatchBodySize max batch request body size in bytes, batch can hold body of every task, zero means default limit of server.
+func (sg *SafeGuard) MaxBatchBodySize(maxTasks int) int {
+	if maxTasks <= 0 {
+		return 0
+	}
+	return sg.MaxBodySize() * maxTasks
+}
+
+// MaxArgsCount max output args per task, zero means unlimited.
+func (sg *SafeGuard) MaxArgsCount() int {
+	if !sg.enabled {
+		return 0
+	}
+	return sg.Config.GetInt("Components.TaskManagement.SafeGuard.MaxArgsCount")
+}
+
+// ReportAbuse save rejected request in abuse log stream.
+func (sg *SafeGuard) ReportAbuse(reason string, details map[string]interface{}) {
+	entry := map[string]interface{}{
+		"reason":      reason,
+		"reported_at": time.Now().UnixMilli(),
+	}
+	for key, value := range details {
+		entry[key] = fmt.Sprint(value)
+	}
+
+	sg.Logger.Warn(
+		"safeguard.report_abuse",
+		"request rejected",
+		entry,
+	)
+
+	if err := sg.Database.SendAbuseLog(entry, int64(sg.Config.GetInt("Components.TaskManagement.SafeGuard.AbuseLogMaxLength"))); err != nil {
+		sg.Logger.Error(
+			"safeguard.report_abuse",
+			fmt.Sprintf("error when saving abuse log: %v", err.Error()),
+			entry,
+		)
+	}
+}
+
+// _allow take token from bucket using rate and burst of the limit, requests are allowed when database is not reachable.
+func (sg *SafeGuard) _allow(limit string, bucket string) (bool, time.Duration) {
+	if !sg.enabled {
+		return true, 0
+	}
+
+	rate := sg.Config.GetInt(fmt.Sprintf("Components.TaskManagement.SafeGuard.RateLimit.%v.Rate", limit))
+	burst := sg.Config.GetInt(fmt.Sprintf("Components.TaskManagement.SafeGuard.RateLimit.%v.Burst", limit))
+	if rate <= 0 {
+		return true, 0
+	}
+	if burst <= 0 {
+		burst = rate
+	}
+
+	allowed, retryAfter, err := sg.Database.TakeToken(bucket, float64(rate), burst)
+	if err != nil {
+		sg.Logger.Error(
+			"safeguard.allow",
+			fmt.Sprintf("error when reading rate limit bucket: %v", err.Error()),
+			map[string]interface{}{
+				"bucket": bucket,
+			},
+		)
+		return true, 0
+	}
+
+	return allowed, retryAfter
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package safeguard
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/logger"
+)
+
+// fakeSafeGuardDatabase keep token buckets and abuse log in memory.
+type fakeSafeGuardDatabase struct {
+	database.Interface
+	taken          map[string]int
+	rates          map[string]float64
+	bursts         map[string]int
+	abuse          []map[string]interface{}
+	abuseMaxLength int64
+	err            error
+}
+
+func newFakeSafeGuardDatabase() *fakeSafeGuardDatabase {
+	return &fakeSafeGuardDatabase{taken: map[string]int{}, rates: map[string]float64{}, bursts: map[string]int{}}
+}
+
+func (f *fakeSafeGuardDatabase) TakeToken(bucket string, rate float64, burst int) (bool, time.Duration, error) {
+	if f.err != nil {
+		return false, 0, f.err
+	}
+
+	f.rates[bucket] = rate
+	f.bursts[bucket] = burst
+
+	// bucket is never refilled, so only burst requests are allowed.
+	if f.taken[bucket] >= burst {
+		return false, time.Duration(float64(time.Second) / rate), nil
+	}
+	f.taken[bucket]++
+	return true, 0, nil
+}
+
+func (f *fakeSafeGuardDatabase) SendAbuseLog(entry map[string]interface{}, maxLength int64) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	f.abuse = append(f.abuse, entry)
+	f.abuseMaxLength = maxLength
+	return nil
+}
+
+func newTestSafeGuard(db *fakeSafeGuardDatabase, settings map[string]interface{}) *SafeGuard {
+	v := viper.New()
+	v.Set("Components.TaskManagement.SafeGuard.Enabled", true)
+	v.Set("Components.TaskManagement.SafeGuard.RateLimit.PerIp.Rate", 2)
+	v.Set("Components.TaskManagement.SafeGuard.RateLimit.PerIp.Burst", 3)
+	v.Set("Components.TaskManagement.SafeGuard.RateLimit.PerApiKey.Rate", 1)
+	v.Set("Components.TaskManagement.SafeGuard.RateLimit.PerApiKey.Burst", 2)
+	v.Set("Components.TaskManagement.SafeGuard.MaxBodySize", 1024)
+	v.Set("Components.TaskManagement.SafeGuard.MaxArgsCount", 8)
+	v.Set("Components.TaskManagement.SafeGuard.AbuseLogMaxLength", 100)
+	for key, value := range settings {
+		v.Set(key, value)
+	}
+
+	return NewSafeGuard(config.New(&config.ViperConfigManager{Viper: v}), logger.NewWithConfig(logger.Options{}), database.New(db))
+}
+
+func TestSafeGuard_Allow(t *testing.T) {
+	cases := []struct {
+		name       string
+		settings   map[string]interface{}
+		allow      func(sg *SafeGuard) (bool, time.Duration)
+		bucket     string
+		rate       float64
+		burst      int
+		allowed    int
+		retryAfter time.Duration
+	}{
+		{
+			name:       "per ip bucket",
+			allow:      func(sg *SafeGuard) (bool, time.Duration) { return sg.AllowIp("10.0.0.1") },
+			bucket:     "ip:10.0.0.1",
+			rate:       2,
+			burst:      3,
+			allowed:    3,
+			retryAfter: 500 * time.Millisecond,
+		},
+		{
+			name:       "per api key bucket",
+			allow:      func(sg *SafeGuard) (bool, time.Duration) { return sg.AllowApiKey("key-1") },
+			bucket:     "key:key-1",
+			rate:       1,
+			burst:      2,
+			allowed:    2,
+			retryAfter: time.Second,
+		},
+		{
+			name:       "burst defaults to rate",
+			settings:   map[string]interface{}{"Components.TaskManagement.SafeGuard.RateLimit.PerIp.Burst": 0},
+			allow:      func(sg *SafeGuard) (bool, time.Duration) { return sg.AllowIp("10.0.0.1") },
+			bucket:     "ip:10.0.0.1",
+			rate:       2,
+			burst:      2,
+			allowed:    2,
+			retryAfter: 500 * time.Millisecond,
+		},
+		{
+			name:     "zero rate is unlimited",
+			settings: map[string]interface{}{"Components.TaskManagement.SafeGuard.RateLimit.PerApiKey.Rate": 0},
+			allow:    func(sg *SafeGuard) (bool, time.Duration) { return sg.AllowApiKey("key-1") },
+			allowed:  10,
+		},
+		{
+			name:     "disabled",
+			settings: map[string]interface{}{"Components.TaskManagement.SafeGuard.Enabled": false},
+			allow:    func(sg *SafeGuard) (bool, time.Duration) { return sg.AllowIp("10.0.0.1") },
+			allowed:  10,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newFakeSafeGuardDatabase()
+			sg := newTestSafeGuard(db, tc.settings)
+
+			allowed := 0
+			var retryAfter time.Duration
+			for i := 0; i < 10; i++ {
+				ok, wait := tc.allow(sg)
+				if ok {
+					allowed++
+					continue
+				}
+				retryAfter = wait
+			}
+
+			assert.Equal(t, tc.allowed, allowed)
+			assert.Equal(t, tc.retryAfter, retryAfter)
+
+			if tc.bucket == "" {
+				assert.Empty(t, db.taken)
+				return
+			}
+			assert.Equal(t, tc.rate, db.rates[tc.bucket])
+			assert.Equal(t, tc.burst, db.bursts[tc.bucket])
+		})
+	}
+}
+
+func TestSafeGuard_BucketsAreIndependent(t *testing.T) {
+	db := newFakeSafeGuardDatabase()
+	sg := newTestSafeGuard(db, nil)
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := sg.AllowIp("10.0.0.1")
+		assert.True(t, allowed)
+	}
+	allowed, _ := sg.AllowIp("10.0.0.1")
+	assert.False(t, allowed)
+
+	allowed, _ = sg.AllowIp("10.0.0.2")
+	assert.True(t, allowed)
+
+	// api key named like ip does not share its bucket.
+	allowed, _ = sg.AllowApiKey("10.0.0.1")
+	assert.True(t, allowed)
+}
+
+func TestSafeGuard_AllowWhenDatabaseUnreachable(t *testing.T) {
+	db := newFakeSafeGuardDatabase()
+	db.err = errors.New("connection refused")
+	sg := newTestSafeGuard(db, nil)
+
+	allowed, retryAfter := sg.AllowIp("10.0.0.1")
+	assert.True(t, allowed)
+	assert.Zero(t, retryAfter)
+}
+
+func TestSafeGuard_Limits(t *testing.T) {
+	cases := []struct {
+		name             string
+		settings         map[string]interface{}
+		maxBodySize      int
+		maxBatchBodySize int
+		maxArgsCount     int
+	}{
+		{name: "enabled", maxBodySize: 1024, maxBatchBodySize: 10 * 1024, maxArgsCount: 8},
+		{name: "disabled", settings: map[string]interface{}{"Components.TaskManagement.SafeGuard.Enabled": false}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sg := newTestSafeGuard(newFakeSafeGuardDatabase(), tc.settings)
+
+			assert.Equal(t, tc.maxBodySize, sg.MaxBodySize())
+			assert.Equal(t, tc.maxBatchBodySize, sg.MaxBatchBodySize(10))
+			assert.Zero(t, sg.MaxBatchBodySize(0))
+			assert.Equal(t, tc.maxArgsCount, sg.MaxArgsCount())
+		})
+	}
+}
+
+func TestSafeGuard_ProxyHeader(t *testing.T) {
+	cases := []struct {
+		name           string
+		settings       map[string]interface{}
+		header         string
+		trustedProxies []string
+		err            error
+	}{
+		{name: "connection ip"},
+		{
+			name: "trusted proxies",
+			settings: map[string]interface{}{
+				"Components.TaskManagement.SafeGuard.ProxyHeader":    "X-Forwarded-For",
+				"Components.TaskManagement.SafeGuard.TrustedProxies": []string{"10.0.0.0/8"},
+			},
+			header:         "X-Forwarded-For",
+			trustedProxies: []string{"10.0.0.0/8"},
+		},
+		{
+			name:     "header without trusted proxies",
+			settings: map[string]interface{}{"Components.TaskManagement.SafeGuard.ProxyHeader": "X-Forwarded-For"},
+			err:      ErrNoTrustedProxies,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sg := newTestSafeGuard(newFakeSafeGuardDatabase(), tc.settings)
+
+			header, trustedProxies, err := sg.ProxyHeader()
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.header, header)
+			assert.Equal(t, tc.trustedProxies, trustedProxies)
+		})
+	}
+}
+
+func TestSafeGuard_ReportAbuse(t *testing.T) {
+	cases := []struct {
+		name   string
+		reason string
+		err    error
+		saved  int
+	}{
+		{name: "ip rate limited", reason: ReasonIpRateLimited, saved: 1},
+		{name: "body too large", reason: ReasonBodyTooLarge, saved: 1},
+		{name: "database unreachable", reason: ReasonUnauthenticated, err: errors.New("connection refused")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newFakeSafeGuardDatabase()
+			db.err = tc.err
+			sg := newTestSafeGuard(db, nil)
+
+			sg.ReportAbuse(tc.reason, map[string]interface{}{"ip": "10.0.0.1", "status": 429})
+
+			assert.Len(t, db.abuse, tc.saved)
+			if tc.saved == 0 {
+				return
+			}
+
+			entry := db.abuse[0]
+			assert.Equal(t, tc.reason, entry["reason"])
+			assert.Equal(t, "10.0.0.1", entry["ip"])
+			assert.Equal(t, "429", entry["status"])
+			assert.NotZero(t, entry["reported_at"])
+			assert.Equal(t, int64(100), db.abuseMaxLength)
+		})
+	}
+}
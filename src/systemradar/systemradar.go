@@ -21,6 +21,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
@@ -31,7 +32,9 @@ import (
 	"openseawave.com/rasbora/internal/config"
 	"openseawave.com/rasbora/internal/data"
 	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/health"
 	"openseawave.com/rasbora/internal/logger"
+	"openseawave.com/rasbora/internal/metrics"
 )
 
 // Name used as identifier.
@@ -44,6 +47,11 @@ type SystemRadar struct {
 	Database *database.Database
 	Feedback *data.Radar
 	workerId string
+	// disks mount paths monitored on each scan.
+	disks []string
+	// previousNetwork counters of previous scan used to compute network rates.
+	previousNetwork   *data.RadarNetwork
+	previousNetworkAt time.Time
 }
 
 // NewRadar make new system radar.
@@ -62,6 +70,9 @@ func (sr *SystemRadar) StartRadar(ctx context.Context) {
 	// get system radar worker id
 	sr.workerId = sr.Config.GetString("Components.SystemRadar.UniqueID")
 
+	// get mount paths of monitored disks
+	sr.disks = monitoredDisks(sr.Config)
+
 	// get system radar scan interval in seconds
 	scanSystemInterval := sr.Config.GetInt("Components.SystemRadar.ScanInterval")
 
@@ -73,11 +84,14 @@ func (sr *SystemRadar) StartRadar(ctx context.Context) {
 		},
 	)
 
+	health.WatchLoop(Name, time.Duration(scanSystemInterval)*time.Second)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
+			health.Beat(Name)
 			sr._scan()
 			time.Sleep(time.Duration(scanSystemInterval) * time.Second)
 		}
@@ -107,7 +121,7 @@ func (sr *SystemRadar) _scan() {
 	sr._send()
 }
 
-// _find search for system data.
+// _find search for system data, readings that fail are logged and left empty.
 func (sr *SystemRadar) _find() {
 
 	sr.Logger.Debug(
@@ -118,56 +132,101 @@ func (sr *SystemRadar) _find() {
 		},
 	)
 
-	sr.Feedback.SystemRadarId = sr.workerId
+	scannedAt := time.Now()
 
-	memoryInfo, err := mem.VirtualMemory()
-	if err != nil {
-		return
+	radar := &data.Radar{
+		SchemaVersion: data.RadarSchemaVersion,
+		SystemRadarId: sr.workerId,
+		ScannedAt:     scannedAt.UnixMilli(),
+		Disks:         []data.RadarDisk{},
 	}
-	memoryInfoJson, _ := json.Marshal(memoryInfo)
-	sr.Feedback.MemoryInfo = memoryInfoJson
 
-	cpuInfo, err := cpu.Info()
-	if err != nil {
-		return
+	if hostInfo, err := host.Info(); err != nil {
+		sr._readingFailed("host", err)
+	} else {
+		radar.Host = data.RadarHost{
+			Hostname:        hostInfo.Hostname,
+			Os:              hostInfo.OS,
+			Platform:        hostInfo.Platform,
+			PlatformVersion: hostInfo.PlatformVersion,
+			KernelVersion:   hostInfo.KernelVersion,
+			Uptime:          hostInfo.Uptime,
+		}
 	}
-	cpuInfoJson, _ := json.Marshal(cpuInfo)
-	sr.Feedback.CpuInfo = cpuInfoJson
 
-	percentageAll, err := cpu.Percent(time.Second, true)
-	if err != nil {
-		return
+	if cpuInfo, err := cpu.Info(); err != nil {
+		sr._readingFailed("cpu_info", err)
+	} else if len(cpuInfo) > 0 {
+		radar.Cpu.Model = cpuInfo[0].ModelName
 	}
-	percentageAllJson, _ := json.Marshal(percentageAll)
-	sr.Feedback.CpuUsageAll = percentageAllJson
 
-	diskStat, err := disk.Usage(sr.Config.GetString("Components.SystemRadar.DiskStat"))
-	if err != nil {
-		return
+	if percentageAll, err := cpu.Percent(time.Second, true); err != nil {
+		sr._readingFailed("cpu_usage", err)
+	} else {
+		radar.Cpu.Cores = len(percentageAll)
+		radar.Cpu.Percent = percentageAll
+		for core, percentage := range percentageAll {
+			metrics.SystemCpuUsage.WithLabelValues(sr.workerId, strconv.Itoa(core)).Set(percentage)
+		}
 	}
-	diskStatJson, _ := json.Marshal(diskStat)
-	sr.Feedback.DiskUsage = diskStatJson
 
-	networkStat, err := net.IOCounters(false)
-	if err != nil {
-		return
+	if memoryInfo, err := mem.VirtualMemory(); err != nil {
+		sr._readingFailed("memory", err)
+	} else {
+		radar.Memory = data.RadarMemory{
+			Used:      memoryInfo.Used,
+			Available: memoryInfo.Available,
+			Total:     memoryInfo.Total,
+		}
+		metrics.SystemMemory.WithLabelValues(sr.workerId, "total").Set(float64(memoryInfo.Total))
+		metrics.SystemMemory.WithLabelValues(sr.workerId, "used").Set(float64(memoryInfo.Used))
+		metrics.SystemMemory.WithLabelValues(sr.workerId, "available").Set(float64(memoryInfo.Available))
 	}
-	networkStatJson, _ := json.Marshal(networkStat)
-	sr.Feedback.NetworkStat = networkStatJson
 
-	networkInterfaces, err := net.Interfaces()
-	if err != nil {
-		return
+	for _, path := range sr.disks {
+		diskStat, err := disk.Usage(path)
+		if err != nil {
+			sr._readingFailed("disk:"+path, err)
+			continue
+		}
+		radar.Disks = append(radar.Disks, data.RadarDisk{
+			Path:  diskStat.Path,
+			Used:  diskStat.Used,
+			Free:  diskStat.Free,
+			Total: diskStat.Total,
+		})
+		metrics.SystemDisk.WithLabelValues(sr.workerId, diskStat.Path, "total").Set(float64(diskStat.Total))
+		metrics.SystemDisk.WithLabelValues(sr.workerId, diskStat.Path, "used").Set(float64(diskStat.Used))
+		metrics.SystemDisk.WithLabelValues(sr.workerId, diskStat.Path, "free").Set(float64(diskStat.Free))
 	}
-	networkInterfacesJson, _ := json.Marshal(networkInterfaces)
-	sr.Feedback.NetworkInterfaces = networkInterfacesJson
 
-	hostInfo, err := host.Info()
-	if err != nil {
-		return
+	if networkStat, err := net.IOCounters(true); err != nil {
+		sr._readingFailed("network", err)
+	} else {
+		network := sumNetworkCounters(networkStat)
+		if sr.previousNetwork != nil {
+			network = networkRates(*sr.previousNetwork, network, scannedAt.Sub(sr.previousNetworkAt))
+		}
+		sr.previousNetwork = &network
+		sr.previousNetworkAt = scannedAt
+
+		radar.Network = network
+		metrics.SystemNetwork.WithLabelValues(sr.workerId, "sent").Set(float64(network.TxBytes))
+		metrics.SystemNetwork.WithLabelValues(sr.workerId, "received").Set(float64(network.RxBytes))
 	}
-	hostInfoJson, _ := json.Marshal(hostInfo)
-	sr.Feedback.HostInfo = hostInfoJson
+
+	sr.Feedback = radar
+}
+
+// _readingFailed log reading of system data which cannot be read in this scan.
+func (sr *SystemRadar) _readingFailed(reading string, err error) {
+	sr.Logger.Warn(
+		"system_radar.find",
+		fmt.Sprintf("cannot read %v: %v", reading, err.Error()),
+		map[string]interface{}{
+			"systemradar_worker_id": sr.workerId,
+		},
+	)
 }
 
 // _send try to send radar data to real-time stream.
@@ -180,16 +239,7 @@ func (sr *SystemRadar) _send() {
 		},
 	)
 
-	if err := sr.Database.SendSystemRadarScannerData(map[string]interface{}{
-		"SystemRadarId":     sr.Feedback.SystemRadarId,
-		"HostInfo":          sr.Feedback.HostInfo,
-		"CpuUsageAll":       sr.Feedback.CpuUsageAll,
-		"DiskUsage":         sr.Feedback.DiskUsage,
-		"NetworkStat":       sr.Feedback.NetworkStat,
-		"CpuInfo":           sr.Feedback.CpuInfo,
-		"MemoryInfo":        sr.Feedback.MemoryInfo,
-		"NetworkInterfaces": sr.Feedback.NetworkInterfaces,
-	}); err != nil {
+	if err := sr.Database.SendSystemRadarScannerData(*sr.Feedback); err != nil {
 		sr.Logger.Error(
 			"system_radar.send",
 			err.Error(),
@@ -207,3 +257,45 @@ func (sr *SystemRadar) _send() {
 		},
 	)
 }
+
+// monitoredDisks mount paths from Components.SystemRadar.Disks, or single DiskStat path of older configs.
+func monitoredDisks(cfg *config.Config) []string {
+	if disks := cfg.GetStringSlice("Components.SystemRadar.Disks"); len(disks) > 0 {
+		return disks
+	}
+
+	if diskStat := cfg.GetString("Components.SystemRadar.DiskStat"); diskStat != "" {
+		return []string{diskStat}
+	}
+
+	return []string{"/"}
+}
+
+// sumNetworkCounters add counters of all interfaces except loopback.
+func sumNetworkCounters(counters []net.IOCountersStat) data.RadarNetwork {
+	var network data.RadarNetwork
+	for _, counter := range counters {
+		if counter.Name == "lo" {
+			continue
+		}
+		network.RxBytes += counter.BytesRecv
+		network.TxBytes += counter.BytesSent
+	}
+	return network
+}
+
+// networkRates compute bytes per second since previous counters, counters going back (reboot or overflow) give zero rate.
+func networkRates(previous, current data.RadarNetwork, elapsed time.Duration) data.RadarNetwork {
+	if elapsed <= 0 {
+		return current
+	}
+
+	if current.RxBytes >= previous.RxBytes {
+		current.RxBytesPerSecond = float64(current.RxBytes-previous.RxBytes) / elapsed.Seconds()
+	}
+	if current.TxBytes >= previous.TxBytes {
+		current.TxBytesPerSecond = float64(current.TxBytes-previous.TxBytes) / elapsed.Seconds()
+	}
+
+	return current
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package systemradar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+)
+
+func TestMonitoredDisks(t *testing.T) {
+	v := viper.New()
+	cfg := config.New(&config.ViperConfigManager{Viper: v})
+	assert.Equal(t, []string{"/"}, monitoredDisks(cfg))
+
+	v.Set("Components.SystemRadar.DiskStat", "/data")
+	assert.Equal(t, []string{"/data"}, monitoredDisks(cfg))
+
+	v.Set("Components.SystemRadar.Disks", []string{"/", "/tmp/rasbora-workspace"})
+	assert.Equal(t, []string{"/", "/tmp/rasbora-workspace"}, monitoredDisks(cfg))
+}
+
+func TestSumNetworkCounters(t *testing.T) {
+	network := sumNetworkCounters([]net.IOCountersStat{
+		{Name: "lo", BytesRecv: 1000, BytesSent: 1000},
+		{Name: "eth0", BytesRecv: 100, BytesSent: 50},
+		{Name: "eth1", BytesRecv: 10, BytesSent: 5},
+	})
+
+	assert.Equal(t, data.RadarNetwork{RxBytes: 110, TxBytes: 55}, network)
+}
+
+func TestNetworkRates(t *testing.T) {
+	previous := data.RadarNetwork{RxBytes: 1000, TxBytes: 500}
+
+	network := networkRates(previous, data.RadarNetwork{RxBytes: 3000, TxBytes: 1500}, 2*time.Second)
+	assert.Equal(t, 1000.0, network.RxBytesPerSecond)
+	assert.Equal(t, 500.0, network.TxBytesPerSecond)
+
+	// counters reset after reboot.
+	network = networkRates(previous, data.RadarNetwork{RxBytes: 10, TxBytes: 600}, time.Second)
+	assert.Equal(t, 0.0, network.RxBytesPerSecond)
+	assert.Equal(t, 100.0, network.TxBytesPerSecond)
+}
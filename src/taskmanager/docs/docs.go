@@ -23,8 +23,337 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/batches/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Count tasks of batch in each status with percentage of done tasks.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get batch progress.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Batch id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "payload": {
+                                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.BatchProgress"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/cluster/nodes": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List nodes sending heartbeat with their components, last seen time, liveness, latest cpu, memory and disk usage, current tasks and throughput.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cluster"
+                ],
+                "summary": "Get cluster nodes.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "payload": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/openseawave_com_rasbora_internal_data.ClusterNode"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/keys": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create new api key, the returned api_key is shown only once. Key created by key of tenant belongs to same tenant.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "Create new api key.",
+                "parameters": [
+                    {
+                        "description": "Key data",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.ApiKey"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revoke api key, requests using it are rejected immediately.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "Revoke api key.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create many tasks in single request, every task is validated alone and result returned per task.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Create batch of tasks.",
+                "parameters": [
+                    {
+                        "description": "Batch data",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.TaskBatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    }
+                }
+            }
+        },
         "/tasks/create": {
             "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "description": "Create new task for video transcoding.",
                 "consumes": [
                     "application/json"
@@ -45,38 +374,427 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Task"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Repeating request with same key returns the original task",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get task with its current status, waiting tasks include their effective priority after aging.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "payload": {
+                                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.QueueableDetails"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change priority of task still waiting, every change is recorded in task priority_changes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Change task priority.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New priority",
+                        "name": "priority",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.PriorityChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "payload": {
+                                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Task"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "openseawave_com_rasbora_internal_data.ApiKey": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "created_at": {
+                    "description": "Timestamp indicating when the key was created.",
+                    "type": "integer"
+                },
+                "created_by": {
+                    "description": "Identifier of the key that created this key.",
+                    "type": "string"
+                },
+                "handlers": {
+                    "description": "Handlers the key is allowed to use, empty means all handlers.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "key_id": {
+                    "description": "Unique identifier for the key, first part of the token.",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Human readable name of the key owner.",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "description": "Timestamp indicating when the key was revoked.",
+                    "type": "integer"
+                },
+                "scopes": {
+                    "description": "Scopes granted to the key.",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/openseawave_com_rasbora_internal_data.ApiKeyScope"
+                    }
+                },
+                "secret_hash": {
+                    "description": "Hex encoded sha256 of the key secret, the secret itself is never saved.",
+                    "type": "string"
+                },
+                "tenant": {
+                    "description": "Tenant owning tasks created with the key, empty means shared tenant.\nOnly letters, digits, \"_\" and \"-\" are allowed, up to 64 characters.",
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.ApiKeyScope": {
+            "type": "string",
+            "enum": [
+                "create",
+                "read",
+                "cancel",
+                "admin"
+            ],
+            "x-enum-varnames": [
+                "ApiKeyScopeCreate",
+                "ApiKeyScopeRead",
+                "ApiKeyScopeCancel",
+                "ApiKeyScopeAdmin"
+            ]
+        },
+        "openseawave_com_rasbora_internal_data.BatchProgress": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "description": "Unique identifier for the batch.",
+                    "type": "string"
+                },
+                "percentage": {
+                    "description": "Percentage of tasks finished, failed or cancelled.",
+                    "type": "number"
+                },
+                "statuses": {
+                    "description": "Number of tasks in each status.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
                     }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Response"
-                        }
+                },
+                "total": {
+                    "description": "Number of tasks in the batch.",
+                    "type": "integer"
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.ClusterNode": {
+            "type": "object",
+            "properties": {
+                "alive": {
+                    "type": "boolean"
+                },
+                "components": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/openseawave_com_rasbora_internal_data.ClusterWorker"
+                    }
+                },
+                "current_tasks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
+                },
+                "last_seen": {
+                    "type": "integer"
+                },
+                "node": {
+                    "type": "string"
+                },
+                "resources": {
+                    "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Radar"
+                },
+                "throughput": {
+                    "$ref": "#/definitions/openseawave_com_rasbora_internal_data.NodeThroughput"
                 }
             }
-        }
-    },
-    "definitions": {
+        },
+        "openseawave_com_rasbora_internal_data.ClusterWorker": {
+            "type": "object",
+            "properties": {
+                "alive": {
+                    "type": "boolean"
+                },
+                "current_tasks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "finished_tasks": {
+                    "type": "integer"
+                },
+                "last_seen": {
+                    "type": "integer"
+                },
+                "worker_id": {
+                    "type": "string"
+                },
+                "worker_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.ErrorCode": {
+            "type": "string",
+            "enum": [
+                "INVALID_TASK",
+                "INPUT_NOT_FOUND",
+                "INPUT_UNREADABLE",
+                "UNSUPPORTED_CODEC",
+                "HANDLER_UNKNOWN",
+                "HANDLER_INVALID",
+                "TRANSCODE_FAILED",
+                "STORAGE_UNAVAILABLE",
+                "UPLOAD_FAILED",
+                "RESOURCE_EXHAUSTED",
+                "TIMEOUT",
+                "STALLED",
+                "CANCELLED",
+                "INTERNAL"
+            ],
+            "x-enum-varnames": [
+                "InvalidTaskErrorCode",
+                "InputNotFoundErrorCode",
+                "InputUnreadableErrorCode",
+                "UnsupportedCodecErrorCode",
+                "HandlerUnknownErrorCode",
+                "HandlerInvalidErrorCode",
+                "TranscodeFailedErrorCode",
+                "StorageUnavailableErrorCode",
+                "UploadFailedErrorCode",
+                "ResourceExhaustedErrorCode",
+                "TimeoutErrorCode",
+                "StalledErrorCode",
+                "CancelledErrorCode",
+                "InternalErrorCode"
+            ]
+        },
         "openseawave_com_rasbora_internal_data.FileSystemType": {
             "type": "string",
             "enum": [
@@ -88,6 +806,282 @@ const docTemplate = `{
                 "ObjectFileSystemType"
             ]
         },
+        "openseawave_com_rasbora_internal_data.NodeThroughput": {
+            "type": "object",
+            "properties": {
+                "finished_tasks": {
+                    "type": "integer"
+                },
+                "tasks_per_hour": {
+                    "type": "number"
+                },
+                "window_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.PriorityChange": {
+            "type": "object",
+            "properties": {
+                "changed_at": {
+                    "description": "Timestamp indicating when the priority was changed.",
+                    "type": "integer"
+                },
+                "changed_by": {
+                    "description": "Api key id of who changed the priority.",
+                    "type": "string"
+                },
+                "previous_priority": {
+                    "description": "Priority before the change.",
+                    "type": "number"
+                },
+                "priority": {
+                    "description": "Priority after the change.",
+                    "type": "number"
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.PriorityChangeRequest": {
+            "type": "object",
+            "required": [
+                "task_priority"
+            ],
+            "properties": {
+                "task_priority": {
+                    "description": "New priority of the task.",
+                    "type": "number"
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.Queueable": {
+            "type": "object",
+            "properties": {
+                "queue_item_capabilities": {
+                    "description": "Capabilities worker needs to process the item.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "queue_item_enqueued_at": {
+                    "description": "Timestamp when item was added to waiting queue, set when item is dequeued.",
+                    "type": "integer"
+                },
+                "queue_item_error_code": {
+                    "description": "Code of last failure of the item.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.ErrorCode"
+                        }
+                    ]
+                },
+                "queue_item_excluded_workers": {
+                    "description": "Workers that should not take the item until given timestamp.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "queue_item_fingerprint": {
+                    "description": "Fingerprint of task as client created it, payload may be changed while item is processed.",
+                    "type": "string"
+                },
+                "queue_item_id": {
+                    "description": "Unique identifier for the queue.",
+                    "type": "string"
+                },
+                "queue_item_payload": {
+                    "description": "Payload to process when its dequeued."
+                },
+                "queue_item_priority": {
+                    "description": "Priority level assigned to the queue.",
+                    "type": "number"
+                },
+                "queue_item_requeues": {
+                    "description": "Times item was sent back to waiting queue without counting a retry, like when worker has not enough disk space.",
+                    "type": "integer"
+                },
+                "queue_item_run_at": {
+                    "description": "Timestamp when item should be moved to waiting queue, item is waiting immediately when not set.",
+                    "type": "integer"
+                },
+                "queue_item_tenant": {
+                    "description": "Tenant owning the item, items of each tenant wait in their own queue.",
+                    "type": "string"
+                },
+                "queue_item_trace_context": {
+                    "description": "Trace context of span that created the item, processing of item continues its trace.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.QueueableDetails": {
+            "type": "object",
+            "properties": {
+                "effective_priority": {
+                    "description": "Priority of waiting item after aging, lower is dequeued first.",
+                    "type": "number"
+                },
+                "error_code": {
+                    "description": "Code of last failure of the item, CANCELLED for cancelled items.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.ErrorCode"
+                        }
+                    ]
+                },
+                "item": {
+                    "description": "Item saved in the queue.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Queueable"
+                        }
+                    ]
+                },
+                "log": {
+                    "description": "Last failure reason saved for the item.",
+                    "type": "string"
+                },
+                "retryable": {
+                    "description": "Whether item that failed with error code may succeed when retried, set only with error code.",
+                    "type": "boolean"
+                },
+                "status": {
+                    "description": "Current status of the item (delayed, waiting, working, finished, failed, cancelled).",
+                    "type": "string"
+                },
+                "worker": {
+                    "description": "Worker currently processing the item.",
+                    "type": "string"
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.Radar": {
+            "type": "object",
+            "properties": {
+                "cpu": {
+                    "$ref": "#/definitions/openseawave_com_rasbora_internal_data.RadarCpu"
+                },
+                "disks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/openseawave_com_rasbora_internal_data.RadarDisk"
+                    }
+                },
+                "host": {
+                    "$ref": "#/definitions/openseawave_com_rasbora_internal_data.RadarHost"
+                },
+                "memory": {
+                    "$ref": "#/definitions/openseawave_com_rasbora_internal_data.RadarMemory"
+                },
+                "network": {
+                    "$ref": "#/definitions/openseawave_com_rasbora_internal_data.RadarNetwork"
+                },
+                "scanned_at": {
+                    "type": "integer"
+                },
+                "schema_version": {
+                    "type": "integer"
+                },
+                "system_radar_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.RadarCpu": {
+            "type": "object",
+            "properties": {
+                "cores": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "percent": {
+                    "description": "Percent usage of each logical core.",
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.RadarDisk": {
+            "type": "object",
+            "properties": {
+                "free": {
+                    "type": "integer"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "used": {
+                    "type": "integer"
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.RadarHost": {
+            "type": "object",
+            "properties": {
+                "hostname": {
+                    "type": "string"
+                },
+                "kernel_version": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "platform_version": {
+                    "type": "string"
+                },
+                "uptime": {
+                    "type": "integer"
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.RadarMemory": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "used": {
+                    "type": "integer"
+                }
+            }
+        },
+        "openseawave_com_rasbora_internal_data.RadarNetwork": {
+            "type": "object",
+            "properties": {
+                "rx_bytes": {
+                    "description": "RxBytes and TxBytes counters of all interfaces except loopback.",
+                    "type": "integer"
+                },
+                "rx_bytes_per_second": {
+                    "description": "RxBytesPerSecond and TxBytesPerSecond rates since previous scan, zero on first scan.",
+                    "type": "number"
+                },
+                "tx_bytes": {
+                    "type": "integer"
+                },
+                "tx_bytes_per_second": {
+                    "type": "number"
+                }
+            }
+        },
         "openseawave_com_rasbora_internal_data.Response": {
             "type": "object",
             "properties": {
@@ -107,6 +1101,10 @@ const docTemplate = `{
                 "task_priority"
             ],
             "properties": {
+                "batch_id": {
+                    "description": "Batch grouping the task, set when task created with batch endpoint.",
+                    "type": "string"
+                },
                 "callback": {
                     "description": "Callback struct holds details for a callback associated with the task.",
                     "type": "object",
@@ -128,6 +1126,11 @@ const docTemplate = `{
                     "description": "Timestamp indicating when the task was created.",
                     "type": "integer"
                 },
+                "delay_seconds": {
+                    "description": "Seconds to wait before task starts, used when run_at is not set.",
+                    "type": "integer",
+                    "minimum": 0
+                },
                 "failed_at": {
                     "description": "Timestamp indicating when the task failed.",
                     "type": "integer"
@@ -136,6 +1139,25 @@ const docTemplate = `{
                     "description": "Timestamp indicating when the task finished.",
                     "type": "integer"
                 },
+                "priority_changes": {
+                    "description": "Changes of priority made while task was waiting.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/openseawave_com_rasbora_internal_data.PriorityChange"
+                    }
+                },
+                "required_capabilities": {
+                    "description": "Capabilities worker needs to process the task, capabilities implied by handler are added to them.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "run_at": {
+                    "description": "Timestamp when task should start, task waits in delayed queue until then.",
+                    "type": "integer",
+                    "minimum": 0
+                },
                 "started_at": {
                     "description": "Timestamp indicating when the task started.",
                     "type": "integer"
@@ -152,6 +1174,10 @@ const docTemplate = `{
                     "description": "Priority level assigned to the task.",
                     "type": "number"
                 },
+                "tenant": {
+                    "description": "Tenant owning the task, taken from the api key used to create it.",
+                    "type": "string"
+                },
                 "video_transcoder": {
                     "description": "VideoTranscoder contains details about video transcoding for the task.",
                     "type": "object",
@@ -209,6 +1235,33 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "openseawave_com_rasbora_internal_data.TaskBatch": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "description": "Unique identifier for the batch, setting it groups tasks.\nOnly letters, digits, \"_\" and \"-\" are allowed, up to 64 characters.",
+                    "type": "string"
+                },
+                "group": {
+                    "description": "Group tasks under batch id, generated when empty.",
+                    "type": "boolean"
+                },
+                "tasks": {
+                    "description": "Tasks to create.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/openseawave_com_rasbora_internal_data.Task"
+                    }
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-Api-Key",
+            "in": "header"
         }
     }
 }`
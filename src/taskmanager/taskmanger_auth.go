@@ -0,0 +1,219 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+)
+
+// apiKeyPrefix used at the start of every generated api key token.
+const apiKeyPrefix = "rsb_"
+
+// placeholderAdminKey admin key shipped in .env.example, it is public so it is never accepted.
+const placeholderAdminKey = "change-me-to-a-long-random-admin-key"
+
+var (
+	// ErrUnauthenticated returned when token is missing, unknown, revoked or expired.
+	ErrUnauthenticated = errors.New("missing or invalid api key")
+	// ErrPermissionDenied returned when key is not granted the required scope or handler.
+	ErrPermissionDenied = errors.New("api key is not allowed to perform this operation")
+	// ErrAuthNotConfigured returned on startup when auth is enabled without admin key or jwt secret, every request would be rejected.
+	ErrAuthNotConfigured = errors.New("auth is enabled but neither Components.TaskManagement.Auth.AdminKey nor Components.TaskManagement.Auth.Jwt.Secret is set, set one of them or disable auth")
+	// ErrPlaceholderAdminKey returned on startup when admin key is still the example one.
+	ErrPlaceholderAdminKey = errors.New("Components.TaskManagement.Auth.AdminKey is the example key from .env.example, set a long random key")
+)
+
+// authenticator verify api keys saved in database and jwt signed by configured secret.
+type authenticator struct {
+	enabled   bool
+	adminKey  string
+	jwtSecret []byte
+	database  *database.Database
+}
+
+// jwtClaims holds claims accepted inside jwt.
+type jwtClaims struct {
+	Scopes   []data.ApiKeyScope `json:"scopes"`
+	Handlers []string           `json:"handlers"`
+	Tenant   string             `json:"tenant"`
+	jwt.RegisteredClaims
+}
+
+// newAuthenticator create authenticator from task manager auth config, config rejecting every request or using example admin key is refused.
+func newAuthenticator(cfg *config.Config, db *database.Database) (*authenticator, error) {
+	a := &authenticator{
+		enabled:   cfg.GetBool("Components.TaskManagement.Auth.Enabled"),
+		adminKey:  cfg.GetString("Components.TaskManagement.Auth.AdminKey"),
+		jwtSecret: []byte(cfg.GetString("Components.TaskManagement.Auth.Jwt.Secret")),
+		database:  db,
+	}
+
+	if a.adminKey == placeholderAdminKey {
+		return nil, ErrPlaceholderAdminKey
+	}
+
+	if a.enabled && a.adminKey == "" && len(a.jwtSecret) == 0 {
+		return nil, ErrAuthNotConfigured
+	}
+
+	return a, nil
+}
+
+// authorize authenticate token and check it is granted the scope.
+func (a *authenticator) authorize(token string, scope data.ApiKeyScope) (data.ApiKey, error) {
+	if !a.enabled {
+		return data.ApiKey{ID: "anonymous", Scopes: []data.ApiKeyScope{data.ApiKeyScopeAdmin}}, nil
+	}
+
+	key, err := a.authenticate(token)
+	if err != nil {
+		return data.ApiKey{}, err
+	}
+
+	if !key.HasScope(scope) {
+		return data.ApiKey{}, ErrPermissionDenied
+	}
+
+	return key, nil
+}
+
+// authenticate find key matching token.
+func (a *authenticator) authenticate(token string) (data.ApiKey, error) {
+	if token == "" {
+		return data.ApiKey{}, ErrUnauthenticated
+	}
+
+	if a.adminKey != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.adminKey)) == 1 {
+		return data.ApiKey{ID: "admin", Name: "admin", Scopes: []data.ApiKeyScope{data.ApiKeyScopeAdmin}}, nil
+	}
+
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		return a._authenticateApiKey(token)
+	}
+
+	if len(a.jwtSecret) > 0 && strings.Count(token, ".") == 2 {
+		return a._authenticateJwt(token)
+	}
+
+	return data.ApiKey{}, ErrUnauthenticated
+}
+
+// newApiKey generate key with random secret, return token which is shown only once.
+// Key created by key of tenant always belongs to same tenant, so it cannot be used to reach other tenants.
+func (a *authenticator) newApiKey(name string, tenant string, scopes []data.ApiKeyScope, handlers []string, creator data.ApiKey) (string, data.ApiKey, error) {
+	if creator.Tenant != "" {
+		tenant = creator.Tenant
+	}
+
+	keyId, err := _randomString(9)
+	if err != nil {
+		return "", data.ApiKey{}, err
+	}
+
+	secret, err := _randomString(32)
+	if err != nil {
+		return "", data.ApiKey{}, err
+	}
+
+	key := data.ApiKey{
+		ID:         keyId,
+		Name:       name,
+		Tenant:     tenant,
+		SecretHash: _hashSecret(secret),
+		Scopes:     scopes,
+		Handlers:   handlers,
+		CreatedBy:  creator.ID,
+		CreatedAt:  time.Now().UnixMilli(),
+	}
+
+	return fmt.Sprintf("%v%v.%v", apiKeyPrefix, keyId, secret), key, nil
+}
+
+// _authenticateApiKey verify token with format "rsb_<key id>.<secret>".
+func (a *authenticator) _authenticateApiKey(token string) (data.ApiKey, error) {
+	keyId, secret, found := strings.Cut(strings.TrimPrefix(token, apiKeyPrefix), ".")
+	if !found {
+		return data.ApiKey{}, ErrUnauthenticated
+	}
+
+	key, err := a.database.GetApiKey(keyId)
+	if errors.Is(err, database.ErrNotFound) {
+		return data.ApiKey{}, ErrUnauthenticated
+	}
+	if err != nil {
+		return data.ApiKey{}, err
+	}
+
+	if key.RevokedAt != 0 || subtle.ConstantTimeCompare([]byte(_hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return data.ApiKey{}, ErrUnauthenticated
+	}
+
+	return key, nil
+}
+
+// _authenticateJwt verify hmac signed jwt, subject is used as key id.
+func (a *authenticator) _authenticateJwt(token string) (data.ApiKey, error) {
+	var claims jwtClaims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		return a.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}), jwt.WithExpirationRequired())
+	if err != nil || claims.Subject == "" {
+		return data.ApiKey{}, ErrUnauthenticated
+	}
+
+	if claims.Tenant != "" && !isValidIdentifier(claims.Tenant) {
+		return data.ApiKey{}, ErrUnauthenticated
+	}
+
+	return data.ApiKey{
+		ID:       claims.Subject,
+		Name:     claims.Subject,
+		Tenant:   claims.Tenant,
+		Scopes:   claims.Scopes,
+		Handlers: claims.Handlers,
+	}, nil
+}
+
+// _hashSecret return hex encoded sha256 of the secret.
+func _hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// _randomString return url safe random string generated from n random bytes.
+func _randomString(n int) (string, error) {
+	buffer := make([]byte, n)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
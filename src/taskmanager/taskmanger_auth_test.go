@@ -0,0 +1,283 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/src/taskmanager/rasborapb"
+)
+
+// fakeKeysDatabase keep api keys in memory, other database methods are not used.
+type fakeKeysDatabase struct {
+	database.Interface
+	keys map[string]data.ApiKey
+}
+
+func (f *fakeKeysDatabase) SaveApiKey(key data.ApiKey) error {
+	f.keys[key.ID] = key
+	return nil
+}
+
+func (f *fakeKeysDatabase) GetApiKey(keyId string) (data.ApiKey, error) {
+	key, ok := f.keys[keyId]
+	if !ok {
+		return data.ApiKey{}, database.ErrNotFound
+	}
+	return key, nil
+}
+
+func newTestAuthenticator() *authenticator {
+	return &authenticator{
+		enabled:   true,
+		adminKey:  "root-key",
+		jwtSecret: []byte("jwt-secret"),
+		database:  database.New(&fakeKeysDatabase{keys: map[string]data.ApiKey{}}),
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	newConfig := func(enabled bool, adminKey string, jwtSecret string) *config.Config {
+		v := viper.New()
+		v.Set("Components.TaskManagement.Auth.Enabled", enabled)
+		v.Set("Components.TaskManagement.Auth.AdminKey", adminKey)
+		v.Set("Components.TaskManagement.Auth.Jwt.Secret", jwtSecret)
+		return config.New(&config.ViperConfigManager{Viper: v})
+	}
+	db := database.New(&fakeKeysDatabase{keys: map[string]data.ApiKey{}})
+
+	_, err := newAuthenticator(newConfig(true, "", ""), db)
+	assert.ErrorIs(t, err, ErrAuthNotConfigured)
+
+	_, err = newAuthenticator(newConfig(true, placeholderAdminKey, ""), db)
+	assert.ErrorIs(t, err, ErrPlaceholderAdminKey)
+
+	_, err = newAuthenticator(newConfig(false, placeholderAdminKey, ""), db)
+	assert.ErrorIs(t, err, ErrPlaceholderAdminKey)
+
+	for _, cfg := range []*config.Config{newConfig(true, "root-key", ""), newConfig(true, "", "jwt-secret"), newConfig(false, "", "")} {
+		auth, err := newAuthenticator(cfg, db)
+		assert.NoError(t, err)
+		assert.NotNil(t, auth)
+	}
+}
+
+func TestAuthenticator_ApiKey(t *testing.T) {
+	auth := newTestAuthenticator()
+
+	token, key, err := auth.newApiKey("uploader", "", []data.ApiKeyScope{data.ApiKeyScopeCreate}, []string{"default"}, data.ApiKey{ID: "admin", Scopes: []data.ApiKeyScope{data.ApiKeyScopeAdmin}})
+	assert.NoError(t, err)
+	assert.NoError(t, auth.database.SaveApiKey(key))
+	assert.NotContains(t, key.SecretHash, token)
+
+	authenticated, err := auth.authorize(token, data.ApiKeyScopeCreate)
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, authenticated.ID)
+
+	_, err = auth.authorize(token, data.ApiKeyScopeCancel)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	_, err = auth.authorize(token+"x", data.ApiKeyScopeCreate)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	key.RevokedAt = time.Now().UnixMilli()
+	assert.NoError(t, auth.database.SaveApiKey(key))
+
+	_, err = auth.authorize(token, data.ApiKeyScopeCreate)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestAuthenticator_AdminKey(t *testing.T) {
+	auth := newTestAuthenticator()
+
+	key, err := auth.authorize("root-key", data.ApiKeyScopeCancel)
+	assert.NoError(t, err)
+	assert.True(t, key.HasScope(data.ApiKeyScopeAdmin))
+
+	_, err = auth.authorize("", data.ApiKeyScopeRead)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestAuthenticator_Jwt(t *testing.T) {
+	auth := newTestAuthenticator()
+
+	sign := func(secret string, expiresAt time.Time) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims{
+			Scopes:   []data.ApiKeyScope{data.ApiKeyScopeRead},
+			Handlers: []string{"default"},
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "team-a",
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		}).SignedString([]byte(secret))
+		assert.NoError(t, err)
+		return token
+	}
+
+	key, err := auth.authorize(sign("jwt-secret", time.Now().Add(time.Hour)), data.ApiKeyScopeRead)
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a", key.ID)
+	assert.False(t, key.AllowsHandler("gpu"))
+
+	_, err = auth.authorize(sign("other-secret", time.Now().Add(time.Hour)), data.ApiKeyScopeRead)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	_, err = auth.authorize(sign("jwt-secret", time.Now().Add(-time.Hour)), data.ApiKeyScopeRead)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestAuthenticator_JwtTenant(t *testing.T) {
+	auth := newTestAuthenticator()
+
+	sign := func(tenant string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims{
+			Scopes: []data.ApiKeyScope{data.ApiKeyScopeRead},
+			Tenant: tenant,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "team-a",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}).SignedString([]byte("jwt-secret"))
+		assert.NoError(t, err)
+		return token
+	}
+
+	key, err := auth.authorize(sign("team-a"), data.ApiKeyScopeRead)
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a", key.Tenant)
+
+	// tenant is used inside database keys, separator would let it reach keys of other tenants.
+	_, err = auth.authorize(sign("team-a:batch"), data.ApiKeyScopeRead)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestAuthenticator_Disabled(t *testing.T) {
+	auth := newTestAuthenticator()
+	auth.enabled = false
+
+	_, err := auth.authorize("", data.ApiKeyScopeAdmin)
+	assert.NoError(t, err)
+}
+
+func TestAuthenticator_ApiKeyCreatedByTenant(t *testing.T) {
+	auth := newTestAuthenticator()
+	creator := data.ApiKey{ID: "team-a-admin", Tenant: "team-a", Scopes: []data.ApiKeyScope{data.ApiKeyScopeAdmin}}
+
+	// admin of tenant cannot create key for other tenant or without tenant.
+	for _, tenant := range []string{"team-b", ""} {
+		token, key, err := auth.newApiKey("uploader", tenant, []data.ApiKeyScope{data.ApiKeyScopeAdmin}, nil, creator)
+		assert.NoError(t, err)
+		assert.Equal(t, "team-a", key.Tenant)
+		assert.Equal(t, "team-a-admin", key.CreatedBy)
+		assert.NoError(t, auth.database.SaveApiKey(key))
+
+		authenticated, err := auth.authorize(token, data.ApiKeyScopeRead)
+		assert.NoError(t, err)
+		assert.Equal(t, "team-a", authenticated.Tenant)
+	}
+
+	// key without tenant can create key for any tenant.
+	_, key, err := auth.newApiKey("uploader", "team-b", []data.ApiKeyScope{data.ApiKeyScopeCreate}, nil, data.ApiKey{ID: "admin"})
+	assert.NoError(t, err)
+	assert.Equal(t, "team-b", key.Tenant)
+}
+
+// fakeTenantKeysDatabase keep api keys and tasks of two tenants in memory.
+type fakeTenantKeysDatabase struct {
+	fakeKeysDatabase
+	usage    data.TenantUsage
+	tasks    []data.QueueableDetails
+	enqueued []data.Queueable
+}
+
+func (f *fakeTenantKeysDatabase) TenantUsage(queueName string, tenant string) (data.TenantUsage, error) {
+	return f.usage, nil
+}
+
+func (f *fakeTenantKeysDatabase) EnqueueUnique(queueName string, item data.Queueable, maxActive int64) error {
+	f.enqueued = append(f.enqueued, item)
+	return nil
+}
+
+func (f *fakeTenantKeysDatabase) Details(queueName string, itemId string) (data.QueueableDetails, error) {
+	for _, task := range f.tasks {
+		if task.Item.ID == itemId {
+			return task, nil
+		}
+	}
+	return data.QueueableDetails{}, database.ErrNotFound
+}
+
+func (f *fakeTenantKeysDatabase) List(queueName string, status string, tenant string, cursor uint64, count int64) ([]data.QueueableDetails, uint64, error) {
+	var tasks []data.QueueableDetails
+	for _, task := range f.tasks {
+		if tenant == "" || task.Item.Tenant == tenant {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, 0, nil
+}
+
+func TestAuthenticator_ApiKeyTenant(t *testing.T) {
+	db := &fakeTenantKeysDatabase{
+		fakeKeysDatabase: fakeKeysDatabase{keys: map[string]data.ApiKey{}},
+		usage:            data.TenantUsage{ActiveTasks: 2},
+		tasks: []data.QueueableDetails{
+			{Item: data.Queueable{ID: "task-a", Tenant: "team-a", Payload: data.Task{ID: "task-a"}}, Status: "waiting"},
+			{Item: data.Queueable{ID: "task-b", Tenant: "team-b", Payload: data.Task{ID: "task-b"}}, Status: "waiting"},
+		},
+	}
+	auth := &authenticator{enabled: true, adminKey: "root-key", database: database.New(db)}
+
+	token, key, err := auth.newApiKey("uploader", "team-a", []data.ApiKeyScope{data.ApiKeyScopeCreate, data.ApiKeyScopeRead}, nil, data.ApiKey{ID: "admin", Scopes: []data.ApiKeyScope{data.ApiKeyScopeAdmin}})
+	assert.NoError(t, err)
+	assert.NoError(t, auth.database.SaveApiKey(key))
+
+	authenticated, err := auth.authorize(token, data.ApiKeyScopeRead)
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a", authenticated.Tenant)
+
+	// get task only sees tasks of key tenant.
+	_, err = tenantTaskDetails(auth.database, "video_transcoder", authenticated, "task-a")
+	assert.NoError(t, err)
+	_, err = tenantTaskDetails(auth.database, "video_transcoder", authenticated, "task-b")
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	// list tasks only returns tasks of key tenant.
+	gtm := &GrpcTaskManager{Database: auth.database, _videoTranscoderQueue: "video_transcoder"}
+	response, err := gtm.ListTasks(context.WithValue(context.Background(), apiKeyContextKey{}, authenticated), &rasborapb.ListTasksRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, response.GetTasks(), 1) {
+		assert.Equal(t, "task-a", response.GetTasks()[0].GetTask().GetTaskId())
+	}
+
+	// quota of key tenant is applied, default tenant allows two concurrent tasks.
+	creator := &taskCreator{config: newTestTenantConfig(), database: auth.database, videoTranscoderQueue: "video_transcoder"}
+	task := newTestTask()
+	_, err = creator.create(context.Background(), authenticated, &task, "")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Empty(t, db.enqueued)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"sort"
+	"time"
+
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/src/systemradar"
+	"openseawave.com/rasbora/src/videotranscoder"
+)
+
+// clusterInventory build view of cluster nodes from heartbeats and system radar entries.
+type clusterInventory struct {
+	database             *database.Database
+	videoTranscoderQueue string
+	aliveAfter           time.Duration
+	throughputWindow     time.Duration
+	radarScanLimit       int64
+}
+
+// newClusterInventory create cluster inventory from task manager config.
+func newClusterInventory(cfg *config.Config, db *database.Database) *clusterInventory {
+	aliveAfter := time.Duration(cfg.GetInt("Components.TaskManagement.Cluster.AliveAfter")) * time.Second
+	if aliveAfter <= 0 {
+		aliveAfter = 3 * time.Duration(cfg.GetInt("Heartbeat.SendInterval")) * time.Second
+	}
+
+	throughputWindow := time.Duration(cfg.GetInt("Components.TaskManagement.Cluster.ThroughputWindow")) * time.Second
+	if throughputWindow <= 0 {
+		throughputWindow = time.Hour
+	}
+
+	radarScanLimit := int64(cfg.GetInt("Components.TaskManagement.Cluster.RadarScanLimit"))
+	if radarScanLimit <= 0 {
+		radarScanLimit = 1000
+	}
+
+	return &clusterInventory{
+		database:             db,
+		videoTranscoderQueue: cfg.GetString("Components.VideoTranscoding.Queue"),
+		aliveAfter:           aliveAfter,
+		throughputWindow:     throughputWindow,
+		radarScanLimit:       radarScanLimit,
+	}
+}
+
+// nodes group workers by node they run on, sorted by node name.
+func (ci *clusterInventory) nodes() ([]data.ClusterNode, error) {
+	workers, err := ci.database.ClusterWorkers(ci.videoTranscoderQueue, ci.throughputWindow, ci.radarScanLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	nodes := map[string]*data.ClusterNode{}
+
+	for _, worker := range workers {
+		name := worker.Node
+		if name == "" {
+			// worker sent heartbeat before nodes were recorded, show it as its own node.
+			name = worker.WorkerId
+		}
+
+		node, ok := nodes[name]
+		if !ok {
+			node = &data.ClusterNode{
+				Node:         name,
+				CurrentTasks: []string{},
+				Throughput: data.NodeThroughput{
+					WindowSeconds: int64(ci.throughputWindow / time.Second),
+				},
+			}
+			nodes[name] = node
+		}
+
+		worker.Alive = now-worker.LastSeen <= ci.aliveAfter.Milliseconds()
+
+		node.Alive = node.Alive || worker.Alive
+		if worker.LastSeen > node.LastSeen {
+			node.LastSeen = worker.LastSeen
+		}
+
+		if worker.WorkerType == videotranscoder.Name {
+			node.CurrentTasks = append(node.CurrentTasks, worker.CurrentTasks...)
+			node.Throughput.FinishedTasks += worker.FinishedTasks
+		}
+
+		if worker.WorkerType == systemradar.Name && worker.Radar != nil &&
+			(node.Resources == nil || worker.Radar.ScannedAt > node.Resources.ScannedAt) {
+			node.Resources = worker.Radar
+		}
+
+		node.Components = append(node.Components, worker)
+	}
+
+	result := make([]data.ClusterNode, 0, len(nodes))
+	for _, node := range nodes {
+		sort.Slice(node.Components, func(i, j int) bool {
+			if node.Components[i].WorkerType != node.Components[j].WorkerType {
+				return node.Components[i].WorkerType < node.Components[j].WorkerType
+			}
+			return node.Components[i].WorkerId < node.Components[j].WorkerId
+		})
+		sort.Strings(node.CurrentTasks)
+		node.Throughput.TasksPerHour = float64(node.Throughput.FinishedTasks) / ci.throughputWindow.Hours()
+		result = append(result, *node)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Node < result[j].Node
+	})
+
+	return result, nil
+}
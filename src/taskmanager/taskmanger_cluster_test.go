@@ -0,0 +1,82 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+)
+
+type fakeClusterDatabase struct {
+	database.Interface
+	workers []data.ClusterWorker
+}
+
+func (f *fakeClusterDatabase) ClusterWorkers(queueName string, throughputWindow time.Duration, radarScanLimit int64) ([]data.ClusterWorker, error) {
+	return f.workers, nil
+}
+
+func TestClusterInventory_Nodes(t *testing.T) {
+	now := time.Now().UnixMilli()
+	radar := &data.Radar{
+		SchemaVersion: data.RadarSchemaVersion,
+		SystemRadarId: "radar-a",
+		ScannedAt:     now - 500,
+		Cpu:           data.RadarCpu{Cores: 2, Percent: []float64{10, 30}},
+		Memory:        data.RadarMemory{Used: 2000, Total: 8000},
+		Disks:         []data.RadarDisk{{Path: "/", Used: 40, Total: 100}},
+	}
+
+	inventory := &clusterInventory{
+		database: database.New(&fakeClusterDatabase{workers: []data.ClusterWorker{
+			{WorkerId: "transcoder-a", WorkerType: "VideoTranscoding", Node: "node-a", LastSeen: now, CurrentTasks: []string{"task-1"}, FinishedTasks: 6},
+			{WorkerId: "radar-a", WorkerType: "SystemRadar", Node: "node-a", LastSeen: now - 1000, Radar: radar},
+			{WorkerId: "transcoder-b", WorkerType: "VideoTranscoding", Node: "node-b", LastSeen: now - time.Hour.Milliseconds(), FinishedTasks: 1},
+			{WorkerId: "legacy", WorkerType: "CallbackManager", LastSeen: now},
+		}}),
+		aliveAfter:       3 * time.Minute,
+		throughputWindow: 30 * time.Minute,
+	}
+
+	nodes, err := inventory.nodes()
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 3)
+
+	assert.Equal(t, "legacy", nodes[0].Node)
+	assert.True(t, nodes[0].Alive)
+
+	nodeA := nodes[1]
+	assert.Equal(t, "node-a", nodeA.Node)
+	assert.True(t, nodeA.Alive)
+	assert.Equal(t, now, nodeA.LastSeen)
+	assert.Equal(t, []string{"task-1"}, nodeA.CurrentTasks)
+	assert.Equal(t, data.NodeThroughput{FinishedTasks: 6, WindowSeconds: 1800, TasksPerHour: 12}, nodeA.Throughput)
+	assert.Equal(t, radar, nodeA.Resources)
+	assert.Equal(t, "SystemRadar", nodeA.Components[0].WorkerType)
+	assert.Equal(t, "VideoTranscoding", nodeA.Components[1].WorkerType)
+
+	nodeB := nodes[2]
+	assert.False(t, nodeB.Alive)
+	assert.False(t, nodeB.Components[0].Alive)
+	assert.Nil(t, nodeB.Resources)
+	assert.Equal(t, []string{}, nodeB.CurrentTasks)
+}
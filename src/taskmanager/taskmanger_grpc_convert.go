@@ -0,0 +1,223 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/src/taskmanager/rasborapb"
+)
+
+// taskFromProto convert protobuf task to data.Task.
+func taskFromProto(pb *rasborapb.Task) data.Task {
+	var task data.Task
+
+	task.ID = pb.GetTaskId()
+	task.Label = pb.GetTaskLabel()
+	if pb != nil && pb.TaskPriority != nil {
+		priority := pb.GetTaskPriority()
+		task.Priority = &priority
+	}
+	task.RunAt = pb.GetRunAt()
+	task.DelaySeconds = pb.GetDelaySeconds()
+	task.RequiredCapabilities = pb.GetRequiredCapabilities()
+	task.Callback.URL = pb.GetCallback().GetCallbackUrl()
+	if pb.GetCallback().GetCallbackData() != nil {
+		task.Callback.Data = pb.GetCallback().GetCallbackData().AsInterface()
+	}
+
+	input := pb.GetVideoTranscoder().GetInput()
+	task.VideoTranscoder.InputVideo.FileSystem = data.FileSystemType(input.GetInputFileSystem())
+	task.VideoTranscoder.InputVideo.FileName = input.GetInputFileName()
+	task.VideoTranscoder.InputVideo.FilePath = input.GetInputFilePath()
+
+	output := pb.GetVideoTranscoder().GetOutput()
+	task.VideoTranscoder.Output.Handler = output.GetHandler()
+	task.VideoTranscoder.Output.Container = output.GetContainer()
+	if output.GetArgs() != nil {
+		task.VideoTranscoder.Output.Args = []map[string]interface{}{}
+		for _, arg := range output.GetArgs() {
+			task.VideoTranscoder.Output.Args = append(task.VideoTranscoder.Output.Args, arg.AsMap())
+		}
+	}
+
+	return task
+}
+
+// taskToProto convert data.Task to protobuf task.
+func taskToProto(task data.Task) (*rasborapb.Task, error) {
+	callbackData, err := structpb.NewValue(task.Callback.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []*structpb.Struct
+	for _, arg := range task.VideoTranscoder.Output.Args {
+		argAsStruct, err := structpb.NewStruct(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, argAsStruct)
+	}
+
+	return &rasborapb.Task{
+		TaskId:               task.ID,
+		TaskLabel:            task.Label,
+		TaskPriority:         task.Priority,
+		RunAt:                task.RunAt,
+		DelaySeconds:         task.DelaySeconds,
+		RequiredCapabilities: task.RequiredCapabilities,
+		Callback: &rasborapb.TaskCallback{
+			CallbackUrl:  task.Callback.URL,
+			CallbackData: callbackData,
+		},
+		VideoTranscoder: &rasborapb.VideoTranscoder{
+			Input: &rasborapb.InputVideo{
+				InputFileSystem: string(task.VideoTranscoder.InputVideo.FileSystem),
+				InputFileName:   task.VideoTranscoder.InputVideo.FileName,
+				InputFilePath:   task.VideoTranscoder.InputVideo.FilePath,
+			},
+			Output: &rasborapb.OutputVideo{
+				Handler:   task.VideoTranscoder.Output.Handler,
+				Container: task.VideoTranscoder.Output.Container,
+				Args:      args,
+			},
+		},
+		CreatedAt:  task.CreatedAt,
+		StartedAt:  task.StartedAt,
+		FinishedAt: task.FinishedAt,
+		FailedAt:   task.FailedAt,
+	}, nil
+}
+
+// callbackToProto convert data.Callback to protobuf callback.
+func callbackToProto(callback data.Callback) (*rasborapb.Callback, error) {
+	callbackData, err := structpb.NewValue(callback.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var priority float64
+	if callback.Priority != nil {
+		priority = *callback.Priority
+	}
+
+	var taskId string
+	if callback.TaskId != nil {
+		taskId = fmt.Sprint(callback.TaskId)
+	}
+
+	processingLogFile, err := fileToProto(callback.ProcessingLogFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var videoOutputFiles []*rasborapb.File
+	for _, file := range callback.VideoOutputFiles {
+		videoOutputFile, err := fileToProto(file)
+		if err != nil {
+			return nil, err
+		}
+		videoOutputFiles = append(videoOutputFiles, videoOutputFile)
+	}
+
+	return &rasborapb.Callback{
+		TaskId:            taskId,
+		TaskLabel:         callback.Label,
+		Priority:          priority,
+		Data:              callbackData,
+		Error:             callback.Error,
+		ErrorCode:         callback.ErrorCode.String(),
+		Retryable:         callback.Retryable,
+		Message:           callback.Message,
+		Url:               callback.URL,
+		VideoOutputFiles:  videoOutputFiles,
+		ProcessingLogFile: processingLogFile,
+		TaskTimeline: &rasborapb.TaskTimeline{
+			Add:      callback.TaskTimeline.Add,
+			Started:  callback.TaskTimeline.Started,
+			Failed:   callback.TaskTimeline.Failed,
+			Finished: callback.TaskTimeline.Finished,
+		},
+	}, nil
+}
+
+// fileToProto convert data.File to protobuf file.
+func fileToProto(file data.File) (*rasborapb.File, error) {
+	fileMeta, err := structpb.NewValue(file.FileMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rasborapb.File{
+		FileName: file.FileName,
+		FilePath: file.FilePath,
+		FileMeta: fileMeta,
+	}, nil
+}
+
+// detailsToProto convert queue details of task to protobuf task details.
+func detailsToProto(details data.QueueableDetails) (*rasborapb.TaskDetails, error) {
+	var task data.Task
+	if err := decodePayload(details.Item.Payload, &task); err != nil {
+		return nil, err
+	}
+
+	taskAsProto, err := taskToProto(task)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rasborapb.TaskDetails{
+		Task:              taskAsProto,
+		Status:            details.Status,
+		Worker:            details.Worker,
+		Log:               details.Log,
+		EffectivePriority: details.EffectivePriority,
+		ErrorCode:         details.ErrorCode.String(),
+		Retryable:         details.Retryable,
+	}, nil
+}
+
+// decodePayload decode queue item payload into given struct.
+func decodePayload(payload interface{}, v interface{}) error {
+	payloadAsJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(payloadAsJsonBytes, v)
+}
+
+// progressEventToProto convert processing event to protobuf progress event.
+func progressEventToProto(taskId string, status string, event data.ProcessingEvent) *rasborapb.ProgressEvent {
+	values := make(map[string]string, len(event.Values))
+	for key, value := range event.Values {
+		values[key] = fmt.Sprint(value)
+	}
+
+	return &rasborapb.ProgressEvent{
+		TaskId:  taskId,
+		EventId: event.ID,
+		Status:  status,
+		Values:  values,
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/data"
+)
+
+func TestTaskProtoRoundTrip(t *testing.T) {
+	priority := 2.0
+
+	var task data.Task
+	task.ID = "task-1"
+	task.Label = "label"
+	task.Priority = &priority
+	task.Callback.URL = "http://localhost/callback"
+	task.Callback.Data = map[string]interface{}{"user": "1"}
+	task.VideoTranscoder.InputVideo.FileSystem = data.ObjectFileSystemType
+	task.VideoTranscoder.InputVideo.FileName = "input.mp4"
+	task.VideoTranscoder.InputVideo.FilePath = "/videos"
+	task.VideoTranscoder.Output.Handler = "default"
+	task.VideoTranscoder.Output.Container = "mp4"
+	task.VideoTranscoder.Output.Args = []map[string]interface{}{{"resolution": "720p", "bitrate": 1200.0}}
+	task.CreatedAt = 1700000000000
+
+	pb, err := taskToProto(task)
+	assert.NoError(t, err)
+	assert.Equal(t, priority, pb.GetTaskPriority())
+
+	back := taskFromProto(pb)
+	back.CreatedAt = pb.GetCreatedAt()
+	assert.Equal(t, task, back)
+}
+
+func TestTaskFromProto_MissingPriority(t *testing.T) {
+	task := taskFromProto(nil)
+
+	assert.Nil(t, task.Priority)
+	assert.Error(t, _taskValidator.Struct(task))
+}
+
+func TestDetailsToProto(t *testing.T) {
+	payload := map[string]interface{}{
+		"task_id":       "task-1",
+		"task_label":    "label",
+		"task_priority": 1.0,
+		"callback":      map[string]interface{}{"callback_url": "http://localhost", "callback_data": nil},
+	}
+
+	details, err := detailsToProto(data.QueueableDetails{
+		Item:   data.Queueable{ID: "task-1", Priority: 1, Payload: payload},
+		Status: "working",
+		Worker: "worker-1",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "task-1", details.GetTask().GetTaskId())
+	assert.Equal(t, "working", details.GetStatus())
+	assert.Equal(t, "worker-1", details.GetWorker())
+}
+
+func TestProgressEventToProto(t *testing.T) {
+	event := progressEventToProto("task-1", "working", data.ProcessingEvent{
+		ID:     "1-0",
+		Values: map[string]interface{}{"progress": "42"},
+	})
+
+	assert.Equal(t, "1-0", event.GetEventId())
+	assert.Equal(t, "42", event.GetValues()["progress"])
+}
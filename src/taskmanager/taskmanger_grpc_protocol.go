@@ -0,0 +1,555 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/filesystem"
+	"openseawave.com/rasbora/internal/logger"
+	"openseawave.com/rasbora/src/safeguard"
+	"openseawave.com/rasbora/src/taskmanager/rasborapb"
+)
+
+// GrpcTaskManager hold an instance
+type GrpcTaskManager struct {
+	rasborapb.UnimplementedTaskManagerServer
+	Config                *config.Config
+	Logger                *logger.Logger
+	Database              *database.Database
+	FileSystem            *filesystem.FileSystem
+	_videoTranscoderQueue string
+	_callbackManagerQueue string
+	_taskManagerWorkerID  string
+	_watchInterval        time.Duration
+	_auth                 *authenticator
+	_tasks                *taskCreator
+	_safeGuard            *safeguard.SafeGuard
+	server                *grpc.Server
+}
+
+// StartTaskManager start grpc server to listen for task operations.
+func (gtm *GrpcTaskManager) StartTaskManager(ctx context.Context) {
+
+	// get video transcoder queue name
+	gtm._videoTranscoderQueue = gtm.Config.GetString("Components.VideoTranscoding.Queue")
+
+	// get callback manager queue name
+	gtm._callbackManagerQueue = gtm.Config.GetString("Components.CallbackManager.Queue")
+
+	// get task manager worker id
+	gtm._taskManagerWorkerID = gtm.Config.GetString("Components.TaskManagement.UniqueID")
+
+	// get how long watch task waits for new processing events before checking task status
+	gtm._watchInterval = time.Duration(gtm.Config.GetInt("Components.TaskManagement.Protocols.Grpc.WatchInterval")) * time.Second
+	if gtm._watchInterval <= 0 {
+		gtm._watchInterval = 5 * time.Second
+	}
+
+	// prepare api key authentication
+	auth, err := newAuthenticator(gtm.Config, gtm.Database)
+	if err != nil {
+		gtm.Logger.Error(
+			"grpc_task_manager",
+			fmt.Sprintf("cannot prepare api key authentication: %v", err.Error()),
+			map[string]interface{}{
+				"task_manager_worker_id": gtm._taskManagerWorkerID,
+				"protocol":               "grpc",
+			},
+		)
+		os.Exit(1)
+	}
+	gtm._auth = auth
+
+	// prepare rate limits and request size limits
+	gtm._safeGuard = safeguard.NewSafeGuard(gtm.Config, gtm.Logger, gtm.Database)
+
+	// prepare task creator
+	gtm._tasks = newTaskCreator(gtm.Config, gtm.Database, gtm._safeGuard.MaxArgsCount())
+
+	// start moving scheduled tasks to waiting queue when they are due
+	go newTaskScheduler(gtm.Config, gtm.Logger, gtm.Database).start(ctx)
+
+	// get listen addr
+	listenAddress := gtm.Config.GetString("Components.TaskManagement.Protocols.Grpc.ListenAddress")
+
+	gtm.Logger.Debug(
+		"grpc_task_manager",
+		"starting",
+		map[string]interface{}{
+			"task_manager_worker_id": gtm._taskManagerWorkerID,
+			"protocol":               "grpc",
+			"address":                listenAddress,
+		},
+	)
+
+	options := []grpc.ServerOption{
+		grpc.UnaryInterceptor(gtm._unaryAuthInterceptor),
+		grpc.StreamInterceptor(gtm._streamAuthInterceptor),
+	}
+	if maxBodySize := gtm._safeGuard.MaxBodySize(); maxBodySize > 0 {
+		options = append(options, grpc.MaxRecvMsgSize(maxBodySize))
+	}
+	if gtm.Config.GetBool("Components.TaskManagement.Protocols.Grpc.TLS.Enabled") {
+		tlsCredentials, err := gtm._loadTLSCredentials()
+		if err != nil {
+			gtm.Logger.Error(
+				"grpc_task_manager.Run",
+				fmt.Sprintf("error loading tls credentials: %v", err.Error()),
+				map[string]interface{}{
+					"task_manager_worker_id": gtm._taskManagerWorkerID,
+					"protocol":               "grpc",
+				},
+			)
+			os.Exit(1)
+		}
+		options = append(options, grpc.Creds(tlsCredentials))
+	}
+
+	gtm.server = grpc.NewServer(options...)
+	rasborapb.RegisterTaskManagerServer(gtm.server, gtm)
+
+	if gtm.Config.GetBool("Components.TaskManagement.Protocols.Grpc.Reflection") {
+		reflection.Register(gtm.server)
+	}
+
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		gtm.Logger.Error(
+			"grpc_task_manager.Run",
+			err.Error(),
+			map[string]interface{}{
+				"task_manager_worker_id": gtm._taskManagerWorkerID,
+				"protocol":               "grpc",
+				"address":                listenAddress,
+			},
+		)
+		os.Exit(1)
+	}
+
+	go func() {
+		<-ctx.Done()
+		gtm.server.GracefulStop()
+	}()
+
+	if err := gtm.server.Serve(listener); err != nil {
+		gtm.Logger.Error(
+			"grpc_task_manager.Run",
+			err.Error(),
+			map[string]interface{}{
+				"task_manager_worker_id": gtm._taskManagerWorkerID,
+				"protocol":               "grpc",
+				"address":                listenAddress,
+			},
+		)
+		os.Exit(1)
+	}
+}
+
+// CreateTask create new task for video transcoding.
+func (gtm *GrpcTaskManager) CreateTask(ctx context.Context, request *rasborapb.CreateTaskRequest) (*rasborapb.CreateTaskResponse, error) {
+	task := taskFromProto(request.GetTask())
+
+	key, _ := ctx.Value(apiKeyContextKey{}).(data.ApiKey)
+
+	var idempotencyKey string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("idempotency-key")) > 0 {
+		idempotencyKey = md.Get("idempotency-key")[0]
+	}
+
+	if _, err := gtm._tasks.create(ctx, key, &task, idempotencyKey); err != nil {
+		if isValidationError(err) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		if errors.Is(err, ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+
+		if errors.Is(err, ErrConflict) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+
+		if errors.Is(err, ErrIdempotencyKeyInvalid) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		if errors.Is(err, ErrTooManyArgs) {
+			gtm._safeGuard.ReportAbuse(safeguard.ReasonTooManyArgs, map[string]interface{}{
+				"ip":         _peerIp(ctx),
+				"key_id":     key.ID,
+				"args_count": len(task.VideoTranscoder.Output.Args),
+			})
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		if errors.Is(err, ErrQuotaExceeded) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+
+		if errors.Is(err, ErrInputNotFound) || errors.Is(err, ErrInputUnreadable) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+
+		gtm._logError("grpc_task_manager.create_task", "error when saving task in database", task.ID, err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	gtm.Logger.Success(
+		"grpc_task_manager.create_task",
+		"task created without any problems",
+		map[string]interface{}{
+			"task_manager_worker_id": gtm._taskManagerWorkerID,
+			"task_id":                task.ID,
+		},
+	)
+
+	return &rasborapb.CreateTaskResponse{TaskId: task.ID}, nil
+}
+
+// GetTask get task with its current status and its callback when task is done.
+func (gtm *GrpcTaskManager) GetTask(ctx context.Context, request *rasborapb.GetTaskRequest) (*rasborapb.TaskDetails, error) {
+	details, err := gtm._tenantDetails(ctx, request.GetTaskId())
+	if err != nil {
+		return nil, gtm._statusError("grpc_task_manager.get_task", request.GetTaskId(), err)
+	}
+
+	taskDetails, err := detailsToProto(details)
+	if err != nil {
+		return nil, gtm._statusError("grpc_task_manager.get_task", request.GetTaskId(), err)
+	}
+
+	callbackDetails, err := gtm.Database.Details(gtm._callbackManagerQueue, request.GetTaskId())
+	if errors.Is(err, database.ErrNotFound) {
+		return taskDetails, nil
+	}
+	if err != nil {
+		return nil, gtm._statusError("grpc_task_manager.get_task", request.GetTaskId(), err)
+	}
+
+	var callback data.Callback
+	if err := decodePayload(callbackDetails.Item.Payload, &callback); err != nil {
+		return nil, gtm._statusError("grpc_task_manager.get_task", request.GetTaskId(), err)
+	}
+
+	if taskDetails.Callback, err = callbackToProto(callback); err != nil {
+		return nil, gtm._statusError("grpc_task_manager.get_task", request.GetTaskId(), err)
+	}
+
+	return taskDetails, nil
+}
+
+// ListTasks get page of tasks filtered by status.
+func (gtm *GrpcTaskManager) ListTasks(ctx context.Context, request *rasborapb.ListTasksRequest) (*rasborapb.ListTasksResponse, error) {
+	var cursor uint64
+	if request.GetPageToken() != "" {
+		var err error
+		if cursor, err = strconv.ParseUint(request.GetPageToken(), 10, 64); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "page token is not valid")
+		}
+	}
+
+	pageSize := request.GetPageSize()
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+
+	// keys of tenant only list tasks of their tenant.
+	key, _ := ctx.Value(apiKeyContextKey{}).(data.ApiKey)
+
+	items, nextCursor, err := gtm.Database.List(gtm._videoTranscoderQueue, request.GetStatus(), key.Tenant, cursor, pageSize)
+	if err != nil {
+		return nil, gtm._statusError("grpc_task_manager.list_tasks", "", err)
+	}
+
+	response := &rasborapb.ListTasksResponse{}
+	for _, item := range items {
+		taskDetails, err := detailsToProto(item)
+		if err != nil {
+			return nil, gtm._statusError("grpc_task_manager.list_tasks", item.Item.ID, err)
+		}
+		response.Tasks = append(response.Tasks, taskDetails)
+	}
+
+	if nextCursor != 0 {
+		response.NextPageToken = strconv.FormatUint(nextCursor, 10)
+	}
+
+	return response, nil
+}
+
+// CancelTask cancel task still waiting in queue.
+func (gtm *GrpcTaskManager) CancelTask(ctx context.Context, request *rasborapb.CancelTaskRequest) (*rasborapb.CancelTaskResponse, error) {
+	if _, err := gtm._tenantDetails(ctx, request.GetTaskId()); err != nil {
+		return nil, gtm._statusError("grpc_task_manager.cancel_task", request.GetTaskId(), err)
+	}
+
+	if err := gtm.Database.Cancel(gtm._videoTranscoderQueue, request.GetTaskId()); err != nil {
+		return nil, gtm._statusError("grpc_task_manager.cancel_task", request.GetTaskId(), err)
+	}
+
+	gtm.Logger.Success(
+		"grpc_task_manager.cancel_task",
+		"task cancelled without any problems",
+		map[string]interface{}{
+			"task_manager_worker_id": gtm._taskManagerWorkerID,
+			"task_id":                request.GetTaskId(),
+		},
+	)
+
+	return &rasborapb.CancelTaskResponse{TaskId: request.GetTaskId(), Status: "cancelled"}, nil
+}
+
+// WatchTask stream processing events until task is finished, failed or cancelled.
+func (gtm *GrpcTaskManager) WatchTask(request *rasborapb.WatchTaskRequest, stream rasborapb.TaskManager_WatchTaskServer) error {
+	taskId := request.GetTaskId()
+	lastEventId := request.GetLastEventId()
+
+	for {
+		details, err := gtm._tenantDetails(stream.Context(), taskId)
+		if err != nil {
+			return gtm._statusError("grpc_task_manager.watch_task", taskId, err)
+		}
+
+		events, err := gtm.Database.ReadProcessing(gtm._videoTranscoderQueue, taskId, lastEventId, gtm._watchInterval)
+		if err != nil {
+			return gtm._statusError("grpc_task_manager.watch_task", taskId, err)
+		}
+
+		for _, event := range events {
+			if err := stream.Send(progressEventToProto(taskId, details.Status, event)); err != nil {
+				return err
+			}
+			lastEventId = event.ID
+		}
+
+		// stop only after all events sent before task was done are delivered.
+		if len(events) == 0 && _isTaskDone(details.Status) {
+			return stream.Send(&rasborapb.ProgressEvent{TaskId: taskId, EventId: lastEventId, Status: details.Status})
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		default:
+		}
+	}
+}
+
+// apiKeyContextKey used to save authenticated api key inside request context.
+type apiKeyContextKey struct{}
+
+// _methodScopes scope required by each grpc method.
+var _methodScopes = map[string]data.ApiKeyScope{
+	rasborapb.TaskManager_CreateTask_FullMethodName: data.ApiKeyScopeCreate,
+	rasborapb.TaskManager_GetTask_FullMethodName:    data.ApiKeyScopeRead,
+	rasborapb.TaskManager_ListTasks_FullMethodName:  data.ApiKeyScopeRead,
+	rasborapb.TaskManager_WatchTask_FullMethodName:  data.ApiKeyScopeRead,
+	rasborapb.TaskManager_CancelTask_FullMethodName: data.ApiKeyScopeCancel,
+}
+
+// _unaryAuthInterceptor authenticate unary calls.
+func (gtm *GrpcTaskManager) _unaryAuthInterceptor(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := gtm._authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+// _streamAuthInterceptor authenticate stream calls.
+func (gtm *GrpcTaskManager) _streamAuthInterceptor(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := gtm._authorize(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(server, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream server stream with context holding authenticated api key.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context return context holding authenticated api key.
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// _authorize read token from "x-api-key" or "authorization" metadata and check it is granted method scope.
+func (gtm *GrpcTaskManager) _authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	scope, ok := _methodScopes[fullMethod]
+	if !ok {
+		// methods outside task manager service such as reflection require admin scope.
+		scope = data.ApiKeyScopeAdmin
+	}
+
+	ip := _peerIp(ctx)
+	if allowed, retryAfter := gtm._safeGuard.AllowIp(ip); !allowed {
+		gtm._safeGuard.ReportAbuse(safeguard.ReasonIpRateLimited, map[string]interface{}{
+			"ip":     ip,
+			"method": fullMethod,
+		})
+		return ctx, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %v", retryAfter)
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-api-key"); len(values) > 0 {
+			token = values[0]
+		} else if values := md.Get("authorization"); len(values) > 0 {
+			token = strings.TrimPrefix(values[0], "Bearer ")
+		}
+	}
+
+	key, err := gtm._auth.authorize(token, scope)
+	if errors.Is(err, ErrUnauthenticated) {
+		gtm._safeGuard.ReportAbuse(safeguard.ReasonUnauthenticated, map[string]interface{}{
+			"ip":     ip,
+			"method": fullMethod,
+		})
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if errors.Is(err, ErrPermissionDenied) {
+		return ctx, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return ctx, gtm._statusError("grpc_task_manager.authorize", "", err)
+	}
+
+	// every request share anonymous key when auth is disabled, they are limited only by their ip.
+	if gtm._auth.enabled {
+		if allowed, retryAfter := gtm._safeGuard.AllowApiKey(key.ID); !allowed {
+			gtm._safeGuard.ReportAbuse(safeguard.ReasonApiKeyRateLimited, map[string]interface{}{
+				"ip":     ip,
+				"key_id": key.ID,
+				"method": fullMethod,
+			})
+			return ctx, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %v", retryAfter)
+		}
+	}
+
+	return context.WithValue(ctx, apiKeyContextKey{}, key), nil
+}
+
+// _peerIp return ip address of grpc client.
+func _peerIp(ctx context.Context) string {
+	client, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(client.Addr.String())
+	if err != nil {
+		return client.Addr.String()
+	}
+
+	return host
+}
+
+// _tenantDetails get task details, tasks of other tenants are reported as not found.
+func (gtm *GrpcTaskManager) _tenantDetails(ctx context.Context, taskId string) (data.QueueableDetails, error) {
+	key, _ := ctx.Value(apiKeyContextKey{}).(data.ApiKey)
+	return tenantTaskDetails(gtm.Database, gtm._videoTranscoderQueue, key, taskId)
+}
+
+// _isTaskDone check if task will not receive any new processing events.
+func _isTaskDone(status string) bool {
+	return status == "finished" || status == "failed" || status == "cancelled"
+}
+
+// _statusError log error and convert it to grpc status error.
+func (gtm *GrpcTaskManager) _statusError(scope string, taskId string, err error) error {
+	if errors.Is(err, database.ErrNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	if errors.Is(err, database.ErrNotCancellable) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	gtm._logError(scope, "error when reading task from database", taskId, err)
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+// _logError log error with task manager details.
+func (gtm *GrpcTaskManager) _logError(scope string, message string, taskId string, err error) {
+	gtm.Logger.Error(
+		scope,
+		fmt.Sprintf("%v: %v", message, err.Error()),
+		map[string]interface{}{
+			"task_manager_worker_id": gtm._taskManagerWorkerID,
+			"protocol":               "grpc",
+			"task_id":                taskId,
+		},
+	)
+}
+
+// _loadTLSCredentials load server certificate and optional client ca for mutual tls.
+func (gtm *GrpcTaskManager) _loadTLSCredentials() (credentials.TransportCredentials, error) {
+	certificate, err := tls.LoadX509KeyPair(
+		gtm.Config.GetString("Components.TaskManagement.Protocols.Grpc.TLS.CertFile"),
+		gtm.Config.GetString("Components.TaskManagement.Protocols.Grpc.TLS.KeyFile"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile := gtm.Config.GetString("Components.TaskManagement.Protocols.Grpc.TLS.ClientCAFile"); clientCAFile != "" {
+		clientCA, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(clientCA) {
+			return nil, errors.New("client ca file does not contain any valid certificate")
+		}
+
+		tlsConfig.ClientCAs = certPool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
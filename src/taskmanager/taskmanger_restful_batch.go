@@ -0,0 +1,121 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+)
+
+// CreateTaskBatch godoc
+// @Summary Create batch of tasks.
+// @Description Create many tasks in single request, every task is validated alone and result returned per task.
+// @Tags tasks
+// @Param batch body data.TaskBatch true "Batch data"
+// @Accept  application/json
+// @Produce  application/json
+// @Success 200 {object} data.Response
+// @Failure 400 {object} data.Response
+// @Failure 401 {object} data.Response
+// @Failure 403 {object} data.Response
+// @Failure 413 {object} data.Response
+// @Failure 429 {object} data.Response
+// @Failure 500 {object} data.Response
+// @Security ApiKeyAuth
+// @Router /tasks/batch [post]
+func (rtm *RestfulTaskManager) _endpointCreateTaskBatch(c *fiber.Ctx) error {
+	var batch data.TaskBatch
+
+	if err := c.BodyParser(&batch); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	results, err := rtm._tasks.createBatch(c.UserContext(), rtm._apiKey(c), &batch)
+	if errors.Is(err, ErrBatchEmpty) || errors.Is(err, ErrBatchTooLarge) || errors.Is(err, ErrBatchIdInvalid) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		rtm.Logger.Error(
+			"restful_task_manager.create_task_batch",
+			"error when saving batch in database",
+			map[string]interface{}{
+				"task_manager_worker_id": rtm._taskManagerWorkerID,
+				"batch_id":               batch.ID,
+			},
+		)
+		return err
+	}
+
+	var created int
+	for _, result := range results {
+		if result.Error == "" {
+			created++
+		}
+	}
+
+	rtm.Logger.Success(
+		"restful_task_manager.create_task_batch",
+		"batch processed without any problems",
+		map[string]interface{}{
+			"task_manager_worker_id": rtm._taskManagerWorkerID,
+			"batch_id":               batch.ID,
+			"tasks_count":            len(results),
+			"created_count":          created,
+		},
+	)
+
+	return c.JSON(data.Response{Error: false, Message: "batch processed without any problems",
+		Payload: struct {
+			BatchId string                 `json:"batch_id,omitempty"`
+			Created int                    `json:"created"`
+			Results []data.TaskBatchResult `json:"results"`
+		}{
+			BatchId: batch.ID,
+			Created: created,
+			Results: results,
+		},
+	})
+}
+
+// GetBatchProgress godoc
+// @Summary Get batch progress.
+// @Description Count tasks of batch in each status with percentage of done tasks.
+// @Tags tasks
+// @Param id path string true "Batch id"
+// @Produce  application/json
+// @Success 200 {object} data.Response{payload=data.BatchProgress}
+// @Failure 401 {object} data.Response
+// @Failure 403 {object} data.Response
+// @Failure 404 {object} data.Response
+// @Failure 500 {object} data.Response
+// @Security ApiKeyAuth
+// @Router /batches/{id} [get]
+func (rtm *RestfulTaskManager) _endpointGetBatchProgress(c *fiber.Ctx) error {
+	progress, err := rtm._tasks.batchProgress(rtm._apiKey(c), c.Params("id"))
+	if errors.Is(err, database.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "batch not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(data.Response{Error: false, Message: "batch progress", Payload: progress})
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"openseawave.com/rasbora/internal/data"
+)
+
+// GetClusterNodes godoc
+// @Summary Get cluster nodes.
+// @Description List nodes sending heartbeat with their components, last seen time, liveness, latest cpu, memory and disk usage, current tasks and throughput.
+// @Tags cluster
+// @Produce  application/json
+// @Success 200 {object} data.Response{payload=[]data.ClusterNode}
+// @Failure 401 {object} data.Response
+// @Failure 403 {object} data.Response
+// @Failure 500 {object} data.Response
+// @Security ApiKeyAuth
+// @Router /cluster/nodes [get]
+func (rtm *RestfulTaskManager) _endpointGetClusterNodes(c *fiber.Ctx) error {
+	nodes, err := rtm._cluster.nodes()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(data.Response{Error: false, Message: "cluster nodes", Payload: nodes})
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+)
+
+// CreateApiKey godoc
+// @Summary Create new api key.
+// @Description Create new api key, the returned api_key is shown only once. Key created by key of tenant belongs to same tenant.
+// @Tags keys
+// @Param key body data.ApiKey true "Key data"
+// @Accept  application/json
+// @Produce  application/json
+// @Success 200 {object} data.Response
+// @Failure 400 {object} data.Response
+// @Failure 401 {object} data.Response
+// @Failure 403 {object} data.Response
+// @Failure 500 {object} data.Response
+// @Security ApiKeyAuth
+// @Router /keys [post]
+func (rtm *RestfulTaskManager) _endpointCreateApiKey(c *fiber.Ctx) error {
+	var request data.ApiKey
+
+	if err := c.BodyParser(&request); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := _taskValidator.Struct(request); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	token, key, err := rtm._auth.newApiKey(request.Name, request.Tenant, request.Scopes, request.Handlers, rtm._apiKey(c))
+	if err != nil {
+		return err
+	}
+
+	if err := rtm.Database.SaveApiKey(key); err != nil {
+		rtm.Logger.Error(
+			"restful_task_manager.create_api_key",
+			"error when saving api key in database",
+			map[string]interface{}{
+				"task_manager_worker_id": rtm._taskManagerWorkerID,
+				"key_id":                 key.ID,
+			},
+		)
+		return err
+	}
+
+	rtm.Logger.Success(
+		"restful_task_manager.create_api_key",
+		"api key created without any problems",
+		map[string]interface{}{
+			"task_manager_worker_id": rtm._taskManagerWorkerID,
+			"key_id":                 key.ID,
+			"created_by":             key.CreatedBy,
+		},
+	)
+
+	key.SecretHash = ""
+
+	return c.JSON(data.Response{Error: false, Message: "api key created without any problems",
+		Payload: struct {
+			data.ApiKey
+			Token string `json:"api_key"`
+		}{
+			ApiKey: key,
+			Token:  token,
+		},
+	})
+}
+
+// RevokeApiKey godoc
+// @Summary Revoke api key.
+// @Description Revoke api key, requests using it are rejected immediately.
+// @Tags keys
+// @Param id path string true "Key id"
+// @Produce  application/json
+// @Success 200 {object} data.Response
+// @Failure 401 {object} data.Response
+// @Failure 403 {object} data.Response
+// @Failure 404 {object} data.Response
+// @Failure 500 {object} data.Response
+// @Security ApiKeyAuth
+// @Router /keys/{id} [delete]
+func (rtm *RestfulTaskManager) _endpointRevokeApiKey(c *fiber.Ctx) error {
+	key, err := rtm.Database.GetApiKey(c.Params("id"))
+	if errors.Is(err, database.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	if key.RevokedAt == 0 {
+		key.RevokedAt = time.Now().UnixMilli()
+		if err := rtm.Database.SaveApiKey(key); err != nil {
+			return err
+		}
+	}
+
+	rtm.Logger.Success(
+		"restful_task_manager.revoke_api_key",
+		"api key revoked without any problems",
+		map[string]interface{}{
+			"task_manager_worker_id": rtm._taskManagerWorkerID,
+			"key_id":                 key.ID,
+			"revoked_by":             rtm._apiKey(c).ID,
+		},
+	)
+
+	return c.JSON(data.Response{Error: false, Message: "api key revoked without any problems",
+		Payload: struct {
+			KeyId     string `json:"key_id"`
+			RevokedAt int64  `json:"revoked_at"`
+		}{
+			KeyId:     key.ID,
+			RevokedAt: key.RevokedAt,
+		},
+	})
+}
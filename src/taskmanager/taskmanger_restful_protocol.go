@@ -19,18 +19,26 @@ package taskmanager
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	swagger "github.com/arsmn/fiber-swagger/v2"
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	"github.com/google/uuid"
 	"openseawave.com/rasbora/internal/config"
 	"openseawave.com/rasbora/internal/data"
 	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/filesystem"
+	"openseawave.com/rasbora/internal/health"
 	"openseawave.com/rasbora/internal/logger"
+	"openseawave.com/rasbora/internal/metrics"
+	"openseawave.com/rasbora/src/safeguard"
 
 	// Auto-generated swagger documentation
 	_ "openseawave.com/rasbora/src/taskmanager/docs"
@@ -41,15 +49,20 @@ type RestfulTaskManager struct {
 	Config                *config.Config
 	Logger                *logger.Logger
 	Database              *database.Database
+	FileSystem            *filesystem.FileSystem
 	_videoTranscoderQueue string
 	_taskManagerWorkerID  string
+	_auth                 *authenticator
+	_tasks                *taskCreator
+	_cluster              *clusterInventory
+	_safeGuard            *safeguard.SafeGuard
 	app                   *fiber.App
 }
 
 // @title Rasbora Task Manager API
 // @version 1.0
 // @description Task Manager API for Rasbora Distributed Video Transcoding.
-// @contact.name Rasbora
+// @contact.name Rasbora Support
 // @contact.url	https://rasbora.openseawave.com
 // @contact.email rasbora.support@openseawave.com
 // @license.name GNU Affero General Public License
@@ -57,6 +70,9 @@ type RestfulTaskManager struct {
 // @host localhost:3701
 // @BasePath /v1.0
 // @schemes http
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-Api-Key
 func (rtm *RestfulTaskManager) StartTaskManager(ctx context.Context) {
 
 	// get video transcoder queue name
@@ -65,6 +81,47 @@ func (rtm *RestfulTaskManager) StartTaskManager(ctx context.Context) {
 	// get task manager worker id
 	rtm._taskManagerWorkerID = rtm.Config.GetString("Components.TaskManagement.UniqueID")
 
+	// prepare api key authentication
+	auth, err := newAuthenticator(rtm.Config, rtm.Database)
+	if err != nil {
+		rtm.Logger.Error(
+			"restful_task_manager",
+			fmt.Sprintf("cannot prepare api key authentication: %v", err.Error()),
+			map[string]interface{}{
+				"task_manager_worker_id": rtm._taskManagerWorkerID,
+				"protocol":               "restful",
+			},
+		)
+		os.Exit(1)
+	}
+	rtm._auth = auth
+
+	// prepare rate limits and request size limits
+	rtm._safeGuard = safeguard.NewSafeGuard(rtm.Config, rtm.Logger, rtm.Database)
+
+	// client ip is read from proxy header only on requests of trusted proxies
+	proxyHeader, trustedProxies, err := rtm._safeGuard.ProxyHeader()
+	if err != nil {
+		rtm.Logger.Error(
+			"restful_task_manager",
+			fmt.Sprintf("cannot prepare client ip detection: %v", err.Error()),
+			map[string]interface{}{
+				"task_manager_worker_id": rtm._taskManagerWorkerID,
+				"protocol":               "restful",
+			},
+		)
+		os.Exit(1)
+	}
+
+	// prepare task creator
+	rtm._tasks = newTaskCreator(rtm.Config, rtm.Database, rtm._safeGuard.MaxArgsCount())
+
+	// prepare cluster inventory
+	rtm._cluster = newClusterInventory(rtm.Config, rtm.Database)
+
+	// start moving scheduled tasks to waiting queue when they are due
+	go newTaskScheduler(rtm.Config, rtm.Logger, rtm.Database).start(ctx)
+
 	// get listen addr
 	listenAddress := rtm.Config.GetString("Components.TaskManagement.Protocols.Restful.ListenAddress")
 
@@ -79,9 +136,14 @@ func (rtm *RestfulTaskManager) StartTaskManager(ctx context.Context) {
 	)
 
 	rtm.app = fiber.New(fiber.Config{
-		AppName:               "Rasbora",
-		DisableStartupMessage: true,
-		ReduceMemoryUsage:     true,
+		AppName:                 "Rasbora",
+		DisableStartupMessage:   true,
+		ReduceMemoryUsage:       true,
+		BodyLimit:               rtm._safeGuard.MaxBatchBodySize(rtm._tasks.maxBatchTasks),
+		ProxyHeader:             proxyHeader,
+		EnableTrustedProxyCheck: proxyHeader != "",
+		TrustedProxies:          trustedProxies,
+		ErrorHandler:            rtm._errorHandler,
 	})
 
 	rtm._prepareHttpServer()
@@ -113,11 +175,41 @@ func (rtm *RestfulTaskManager) _prepareHttpServer() {
 	// Load json errors middleware.
 	rtm.app.Use(rtm._middlewareJsonErrors)
 
+	// Load client ip rate limit middleware.
+	rtm.app.Use(rtm._middlewareIpRateLimit)
+
+	// Load request body size limit middleware.
+	rtm.app.Use(rtm._middlewareBodyLimit)
+
 	// Add endpoint to server creating new tasks.
-	rtm.app.Post("/v1.0/tasks/create", rtm._endpointCreateNewTask)
+	rtm.app.Post("/v1.0/tasks/create", rtm._requireScope(data.ApiKeyScopeCreate), rtm._endpointCreateNewTask)
+
+	// Add endpoints to get task details and change priority of waiting task.
+	rtm.app.Get("/v1.0/tasks/:id", rtm._requireScope(data.ApiKeyScopeRead), rtm._endpointGetTask)
+	rtm.app.Patch("/v1.0/tasks/:id", rtm._requireScope(data.ApiKeyScopeAdmin), rtm._endpointChangeTaskPriority)
+
+	// Add endpoints to create batch of tasks and follow its progress.
+	rtm.app.Post("/v1.0/tasks/batch", rtm._requireScope(data.ApiKeyScopeCreate), rtm._endpointCreateTaskBatch)
+	rtm.app.Get("/v1.0/batches/:id", rtm._requireScope(data.ApiKeyScopeRead), rtm._endpointGetBatchProgress)
+
+	// Add endpoints to manage api keys.
+	rtm.app.Post("/v1.0/keys", rtm._requireScope(data.ApiKeyScopeAdmin), rtm._endpointCreateApiKey)
+	rtm.app.Delete("/v1.0/keys/:id", rtm._requireScope(data.ApiKeyScopeAdmin), rtm._endpointRevokeApiKey)
+
+	// Add endpoint to list cluster nodes.
+	rtm.app.Get("/v1.0/cluster/nodes", rtm._requireScope(data.ApiKeyScopeAdmin), rtm._endpointGetClusterNodes)
 
 	// Add endpoint to serve swagger documentation.
 	rtm.app.Get("/swagger/*", swagger.HandlerDefault)
+
+	// Add endpoint to serve prometheus metrics.
+	rtm.app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+
+	// Add endpoints to serve health, readiness and version of this node.
+	healthHandler := adaptor.HTTPHandler(health.Handler())
+	rtm.app.Get("/healthz", healthHandler)
+	rtm.app.Get("/readyz", healthHandler)
+	rtm.app.Get("/version", healthHandler)
 }
 
 // _middlewareJsonErrors return errors as json response.
@@ -133,7 +225,14 @@ func (rtm *RestfulTaskManager) _middlewareJsonErrors(c *fiber.Ctx) error {
 			},
 		)
 
-		return c.Status(fiber.StatusInternalServerError).JSON(data.Response{
+		code := fiber.StatusInternalServerError
+
+		var fiberError *fiber.Error
+		if errors.As(err, &fiberError) {
+			code = fiberError.Code
+		}
+
+		return c.Status(code).JSON(data.Response{
 			Error:   true,
 			Message: err.Error(),
 			Payload: nil,
@@ -144,17 +243,137 @@ func (rtm *RestfulTaskManager) _middlewareJsonErrors(c *fiber.Ctx) error {
 	return nil
 }
 
+// _errorHandler return errors raised outside handlers, such as too large body, as json response.
+func (rtm *RestfulTaskManager) _errorHandler(c *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+
+	var fiberError *fiber.Error
+	if errors.As(err, &fiberError) {
+		code = fiberError.Code
+	}
+
+	if code == fiber.StatusRequestEntityTooLarge {
+		rtm._safeGuard.ReportAbuse(safeguard.ReasonBodyTooLarge, map[string]interface{}{
+			"ip":     c.IP(),
+			"method": c.Method(),
+			"path":   c.Path(),
+		})
+	}
+
+	return c.Status(code).JSON(data.Response{
+		Error:   true,
+		Message: err.Error(),
+		Payload: nil,
+	})
+}
+
+// _middlewareIpRateLimit reject requests when client ip used all its rate limit tokens.
+func (rtm *RestfulTaskManager) _middlewareIpRateLimit(c *fiber.Ctx) error {
+	if allowed, retryAfter := rtm._safeGuard.AllowIp(c.IP()); !allowed {
+		rtm._safeGuard.ReportAbuse(safeguard.ReasonIpRateLimited, map[string]interface{}{
+			"ip":     c.IP(),
+			"method": c.Method(),
+			"path":   c.Path(),
+		})
+		return rtm._tooManyRequests(c, retryAfter)
+	}
+
+	return c.Next()
+}
+
+// _middlewareBodyLimit reject requests with body larger than max body size, batch endpoint accept body of all its tasks.
+func (rtm *RestfulTaskManager) _middlewareBodyLimit(c *fiber.Ctx) error {
+	maxBodySize := rtm._safeGuard.MaxBodySize()
+	if c.Path() == "/v1.0/tasks/batch" {
+		maxBodySize = rtm._safeGuard.MaxBatchBodySize(rtm._tasks.maxBatchTasks)
+	}
+
+	if maxBodySize > 0 && len(c.Body()) > maxBodySize {
+		rtm._safeGuard.ReportAbuse(safeguard.ReasonBodyTooLarge, map[string]interface{}{
+			"ip":     c.IP(),
+			"method": c.Method(),
+			"path":   c.Path(),
+		})
+		return fiber.ErrRequestEntityTooLarge
+	}
+
+	return c.Next()
+}
+
+// _tooManyRequests reject request with 429 and tell client when to retry.
+func (rtm *RestfulTaskManager) _tooManyRequests(c *fiber.Ctx, retryAfter time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return fiber.NewError(fiber.StatusTooManyRequests, "rate limit exceeded")
+}
+
+// _requireScope authenticate request and check api key is granted the scope.
+func (rtm *RestfulTaskManager) _requireScope(scope data.ApiKeyScope) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Get("X-Api-Key")
+		if token == "" {
+			token = strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		}
+
+		key, err := rtm._auth.authorize(token, scope)
+		if errors.Is(err, ErrUnauthenticated) {
+			rtm._safeGuard.ReportAbuse(safeguard.ReasonUnauthenticated, map[string]interface{}{
+				"ip":     c.IP(),
+				"method": c.Method(),
+				"path":   c.Path(),
+			})
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		}
+		if errors.Is(err, ErrPermissionDenied) {
+			return fiber.NewError(fiber.StatusForbidden, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+
+		// every request share anonymous key when auth is disabled, they are limited only by their ip.
+		if rtm._auth.enabled {
+			if allowed, retryAfter := rtm._safeGuard.AllowApiKey(key.ID); !allowed {
+				rtm._safeGuard.ReportAbuse(safeguard.ReasonApiKeyRateLimited, map[string]interface{}{
+					"ip":     c.IP(),
+					"key_id": key.ID,
+					"method": c.Method(),
+					"path":   c.Path(),
+				})
+				return rtm._tooManyRequests(c, retryAfter)
+			}
+		}
+
+		c.Locals("api_key", key)
+
+		return c.Next()
+	}
+}
+
+// _apiKey return api key authenticated for the request.
+func (rtm *RestfulTaskManager) _apiKey(c *fiber.Ctx) data.ApiKey {
+	key, _ := c.Locals("api_key").(data.ApiKey)
+	return key
+}
+
 // CreateTask godoc
 // @Summary Create new task for video transcoding.
 // @Description Create new task for video transcoding.
 // @Tags tasks
 // @Param task body data.Task true "Task data"
+// @Param Idempotency-Key header string false "Repeating request with same key returns the original task"
 // @Accept  application/json
 // @Produce  application/json
 // @Success 200 {object} data.Response
 // @Failure 400 {object} data.Response
 // @Failure 404 {object} data.Response
 // @Failure 500 {object} data.Response
+// @Failure 401 {object} data.Response
+// @Failure 403 {object} data.Response
+// @Failure 409 {object} data.Response
+// @Failure 413 {object} data.Response
+// @Failure 422 {object} data.Response
+// @Failure 429 {object} data.Response
+// @Security ApiKeyAuth
 // @Router /tasks/create [post]
 func (rtm *RestfulTaskManager) _endpointCreateNewTask(c *fiber.Ctx) error {
 
@@ -167,7 +386,6 @@ func (rtm *RestfulTaskManager) _endpointCreateNewTask(c *fiber.Ctx) error {
 	)
 
 	var task data.Task
-	var queueable data.Queueable
 
 	if err := c.BodyParser(&task); err != nil {
 		rtm.Logger.Error(
@@ -189,11 +407,77 @@ func (rtm *RestfulTaskManager) _endpointCreateNewTask(c *fiber.Ctx) error {
 		},
 	)
 
-	check := validator.New()
-	if err := check.Struct(task); err != nil {
+	replayed, err := rtm._tasks.create(c.UserContext(), rtm._apiKey(c), &task, c.Get("Idempotency-Key"))
+	if err != nil {
+		if isValidationError(err) {
+			rtm.Logger.Error(
+				"restful_task_manager.create_new_task",
+				"error json input is not correct",
+				map[string]interface{}{
+					"task_manager_worker_id": rtm._taskManagerWorkerID,
+					"task_id":                task.ID,
+				},
+			)
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		if errors.Is(err, ErrPermissionDenied) {
+			rtm.Logger.Warn(
+				"restful_task_manager.create_new_task",
+				"api key is not allowed to use handler",
+				map[string]interface{}{
+					"task_manager_worker_id": rtm._taskManagerWorkerID,
+					"key_id":                 rtm._apiKey(c).ID,
+					"ffmpeg_handler":         task.VideoTranscoder.Output.Handler,
+				},
+			)
+			return fiber.NewError(fiber.StatusForbidden, err.Error())
+		}
+
+		if errors.Is(err, ErrConflict) {
+			rtm.Logger.Warn(
+				"restful_task_manager.create_new_task",
+				err.Error(),
+				map[string]interface{}{
+					"task_manager_worker_id": rtm._taskManagerWorkerID,
+					"task_id":                task.ID,
+				},
+			)
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+
+		if errors.Is(err, ErrIdempotencyKeyInvalid) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		if errors.Is(err, ErrTooManyArgs) {
+			rtm._safeGuard.ReportAbuse(safeguard.ReasonTooManyArgs, map[string]interface{}{
+				"ip":         c.IP(),
+				"key_id":     rtm._apiKey(c).ID,
+				"args_count": len(task.VideoTranscoder.Output.Args),
+			})
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		if errors.Is(err, ErrQuotaExceeded) {
+			rtm.Logger.Warn(
+				"restful_task_manager.create_new_task",
+				err.Error(),
+				map[string]interface{}{
+					"task_manager_worker_id": rtm._taskManagerWorkerID,
+					"tenant":                 task.Tenant,
+				},
+			)
+			return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+		}
+
+		if errors.Is(err, ErrInputNotFound) || errors.Is(err, ErrInputUnreadable) {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		}
+
 		rtm.Logger.Error(
 			"restful_task_manager.create_new_task",
-			"error json input is not correct",
+			"error when saving task in database",
 			map[string]interface{}{
 				"task_manager_worker_id": rtm._taskManagerWorkerID,
 				"task_id":                task.ID,
@@ -202,26 +486,25 @@ func (rtm *RestfulTaskManager) _endpointCreateNewTask(c *fiber.Ctx) error {
 		return err
 	}
 
-	if len(task.ID) <= 0 {
-		task.ID = uuid.NewString()
-	}
-
-	task.CreatedAt = time.Now().UnixMilli()
-
-	queueable.ID = task.ID
-	queueable.Priority = *task.Priority
-	queueable.Payload = task
-
-	if err := rtm.Database.Enqueue(rtm._videoTranscoderQueue, queueable); err != nil {
-		rtm.Logger.Error(
+	if replayed {
+		rtm.Logger.Info(
 			"restful_task_manager.create_new_task",
-			"error when saving task in database",
+			"same task was created before, returning original task",
 			map[string]interface{}{
 				"task_manager_worker_id": rtm._taskManagerWorkerID,
 				"task_id":                task.ID,
 			},
 		)
-		return err
+
+		c.Set("Idempotent-Replayed", "true")
+
+		return c.JSON(data.Response{Error: false, Message: "task already created",
+			Payload: struct {
+				TaskId string `json:"task_id"`
+			}{
+				TaskId: task.ID,
+			},
+		})
 	}
 
 	rtm.Logger.Success(
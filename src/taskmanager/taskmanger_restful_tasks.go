@@ -0,0 +1,112 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+)
+
+// GetTask godoc
+// @Summary Get task.
+// @Description Get task with its current status, waiting tasks include their effective priority after aging.
+// @Tags tasks
+// @Param id path string true "Task id"
+// @Produce  application/json
+// @Success 200 {object} data.Response{payload=data.QueueableDetails}
+// @Failure 401 {object} data.Response
+// @Failure 403 {object} data.Response
+// @Failure 404 {object} data.Response
+// @Failure 500 {object} data.Response
+// @Security ApiKeyAuth
+// @Router /tasks/{id} [get]
+func (rtm *RestfulTaskManager) _endpointGetTask(c *fiber.Ctx) error {
+	details, err := tenantTaskDetails(rtm.Database, rtm._videoTranscoderQueue, rtm._apiKey(c), c.Params("id"))
+	if errors.Is(err, database.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "task not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(data.Response{Error: false, Message: "task details", Payload: details})
+}
+
+// ChangeTaskPriority godoc
+// @Summary Change task priority.
+// @Description Change priority of task still waiting, every change is recorded in task priority_changes.
+// @Tags tasks
+// @Param id path string true "Task id"
+// @Param priority body data.PriorityChangeRequest true "New priority"
+// @Accept  application/json
+// @Produce  application/json
+// @Success 200 {object} data.Response{payload=data.Task}
+// @Failure 400 {object} data.Response
+// @Failure 401 {object} data.Response
+// @Failure 403 {object} data.Response
+// @Failure 404 {object} data.Response
+// @Failure 409 {object} data.Response
+// @Failure 500 {object} data.Response
+// @Security ApiKeyAuth
+// @Router /tasks/{id} [patch]
+func (rtm *RestfulTaskManager) _endpointChangeTaskPriority(c *fiber.Ctx) error {
+	var request data.PriorityChangeRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := _taskValidator.Struct(request); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	task, err := rtm._tasks.changePriority(rtm._apiKey(c), c.Params("id"), *request.Priority)
+	if errors.Is(err, database.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "task not found")
+	}
+	if errors.Is(err, database.ErrNotWaiting) {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	if err != nil {
+		rtm.Logger.Error(
+			"restful_task_manager.change_task_priority",
+			"error when changing task priority",
+			map[string]interface{}{
+				"task_manager_worker_id": rtm._taskManagerWorkerID,
+				"task_id":                c.Params("id"),
+			},
+		)
+		return err
+	}
+
+	rtm.Logger.Success(
+		"restful_task_manager.change_task_priority",
+		"task priority changed without any problems",
+		map[string]interface{}{
+			"task_manager_worker_id": rtm._taskManagerWorkerID,
+			"task_id":                task.ID,
+			"task_priority":          *request.Priority,
+			"changed_by":             rtm._apiKey(c).ID,
+		},
+	)
+
+	return c.JSON(data.Response{Error: false, Message: "task priority changed without any problems", Payload: task})
+}
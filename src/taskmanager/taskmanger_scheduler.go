@@ -0,0 +1,117 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/health"
+	"openseawave.com/rasbora/internal/logger"
+)
+
+// taskScheduler move scheduled tasks to waiting queue when they are due and age waiting tasks, shared by all protocols.
+// Promotion and aging are done by single redis scripts, so running them on many task managers is safe.
+type taskScheduler struct {
+	config               *config.Config
+	logger               *logger.Logger
+	database             *database.Database
+	videoTranscoderQueue string
+	taskManagerWorkerID  string
+	interval             time.Duration
+	agingEnabled         bool
+	agingRatePerSecond   float64
+	agingMaxWait         time.Duration
+	agingMaxWaitPriority float64
+}
+
+// newTaskScheduler create task scheduler from task manager config.
+func newTaskScheduler(cfg *config.Config, log *logger.Logger, db *database.Database) *taskScheduler {
+	interval := time.Duration(cfg.GetInt("Components.TaskManagement.Scheduler.PromoteInterval")) * time.Second
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	return &taskScheduler{
+		config:               cfg,
+		logger:               log,
+		database:             db,
+		videoTranscoderQueue: cfg.GetString("Components.VideoTranscoding.Queue"),
+		taskManagerWorkerID:  cfg.GetString("Components.TaskManagement.UniqueID"),
+		interval:             interval,
+		agingEnabled:         cfg.GetBool("Components.TaskManagement.Scheduler.Aging.Enabled"),
+		agingRatePerSecond:   cfg.GetFloat64("Components.TaskManagement.Scheduler.Aging.RatePerMinute") / 60,
+		agingMaxWait:         time.Duration(cfg.GetInt("Components.TaskManagement.Scheduler.Aging.MaxWait")) * time.Second,
+		agingMaxWaitPriority: cfg.GetFloat64("Components.TaskManagement.Scheduler.Aging.MaxWaitPriority"),
+	}
+}
+
+// start promote due tasks and age waiting tasks on every interval until context is done.
+func (ts *taskScheduler) start(ctx context.Context) {
+	ts.logger.Info(
+		"task_scheduler",
+		"task scheduler has been started",
+		map[string]interface{}{
+			"task_manager_worker_id": ts.taskManagerWorkerID,
+			"promote_interval":       ts.interval.String(),
+		},
+	)
+
+	health.WatchLoop(Name, ts.interval)
+
+	ticker := time.NewTicker(ts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			health.Beat(Name)
+
+			if err := ts.database.PromoteDelayed(ts.videoTranscoderQueue); err != nil {
+				ts.logger.Warn(
+					"task_scheduler",
+					fmt.Sprintf("cannot promote scheduled tasks: %v", err.Error()),
+					map[string]interface{}{
+						"task_manager_worker_id": ts.taskManagerWorkerID,
+					},
+				)
+			}
+
+			// rate is saved with queue even when aging is disabled, so waiting items are scored again when it is turned off.
+			ratePerSecond, maxWait := ts.agingRatePerSecond, ts.agingMaxWait
+			if !ts.agingEnabled {
+				ratePerSecond, maxWait = 0, 0
+			}
+
+			if err := ts.database.AgeWaiting(ts.videoTranscoderQueue, ratePerSecond, maxWait, ts.agingMaxWaitPriority); err != nil {
+				ts.logger.Warn(
+					"task_scheduler",
+					fmt.Sprintf("cannot age waiting tasks: %v", err.Error()),
+					map[string]interface{}{
+						"task_manager_worker_id": ts.taskManagerWorkerID,
+					},
+				)
+			}
+		}
+	}
+}
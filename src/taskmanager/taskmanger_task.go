@@ -0,0 +1,587 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/filesystem"
+	"openseawave.com/rasbora/internal/tracing"
+	"openseawave.com/rasbora/internal/utilities"
+)
+
+// _taskValidator shared validator instance, it caches struct information.
+var _taskValidator = _newTaskValidator()
+
+// _newTaskValidator create validator knowing "identifier" tag used by tenant.
+func _newTaskValidator() *validator.Validate {
+	v := validator.New()
+	_ = v.RegisterValidation("identifier", func(fl validator.FieldLevel) bool {
+		return isValidIdentifier(fl.Field().String())
+	})
+	return v
+}
+
+// taskCreator validate new tasks and save them in video transcoder queue, shared by all protocols.
+type taskCreator struct {
+	config               *config.Config
+	database             *database.Database
+	fileSystemFor        func(fileSystemType data.FileSystemType) (*filesystem.FileSystem, error)
+	videoTranscoderQueue string
+	idempotencyTTL       time.Duration
+	maxArgsCount         int
+	maxBatchTasks        int
+	batchTTL             time.Duration
+	handlerCapabilities  map[string][]string
+}
+
+// handlerCapabilities capabilities implied by handler, read from config.
+type handlerCapabilities struct {
+	Handler      string
+	Capabilities []string
+}
+
+var (
+	// ErrConflict returned when task id or idempotency key was used before with different task.
+	ErrConflict = errors.New("task id or idempotency key already used with different task")
+	// ErrBatchEmpty returned when batch has no tasks.
+	ErrBatchEmpty = errors.New("batch has no tasks")
+	// ErrBatchTooLarge returned when batch has more tasks than allowed.
+	ErrBatchTooLarge = errors.New("batch has more tasks than allowed")
+	// ErrBatchIdInvalid returned when batch id has characters not allowed or is too long.
+	ErrBatchIdInvalid = errors.New("batch id must have only letters, digits, \"_\" and \"-\" and not be longer than 64 characters")
+	// ErrDuplicateTaskId returned when task id is used by another task.
+	ErrDuplicateTaskId = errors.New("task id already used by another task")
+	// ErrTooManyArgs returned when task has more output args than allowed.
+	ErrTooManyArgs = errors.New("task has more output args than allowed")
+	// ErrIdempotencyKeyInvalid returned when idempotency key is too long.
+	ErrIdempotencyKeyInvalid = errors.New("idempotency key must not be longer than 255 characters")
+	// ErrInputNotFound returned when input file of task does not exist in its file system.
+	ErrInputNotFound = errors.New("input file does not exist")
+	// ErrInputUnreadable returned when input file of task cannot be read from its file system.
+	ErrInputUnreadable = errors.New("input file cannot be read")
+)
+
+// newTaskCreator create task creator from task manager config, zero max args count means unlimited.
+func newTaskCreator(cfg *config.Config, db *database.Database, maxArgsCount int) *taskCreator {
+	idempotencyTTL := time.Duration(cfg.GetInt("Components.TaskManagement.Idempotency.TTL")) * time.Second
+	if idempotencyTTL <= 0 {
+		idempotencyTTL = 24 * time.Hour
+	}
+
+	batchTTL := time.Duration(cfg.GetInt("Components.TaskManagement.Batch.TTL")) * time.Second
+	if batchTTL <= 0 {
+		batchTTL = 7 * 24 * time.Hour
+	}
+
+	var handlers []handlerCapabilities
+	_ = cfg.UnmarshalKey("Components.VideoTranscoding.Engine.Ffmpeg.HandlerCapabilities", &handlers)
+
+	capabilities := map[string][]string{}
+	for _, handler := range handlers {
+		capabilities[handler.Handler] = utilities.MergeCapabilities(capabilities[handler.Handler], handler.Capabilities)
+	}
+
+	return &taskCreator{
+		config:   cfg,
+		database: db,
+		fileSystemFor: func(fileSystemType data.FileSystemType) (*filesystem.FileSystem, error) {
+			return filesystem.ForType(fileSystemType, *cfg)
+		},
+		videoTranscoderQueue: cfg.GetString("Components.VideoTranscoding.Queue"),
+		idempotencyTTL:       idempotencyTTL,
+		maxArgsCount:         maxArgsCount,
+		maxBatchTasks:        cfg.GetInt("Components.TaskManagement.Batch.MaxTasks"),
+		batchTTL:             batchTTL,
+		handlerCapabilities:  capabilities,
+	}
+}
+
+// create validate task against api key permissions and tenant quota, assign its id and creation time then save it.
+// Repeating same request with same idempotency key or task id return replayed with id of the original task.
+func (tc *taskCreator) create(ctx context.Context, key data.ApiKey, task *data.Task, idempotencyKey string) (replayed bool, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "task.create")
+	defer func() { tracing.End(span, err) }()
+
+	if err := tc._prepare(key, task); err != nil {
+		return false, err
+	}
+
+	fingerprint, err := taskFingerprint(*task)
+	if err != nil {
+		return false, err
+	}
+
+	if len(task.ID) <= 0 {
+		task.ID = uuid.NewString()
+	}
+	span.SetAttributes(attribute.String("task.id", task.ID))
+
+	if idempotencyKey != "" {
+		if len(idempotencyKey) > 255 {
+			return false, ErrIdempotencyKeyInvalid
+		}
+
+		// keys are scoped to api key so clients cannot see tasks of each other.
+		idempotencyKey = fmt.Sprintf("%v:%v", key.ID, idempotencyKey)
+
+		record, reserved, err := tc.database.ReserveIdempotencyKey(idempotencyKey, data.IdempotencyRecord{
+			TaskId:      task.ID,
+			Fingerprint: fingerprint,
+			CreatedAt:   time.Now().UnixMilli(),
+		}, tc.idempotencyTTL)
+		if err != nil {
+			return false, err
+		}
+
+		if !reserved {
+			if record.Fingerprint != fingerprint {
+				return false, ErrConflict
+			}
+			task.ID = record.TaskId
+			return true, nil
+		}
+	}
+
+	replayed, err = tc._enqueue(ctx, task, fingerprint)
+
+	// release the key so client can retry after fixing the problem.
+	if idempotencyKey != "" && err != nil {
+		_ = tc.database.ReleaseIdempotencyKey(idempotencyKey)
+	}
+
+	return replayed, err
+}
+
+// createBatch validate every task and save valid ones in single pipeline, tasks are grouped when batch id is set or group requested.
+func (tc *taskCreator) createBatch(ctx context.Context, key data.ApiKey, batch *data.TaskBatch) ([]data.TaskBatchResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "task.create_batch")
+	defer span.End()
+
+	if len(batch.Tasks) == 0 {
+		return nil, ErrBatchEmpty
+	}
+
+	if tc.maxBatchTasks > 0 && len(batch.Tasks) > tc.maxBatchTasks {
+		return nil, fmt.Errorf("%w: max %d tasks", ErrBatchTooLarge, tc.maxBatchTasks)
+	}
+
+	if batch.ID != "" && !isValidIdentifier(batch.ID) {
+		return nil, ErrBatchIdInvalid
+	}
+
+	if batch.Group && batch.ID == "" {
+		batch.ID = uuid.NewString()
+	}
+
+	quota, usage, err := tc._tenantQuota(key.Tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]data.TaskBatchResult, len(batch.Tasks))
+	seen := map[string]bool{}
+	var items []data.Queueable
+	var itemIndexes = map[string]int{}
+
+	for i := range batch.Tasks {
+		task := &batch.Tasks[i]
+		results[i].Index = i
+
+		if err := tc._prepare(key, task); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		fingerprint, err := taskFingerprint(*task)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		// task sent again with its id is reported as replayed, quota is checked only for new tasks.
+		if len(task.ID) > 0 && tc._existingResult(&results[i], task.ID, fingerprint) {
+			continue
+		}
+
+		if err := tc._checkTenantQuota(*task, quota, usage); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		if len(task.ID) <= 0 {
+			task.ID = uuid.NewString()
+		}
+
+		if seen[task.ID] {
+			results[i].Error = ErrDuplicateTaskId.Error()
+			continue
+		}
+		seen[task.ID] = true
+
+		task.BatchId = batch.ID
+		task.CreatedAt = time.Now().UnixMilli()
+
+		// every task of batch starts its own trace linked to batch request.
+		taskCtx, taskSpan := tracing.Tracer().Start(
+			context.Background(),
+			"task.create",
+			trace.WithLinks(trace.LinkFromContext(ctx)),
+			trace.WithAttributes(attribute.String("task.id", task.ID), attribute.String("batch.id", batch.ID)),
+		)
+		items = append(items, tc._queueable(taskCtx, *task, fingerprint))
+		taskSpan.End()
+		itemIndexes[task.ID] = i
+		results[i].TaskId = task.ID
+
+		usage.ActiveTasks++
+	}
+
+	existing, limited, err := tc.database.EnqueueBatch(tc.videoTranscoderQueue, items, tc._batchKey(key, batch.ID), tc.batchTTL, int64(quota.MaxConcurrentTasks))
+	if err != nil {
+		return nil, err
+	}
+
+	// tasks saved by another request meanwhile.
+	fingerprints := map[string]string{}
+	for _, item := range items {
+		fingerprints[item.ID] = item.Fingerprint
+	}
+	for _, taskId := range existing {
+		result := &results[itemIndexes[taskId]]
+		if !tc._existingResult(result, taskId, fingerprints[taskId]) {
+			result.TaskId = ""
+			result.Error = ErrDuplicateTaskId.Error()
+		}
+	}
+
+	for _, taskId := range limited {
+		results[itemIndexes[taskId]].TaskId = ""
+		results[itemIndexes[taskId]].Error = quota.errConcurrentTasks().Error()
+	}
+
+	return results, nil
+}
+
+// _existingResult fill batch result of task whose id is already used, same task is reported as replayed and other task as duplicate.
+// It returns false when id is not used.
+func (tc *taskCreator) _existingResult(result *data.TaskBatchResult, taskId string, fingerprint string) bool {
+	replayed, err := tc._replayed(taskId, fingerprint)
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return false
+	case errors.Is(err, ErrConflict):
+		result.TaskId = ""
+		result.Error = ErrDuplicateTaskId.Error()
+	case err != nil:
+		result.TaskId = ""
+		result.Error = err.Error()
+	default:
+		result.TaskId = taskId
+		result.Replayed = replayed
+	}
+	return true
+}
+
+// batchProgress count tasks of batch in each status.
+func (tc *taskCreator) batchProgress(key data.ApiKey, batchId string) (data.BatchProgress, error) {
+	statuses, err := tc.database.BatchStatuses(tc.videoTranscoderQueue, tc._batchKey(key, batchId))
+	if err != nil {
+		return data.BatchProgress{}, err
+	}
+
+	progress := data.BatchProgress{ID: batchId, Statuses: statuses}
+	var done int64
+	for status, count := range statuses {
+		progress.Total += count
+		if _isTaskDone(status) {
+			done += count
+		}
+	}
+
+	if progress.Total > 0 {
+		progress.Percentage = math.Round(float64(done)/float64(progress.Total)*10000) / 100
+	}
+
+	return progress, nil
+}
+
+// _batchKey scope batch id to tenant so tenants cannot read batches of each other.
+func (tc *taskCreator) _batchKey(key data.ApiKey, batchId string) string {
+	if batchId == "" || key.Tenant == "" {
+		return batchId
+	}
+	return fmt.Sprintf("%v:%v", key.Tenant, batchId)
+}
+
+// _prepare validate task and check api key can create it, task is assigned to tenant of api key.
+func (tc *taskCreator) _prepare(key data.ApiKey, task *data.Task) error {
+	if err := _taskValidator.Struct(task); err != nil {
+		return err
+	}
+
+	if tc.maxArgsCount > 0 && len(task.VideoTranscoder.Output.Args) > tc.maxArgsCount {
+		return ErrTooManyArgs
+	}
+
+	if !key.AllowsHandler(task.VideoTranscoder.Output.Handler) {
+		return ErrPermissionDenied
+	}
+
+	task.Tenant = key.Tenant
+
+	// history of priority changes and batch are managed by rasbora, client cannot set them.
+	task.PriorityChanges = nil
+	task.BatchId = ""
+
+	if task.RunAt == 0 && task.DelaySeconds > 0 {
+		task.RunAt = time.Now().Add(time.Duration(task.DelaySeconds) * time.Second).UnixMilli()
+	}
+
+	return nil
+}
+
+// _enqueue save task, task with existing id is accepted only when it is same task.
+// Tenant quota is checked only for new task, its active task slot is reserved while task is saved.
+func (tc *taskCreator) _enqueue(ctx context.Context, task *data.Task, fingerprint string) (replayed bool, err error) {
+	replayed, err = tc._replayed(task.ID, fingerprint)
+	if !errors.Is(err, database.ErrNotFound) {
+		return replayed, err
+	}
+
+	quota, usage, err := tc._tenantQuota(task.Tenant)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tc._checkTenantQuota(*task, quota, usage); err != nil {
+		return false, err
+	}
+
+	task.CreatedAt = time.Now().UnixMilli()
+
+	_, span := tracing.Tracer().Start(ctx, "queue.enqueue")
+	err = tc.database.EnqueueUnique(tc.videoTranscoderQueue, tc._queueable(ctx, *task, fingerprint), int64(quota.MaxConcurrentTasks))
+	span.End()
+
+	if errors.Is(err, database.ErrActiveLimitReached) {
+		return false, quota.errConcurrentTasks()
+	}
+
+	// task with same id is saved by another request meanwhile.
+	if errors.Is(err, database.ErrAlreadyExists) {
+		return tc._replayed(task.ID, fingerprint)
+	}
+
+	return false, err
+}
+
+// _replayed compare task with saved task of same id, database.ErrNotFound returned when id is not used.
+func (tc *taskCreator) _replayed(taskId string, fingerprint string) (bool, error) {
+	details, err := tc.database.Details(tc.videoTranscoderQueue, taskId)
+	if err != nil {
+		return false, err
+	}
+
+	// compare with fingerprint saved at creation, payload is changed by transcoder while task is processed.
+	existingFingerprint := details.Item.Fingerprint
+	if existingFingerprint == "" {
+		var existing data.Task
+		if err := decodePayload(details.Item.Payload, &existing); err != nil {
+			return false, err
+		}
+
+		if existingFingerprint, err = taskFingerprint(existing); err != nil {
+			return false, err
+		}
+	}
+
+	if existingFingerprint != fingerprint {
+		return false, ErrConflict
+	}
+
+	return true, nil
+}
+
+// _queueable create queue item of task, item requires capabilities of task and capabilities implied by its handler.
+// Trace context of ctx is carried inside item so processing of task continues same trace.
+func (tc *taskCreator) _queueable(ctx context.Context, task data.Task, fingerprint string) data.Queueable {
+	return data.Queueable{
+		ID:           task.ID,
+		Priority:     *task.Priority,
+		Tenant:       task.Tenant,
+		RunAt:        task.RunAt,
+		Capabilities: utilities.MergeCapabilities(task.RequiredCapabilities, tc.handlerCapabilities[task.VideoTranscoder.Output.Handler]),
+		TraceContext: tracing.Inject(ctx),
+		Fingerprint:  fingerprint,
+		Payload:      task,
+	}
+}
+
+// taskFingerprint hash task fields sent by client, fields managed by rasbora are ignored.
+func taskFingerprint(task data.Task) (string, error) {
+	task.ID = ""
+	task.BatchId = ""
+	task.CreatedAt = 0
+	task.StartedAt = 0
+	task.FinishedAt = 0
+	task.FailedAt = 0
+
+	// compare with priority task was created with, it can be changed while waiting.
+	if len(task.PriorityChanges) > 0 {
+		priority := task.PriorityChanges[0].PreviousPriority
+		task.Priority = &priority
+		task.PriorityChanges = nil
+	}
+
+	// run time is calculated again on every request using delay seconds.
+	if task.DelaySeconds > 0 {
+		task.RunAt = 0
+	}
+
+	// decode and encode again so numbers and map keys are written the same way as saved tasks.
+	var normalized interface{}
+	if err := decodePayload(task, &normalized); err != nil {
+		return "", err
+	}
+
+	taskAsJsonBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(taskAsJsonBytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// changePriority change priority of task still waiting and record who changed it.
+func (tc *taskCreator) changePriority(key data.ApiKey, taskId string, priority float64) (data.Task, error) {
+	if _, err := tenantTaskDetails(tc.database, tc.videoTranscoderQueue, key, taskId); err != nil {
+		return data.Task{}, err
+	}
+
+	var task data.Task
+	_, err := tc.database.UpdateWaiting(tc.videoTranscoderQueue, taskId, func(item *data.Queueable) error {
+		task = data.Task{}
+		if err := decodePayload(item.Payload, &task); err != nil {
+			return err
+		}
+
+		task.PriorityChanges = append(task.PriorityChanges, data.PriorityChange{
+			PreviousPriority: item.Priority,
+			Priority:         priority,
+			ChangedBy:        key.ID,
+			ChangedAt:        time.Now().UnixMilli(),
+		})
+		task.Priority = &priority
+
+		item.Priority = priority
+		item.Payload = task
+		return nil
+	})
+	if err != nil {
+		return data.Task{}, err
+	}
+
+	return task, nil
+}
+
+// tenantTaskDetails get task details, tasks of other tenants are reported as not found.
+func tenantTaskDetails(db *database.Database, queueName string, key data.ApiKey, taskId string) (data.QueueableDetails, error) {
+	details, err := db.Details(queueName, taskId)
+	if err != nil {
+		return details, err
+	}
+
+	if key.Tenant != "" && details.Item.Tenant != key.Tenant {
+		return data.QueueableDetails{}, database.ErrNotFound
+	}
+
+	return details, nil
+}
+
+// isValidationError check if error returned because task input is not correct.
+func isValidationError(err error) bool {
+	var validationErrors validator.ValidationErrors
+	return errors.As(err, &validationErrors)
+}
+
+// _tenantQuota load tenant quota with its current usage, tasks without tenant are unlimited.
+func (tc *taskCreator) _tenantQuota(tenant string) (tenantQuota, data.TenantUsage, error) {
+	if tenant == "" {
+		return tenantQuota{}, data.TenantUsage{}, nil
+	}
+
+	quota := loadTenantQuota(tc.config, tenant)
+
+	if quota.MaxConcurrentTasks <= 0 && quota.DailyTranscodeMinutes <= 0 {
+		return quota, data.TenantUsage{}, nil
+	}
+
+	usage, err := tc.database.TenantUsage(tc.videoTranscoderQueue, tenant)
+	if err != nil {
+		return quota, usage, err
+	}
+
+	return quota, usage, nil
+}
+
+// _checkTenantQuota refuse task when tenant reached one of its quotas.
+func (tc *taskCreator) _checkTenantQuota(task data.Task, quota tenantQuota, usage data.TenantUsage) error {
+	if err := quota.check(usage); err != nil {
+		return err
+	}
+
+	if quota.MaxInputSize > 0 && tc.fileSystemFor != nil {
+		fs, err := tc.fileSystemFor(task.VideoTranscoder.InputVideo.FileSystem)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInputUnreadable, err)
+		}
+
+		stat, err := fs.Stat(data.File{
+			FileName: task.VideoTranscoder.InputVideo.FileName,
+			FilePath: task.VideoTranscoder.InputVideo.FilePath,
+		})
+		if filesystem.IsNotFound(err) {
+			return ErrInputNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInputUnreadable, err)
+		}
+
+		if stat.Size > int64(quota.MaxInputSize)*1024*1024 {
+			return fmt.Errorf("%w: input file is larger than %d MB", ErrQuotaExceeded, quota.MaxInputSize)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,370 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+)
+
+// fakeQueueDatabase keep tasks and idempotency keys in memory.
+type fakeQueueDatabase struct {
+	database.Interface
+	items       map[string]data.Queueable
+	idempotency map[string]data.IdempotencyRecord
+}
+
+func newFakeQueueDatabase() *fakeQueueDatabase {
+	return &fakeQueueDatabase{items: map[string]data.Queueable{}, idempotency: map[string]data.IdempotencyRecord{}}
+}
+
+func (f *fakeQueueDatabase) EnqueueUnique(queueName string, item data.Queueable, maxActive int64) error {
+	if _, ok := f.items[item.ID]; ok {
+		return database.ErrAlreadyExists
+	}
+	f.items[item.ID] = item
+	return nil
+}
+
+func (f *fakeQueueDatabase) Details(queueName string, itemId string) (data.QueueableDetails, error) {
+	item, ok := f.items[itemId]
+	if !ok {
+		return data.QueueableDetails{}, database.ErrNotFound
+	}
+	return data.QueueableDetails{Item: item, Status: "waiting"}, nil
+}
+
+func (f *fakeQueueDatabase) ReserveIdempotencyKey(key string, record data.IdempotencyRecord, ttl time.Duration) (data.IdempotencyRecord, bool, error) {
+	if saved, ok := f.idempotency[key]; ok {
+		return saved, false, nil
+	}
+	f.idempotency[key] = record
+	return record, true, nil
+}
+
+func (f *fakeQueueDatabase) ReleaseIdempotencyKey(key string) error {
+	delete(f.idempotency, key)
+	return nil
+}
+
+func (f *fakeQueueDatabase) UpdateWaiting(queueName string, itemId string, update func(item *data.Queueable) error) (data.Queueable, error) {
+	item, ok := f.items[itemId]
+	if !ok {
+		return item, database.ErrNotFound
+	}
+	if err := update(&item); err != nil {
+		return item, err
+	}
+	f.items[itemId] = item
+	return item, nil
+}
+
+func TestTaskCreator_IdempotencyKey(t *testing.T) {
+	db := newFakeQueueDatabase()
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+	key := data.ApiKey{ID: "key"}
+
+	first := newTestTask()
+	replayed, err := creator.create(context.Background(), key, &first, "upload-1")
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+
+	retry := newTestTask()
+	replayed, err = creator.create(context.Background(), key, &retry, "upload-1")
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.ID, retry.ID)
+	assert.Len(t, db.items, 1)
+
+	conflicting := newTestTask()
+	conflicting.Label = "other"
+	_, err = creator.create(context.Background(), key, &conflicting, "upload-1")
+	assert.ErrorIs(t, err, ErrConflict)
+
+	otherClient := newTestTask()
+	replayed, err = creator.create(context.Background(), data.ApiKey{ID: "other-key"}, &otherClient, "upload-1")
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Len(t, db.items, 2)
+}
+
+func TestTaskCreator_DuplicateTaskId(t *testing.T) {
+	db := newFakeQueueDatabase()
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+
+	first := newTestTask()
+	first.ID = "task-1"
+	replayed, err := creator.create(context.Background(), data.ApiKey{}, &first, "")
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+
+	same := newTestTask()
+	same.ID = "task-1"
+	replayed, err = creator.create(context.Background(), data.ApiKey{}, &same, "")
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+
+	different := newTestTask()
+	different.ID = "task-1"
+	different.VideoTranscoder.Output.Container = "webm"
+	_, err = creator.create(context.Background(), data.ApiKey{}, &different, "")
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestTaskCreator_DuplicateTaskIdAfterProcessing(t *testing.T) {
+	db := newFakeQueueDatabase()
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+
+	first := newTestTask()
+	first.ID = "task-1"
+	first.VideoTranscoder.Output.Args = []map[string]interface{}{{"resolution": "720p"}}
+	_, err := creator.create(context.Background(), data.ApiKey{}, &first, "")
+	assert.NoError(t, err)
+
+	// transcoder saves args with output files once task is processed.
+	item := db.items["task-1"]
+	processed := first
+	processed.StartedAt = time.Now().UnixMilli()
+	processed.VideoTranscoder.Output.Args = []map[string]interface{}{{"resolution": "720p", "output": "task-1_0.mp4"}}
+	item.Payload = processed
+	db.items["task-1"] = item
+
+	same := newTestTask()
+	same.ID = "task-1"
+	same.VideoTranscoder.Output.Args = []map[string]interface{}{{"resolution": "720p"}}
+	replayed, err := creator.create(context.Background(), data.ApiKey{}, &same, "")
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+}
+
+func TestTaskCreator_TooManyArgs(t *testing.T) {
+	db := newFakeQueueDatabase()
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder", maxArgsCount: 1}
+
+	task := newTestTask()
+	task.VideoTranscoder.Output.Args = []map[string]interface{}{{"a": 1.0}, {"b": 2.0}}
+
+	_, err := creator.create(context.Background(), data.ApiKey{}, &task, "")
+	assert.ErrorIs(t, err, ErrTooManyArgs)
+	assert.Empty(t, db.items)
+}
+
+func (f *fakeQueueDatabase) EnqueueBatch(queueName string, items []data.Queueable, batch string, batchTTL time.Duration, maxActive int64) ([]string, []string, error) {
+	var existing []string
+	for _, item := range items {
+		if _, ok := f.items[item.ID]; ok {
+			existing = append(existing, item.ID)
+			continue
+		}
+		f.items[item.ID] = item
+	}
+	return existing, nil, nil
+}
+
+func TestTaskCreator_CreateBatch(t *testing.T) {
+	db := newFakeQueueDatabase()
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder", maxBatchTasks: 5}
+
+	saved := newTestTask()
+	saved.ID = "saved"
+	db.items["saved"] = data.Queueable{ID: "saved"}
+
+	invalid := newTestTask()
+	invalid.VideoTranscoder.Output.Handler = ""
+
+	duplicate := newTestTask()
+	duplicate.ID = "dup"
+
+	batch := data.TaskBatch{Group: true, Tasks: []data.Task{newTestTask(), invalid, saved, duplicate, duplicate}}
+	results, err := creator.createBatch(context.Background(), data.ApiKey{}, &batch)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, batch.ID)
+	assert.Len(t, results, 5)
+
+	assert.Empty(t, results[0].Error)
+	assert.NotEmpty(t, results[0].TaskId)
+	assert.NotEmpty(t, results[1].Error)
+	assert.Equal(t, ErrDuplicateTaskId.Error(), results[2].Error)
+	assert.Empty(t, results[3].Error)
+	assert.Equal(t, ErrDuplicateTaskId.Error(), results[4].Error)
+	assert.Equal(t, batch.ID, db.items[results[0].TaskId].Payload.(data.Task).BatchId)
+
+	_, err = creator.createBatch(context.Background(), data.ApiKey{}, &data.TaskBatch{})
+	assert.ErrorIs(t, err, ErrBatchEmpty)
+
+	_, err = creator.createBatch(context.Background(), data.ApiKey{}, &data.TaskBatch{Tasks: make([]data.Task, 6)})
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+
+	_, err = creator.createBatch(context.Background(), data.ApiKey{Tenant: "team-a"}, &data.TaskBatch{ID: "other:batch", Tasks: []data.Task{newTestTask()}})
+	assert.ErrorIs(t, err, ErrBatchIdInvalid)
+
+	_, err = creator.createBatch(context.Background(), data.ApiKey{Tenant: "team-a"}, &data.TaskBatch{ID: strings.Repeat("a", 65), Tasks: []data.Task{newTestTask()}})
+	assert.ErrorIs(t, err, ErrBatchIdInvalid)
+}
+
+func TestTaskCreator_CreateBatchReplay(t *testing.T) {
+	db := newFakeQueueDatabase()
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder", maxBatchTasks: 5}
+
+	task := newTestTask()
+	task.ID = "task-1"
+	_, err := creator.create(context.Background(), data.ApiKey{}, &task, "")
+	assert.NoError(t, err)
+
+	same := newTestTask()
+	same.ID = "task-1"
+
+	changed := newTestTask()
+	changed.ID = "task-1"
+	changed.VideoTranscoder.InputVideo.FileName = "other.mp4"
+
+	results, err := creator.createBatch(context.Background(), data.ApiKey{}, &data.TaskBatch{Tasks: []data.Task{same, changed}})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	assert.Empty(t, results[0].Error)
+	assert.True(t, results[0].Replayed)
+	assert.Equal(t, "task-1", results[0].TaskId)
+	assert.Equal(t, ErrDuplicateTaskId.Error(), results[1].Error)
+	assert.False(t, results[1].Replayed)
+	assert.Empty(t, results[1].TaskId)
+}
+
+func TestTaskCreator_DelaySeconds(t *testing.T) {
+	db := newFakeQueueDatabase()
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+
+	task := newTestTask()
+	task.ID = "task-1"
+	task.DelaySeconds = 3600
+	_, err := creator.create(context.Background(), data.ApiKey{}, &task, "")
+	assert.NoError(t, err)
+	assert.Greater(t, db.items["task-1"].RunAt, time.Now().Add(59*time.Minute).UnixMilli())
+
+	retry := newTestTask()
+	retry.ID = "task-1"
+	retry.DelaySeconds = 3600
+	replayed, err := creator.create(context.Background(), data.ApiKey{}, &retry, "")
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+
+	both := newTestTask()
+	both.RunAt = time.Now().UnixMilli()
+	both.DelaySeconds = 10
+	_, err = creator.create(context.Background(), data.ApiKey{}, &both, "")
+	assert.True(t, isValidationError(err))
+}
+
+func TestTaskCreator_ChangePriority(t *testing.T) {
+	db := newFakeQueueDatabase()
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+
+	task := newTestTask()
+	task.ID = "task-1"
+	_, err := creator.create(context.Background(), data.ApiKey{}, &task, "")
+	assert.NoError(t, err)
+
+	changed, err := creator.changePriority(data.ApiKey{ID: "support"}, "task-1", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, *changed.Priority)
+	assert.Len(t, changed.PriorityChanges, 1)
+	assert.Equal(t, "support", changed.PriorityChanges[0].ChangedBy)
+	assert.Equal(t, *task.Priority, changed.PriorityChanges[0].PreviousPriority)
+	assert.Equal(t, 0.0, db.items["task-1"].Priority)
+
+	retry := newTestTask()
+	retry.ID = "task-1"
+	replayed, err := creator.create(context.Background(), data.ApiKey{}, &retry, "")
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+
+	_, err = creator.changePriority(data.ApiKey{Tenant: "other"}, "task-1", 0)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestTaskCreator_IgnoreManagedFields(t *testing.T) {
+	db := newFakeQueueDatabase()
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+
+	task := newTestTask()
+	task.ID = "task-1"
+	task.BatchId = "batch-of-other-tenant"
+	task.PriorityChanges = []data.PriorityChange{{PreviousPriority: 5, Priority: 1, ChangedBy: "admin"}}
+	_, err := creator.create(context.Background(), data.ApiKey{}, &task, "")
+	assert.NoError(t, err)
+
+	saved := db.items["task-1"].Payload.(data.Task)
+	assert.Empty(t, saved.BatchId)
+	assert.Empty(t, saved.PriorityChanges)
+
+	// forged history cannot make task with other priority look like same task.
+	priority := 5.0
+	forged := newTestTask()
+	forged.ID = "task-1"
+	forged.Priority = &priority
+	forged.PriorityChanges = []data.PriorityChange{{PreviousPriority: 1, Priority: 5}}
+	_, err = creator.create(context.Background(), data.ApiKey{}, &forged, "")
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestTaskCreator_HandlerCapabilities(t *testing.T) {
+	v := viper.New()
+	v.Set("Components.VideoTranscoding.Engine.Ffmpeg.HandlerCapabilities", []map[string]interface{}{
+		{"handler": "custom:/etc/rasbora/handlers/gpu.handler", "capabilities": []string{"NVENC"}},
+	})
+	db := newFakeQueueDatabase()
+	creator := newTaskCreator(config.New(&config.ViperConfigManager{Viper: v}), database.New(db), 0)
+
+	gpu := newTestTask()
+	gpu.ID = "gpu"
+	gpu.RequiredCapabilities = []string{"hevc"}
+	gpu.VideoTranscoder.Output.Handler = "custom:/etc/rasbora/handlers/gpu.handler"
+	_, err := creator.create(context.Background(), data.ApiKey{}, &gpu, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hevc", "nvenc"}, db.items["gpu"].Capabilities)
+
+	cpu := newTestTask()
+	cpu.ID = "cpu"
+	_, err = creator.create(context.Background(), data.ApiKey{}, &cpu, "")
+	assert.NoError(t, err)
+	assert.Empty(t, db.items["cpu"].Capabilities)
+}
+
+func TestTaskCreator_TraceContext(t *testing.T) {
+	db := newFakeQueueDatabase()
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	task := newTestTask()
+	_, err := creator.create(ctx, data.ApiKey{}, &task, "")
+	assert.NoError(t, err)
+
+	traceContext := db.items[task.ID].TraceContext
+	assert.Contains(t, traceContext["traceparent"], span.SpanContext().TraceID().String())
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+)
+
+// ErrQuotaExceeded returned when tenant reached one of its quotas.
+var ErrQuotaExceeded = errors.New("tenant quota exceeded")
+
+// _identifierPattern characters allowed in tenant and batch id, they are joined with ":" inside database keys.
+var _identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// isValidIdentifier check tenant or batch id has only letters, digits, "_" and "-" and is not longer than 64 characters.
+func isValidIdentifier(value string) bool {
+	return _identifierPattern.MatchString(value)
+}
+
+// tenantQuota holds tenant limits, zero means unlimited.
+type tenantQuota struct {
+	MaxConcurrentTasks    int
+	DailyTranscodeMinutes int
+	MaxInputSize          int
+}
+
+// loadTenantQuota read tenant quota from config, missing values are taken from default tenant.
+func loadTenantQuota(cfg *config.Config, tenant string) tenantQuota {
+	value := func(name string) int {
+		key := fmt.Sprintf("Components.TaskManagement.Tenants.%v.%v", tenant, name)
+		if cfg.GetString(key) == "" {
+			key = fmt.Sprintf("Components.TaskManagement.Tenants.Default.%v", name)
+		}
+		return cfg.GetInt(key)
+	}
+
+	return tenantQuota{
+		MaxConcurrentTasks:    value("MaxConcurrentTasks"),
+		DailyTranscodeMinutes: value("DailyTranscodeMinutes"),
+		MaxInputSize:          value("MaxInputSize"),
+	}
+}
+
+// check compare tenant usage with quota.
+func (q tenantQuota) check(usage data.TenantUsage) error {
+	if q.MaxConcurrentTasks > 0 && usage.ActiveTasks >= int64(q.MaxConcurrentTasks) {
+		return q.errConcurrentTasks()
+	}
+
+	if q.DailyTranscodeMinutes > 0 && usage.TranscodeSecondsToday >= float64(q.DailyTranscodeMinutes*60) {
+		return fmt.Errorf("%w: %d daily transcode minutes limit reached", ErrQuotaExceeded, q.DailyTranscodeMinutes)
+	}
+
+	return nil
+}
+
+// errConcurrentTasks error returned when tenant has max concurrent tasks.
+func (q tenantQuota) errConcurrentTasks() error {
+	return fmt.Errorf("%w: %d concurrent tasks limit reached", ErrQuotaExceeded, q.MaxConcurrentTasks)
+}
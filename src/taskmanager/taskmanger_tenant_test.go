@@ -0,0 +1,237 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/config"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/database"
+	"openseawave.com/rasbora/internal/filesystem"
+)
+
+// fakeTenantDatabase report fixed tenant usage and keep enqueued items in memory.
+type fakeTenantDatabase struct {
+	database.Interface
+	usage    data.TenantUsage
+	raced    int64
+	enqueued []data.Queueable
+}
+
+func (f *fakeTenantDatabase) TenantUsage(queueName string, tenant string) (data.TenantUsage, error) {
+	return f.usage, nil
+}
+
+func (f *fakeTenantDatabase) EnqueueUnique(queueName string, item data.Queueable, maxActive int64) error {
+	// active tasks are counted by database while task is saved, tasks created meanwhile by other requests are included.
+	if item.Tenant != "" && maxActive > 0 && f.usage.ActiveTasks+f.raced >= maxActive {
+		return database.ErrActiveLimitReached
+	}
+	f.enqueued = append(f.enqueued, item)
+	return nil
+}
+
+func (f *fakeTenantDatabase) Details(queueName string, itemId string) (data.QueueableDetails, error) {
+	for _, item := range f.enqueued {
+		if item.ID == itemId {
+			return data.QueueableDetails{Item: item, Status: "waiting"}, nil
+		}
+	}
+	return data.QueueableDetails{}, database.ErrNotFound
+}
+
+func newTestTenantConfig() *config.Config {
+	v := viper.New()
+	v.Set("Components.TaskManagement.Tenants.Default.MaxConcurrentTasks", 2)
+	v.Set("Components.TaskManagement.Tenants.Default.DailyTranscodeMinutes", 60)
+	v.Set("Components.TaskManagement.Tenants.marketing.MaxConcurrentTasks", 10)
+	return config.New(&config.ViperConfigManager{Viper: v})
+}
+
+func newTestTask() data.Task {
+	priority := 1.0
+
+	var task data.Task
+	task.Label = "label"
+	task.Priority = &priority
+	task.Callback.URL = "http://localhost/callback"
+	task.Callback.Data = "data"
+	task.VideoTranscoder.InputVideo.FileSystem = data.ObjectFileSystemType
+	task.VideoTranscoder.InputVideo.FileName = "input.mp4"
+	task.VideoTranscoder.InputVideo.FilePath = "videos"
+	task.VideoTranscoder.Output.Handler = "default"
+	task.VideoTranscoder.Output.Container = "mp4"
+	task.VideoTranscoder.Output.Args = []map[string]interface{}{}
+
+	return task
+}
+
+func TestLoadTenantQuota(t *testing.T) {
+	cfg := newTestTenantConfig()
+
+	assert.Equal(t, tenantQuota{MaxConcurrentTasks: 10, DailyTranscodeMinutes: 60}, loadTenantQuota(cfg, "marketing"))
+	assert.Equal(t, tenantQuota{MaxConcurrentTasks: 2, DailyTranscodeMinutes: 60}, loadTenantQuota(cfg, "sales"))
+}
+
+func TestIsValidIdentifier(t *testing.T) {
+	cases := map[string]bool{
+		"team-a":                true,
+		"Team_01":               true,
+		strings.Repeat("a", 64): true,
+		"":                      false,
+		"team:a":                false,
+		"team a":                false,
+		"team/a":                false,
+		"{{tenant}}":            false,
+		strings.Repeat("a", 65): false,
+	}
+
+	for value, valid := range cases {
+		assert.Equal(t, valid, isValidIdentifier(value), value)
+	}
+
+	assert.NoError(t, _taskValidator.Struct(data.ApiKey{Name: "uploader", Tenant: "team-a", Scopes: []data.ApiKeyScope{data.ApiKeyScopeRead}}))
+	assert.Error(t, _taskValidator.Struct(data.ApiKey{Name: "uploader", Tenant: "team:a", Scopes: []data.ApiKeyScope{data.ApiKeyScopeRead}}))
+}
+
+func TestTenantQuota_Check(t *testing.T) {
+	quota := tenantQuota{MaxConcurrentTasks: 2, DailyTranscodeMinutes: 60}
+
+	assert.NoError(t, quota.check(data.TenantUsage{ActiveTasks: 1, TranscodeSecondsToday: 100}))
+	assert.ErrorIs(t, quota.check(data.TenantUsage{ActiveTasks: 2}), ErrQuotaExceeded)
+	assert.ErrorIs(t, quota.check(data.TenantUsage{TranscodeSecondsToday: 3600}), ErrQuotaExceeded)
+	assert.NoError(t, tenantQuota{}.check(data.TenantUsage{ActiveTasks: 1000}))
+}
+
+func TestTaskCreator_TenantQuota(t *testing.T) {
+	db := &fakeTenantDatabase{usage: data.TenantUsage{ActiveTasks: 2}}
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+
+	task := newTestTask()
+	_, err := creator.create(context.Background(), data.ApiKey{Tenant: "sales"}, &task, "")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Empty(t, db.enqueued)
+
+	task = newTestTask()
+	_, err = creator.create(context.Background(), data.ApiKey{Tenant: "marketing"}, &task, "")
+	assert.NoError(t, err)
+	assert.Len(t, db.enqueued, 1)
+	assert.Equal(t, "marketing", db.enqueued[0].Tenant)
+	assert.Equal(t, "marketing", task.Tenant)
+	assert.NotEmpty(t, task.ID)
+}
+
+func TestTaskCreator_TenantQuotaReplay(t *testing.T) {
+	db := &fakeTenantDatabase{usage: data.TenantUsage{ActiveTasks: 1}}
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+
+	task := newTestTask()
+	task.ID = "task-1"
+	_, err := creator.create(context.Background(), data.ApiKey{Tenant: "sales"}, &task, "")
+	assert.NoError(t, err)
+
+	// sending same task again when tenant is at its quota is replay, not new task.
+	db.usage.ActiveTasks = 2
+	retry := newTestTask()
+	retry.ID = "task-1"
+	replayed, err := creator.create(context.Background(), data.ApiKey{Tenant: "sales"}, &retry, "")
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Len(t, db.enqueued, 1)
+}
+
+func TestTaskCreator_TenantQuotaReservedWhileSaving(t *testing.T) {
+	db := &fakeTenantDatabase{usage: data.TenantUsage{ActiveTasks: 1}, raced: 1}
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+
+	// usage read before saving allows task, but another request took last slot meanwhile.
+	task := newTestTask()
+	_, err := creator.create(context.Background(), data.ApiKey{Tenant: "sales"}, &task, "")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Empty(t, db.enqueued)
+}
+
+func TestTaskCreator_HandlerNotAllowed(t *testing.T) {
+	db := &fakeTenantDatabase{}
+	creator := &taskCreator{config: newTestTenantConfig(), database: database.New(db), videoTranscoderQueue: "video_transcoder"}
+
+	task := newTestTask()
+	_, err := creator.create(context.Background(), data.ApiKey{Handlers: []string{"gpu"}}, &task, "")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Empty(t, db.enqueued)
+}
+
+// fakeStatFileSystem report fixed stat result for every file.
+type fakeStatFileSystem struct {
+	filesystem.Interface
+	stat data.FileStat
+	err  error
+}
+
+func (f *fakeStatFileSystem) Stat(file data.File) (data.FileStat, error) {
+	return f.stat, f.err
+}
+
+func TestTaskCreator_MaxInputSize(t *testing.T) {
+	v := viper.New()
+	v.Set("Components.TaskManagement.Tenants.Default.MaxInputSize", 1)
+	cfg := config.New(&config.ViperConfigManager{Viper: v})
+
+	var usedFileSystem data.FileSystemType
+	fileSystem := &fakeStatFileSystem{}
+	creator := &taskCreator{
+		config:               cfg,
+		database:             database.New(&fakeTenantDatabase{}),
+		videoTranscoderQueue: "video_transcoder",
+		fileSystemFor: func(fileSystemType data.FileSystemType) (*filesystem.FileSystem, error) {
+			usedFileSystem = fileSystemType
+			return filesystem.NewFileSystem(fileSystem), nil
+		},
+	}
+
+	task := newTestTask()
+	task.VideoTranscoder.InputVideo.FileSystem = data.LocalFileSystemType
+	fileSystem.stat = data.FileStat{Size: 2 * 1024 * 1024}
+	_, err := creator.create(context.Background(), data.ApiKey{Tenant: "sales"}, &task, "")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, data.LocalFileSystemType, usedFileSystem)
+
+	task = newTestTask()
+	fileSystem.err = os.ErrNotExist
+	_, err = creator.create(context.Background(), data.ApiKey{Tenant: "sales"}, &task, "")
+	assert.ErrorIs(t, err, ErrInputNotFound)
+	assert.Equal(t, data.ObjectFileSystemType, usedFileSystem)
+
+	task = newTestTask()
+	fileSystem.err = errors.New("access denied")
+	_, err = creator.create(context.Background(), data.ApiKey{Tenant: "sales"}, &task, "")
+	assert.ErrorIs(t, err, ErrInputUnreadable)
+
+	task = newTestTask()
+	fileSystem.err = nil
+	fileSystem.stat = data.FileStat{Size: 1024}
+	_, err = creator.create(context.Background(), data.ApiKey{Tenant: "sales"}, &task, "")
+	assert.NoError(t, err)
+}
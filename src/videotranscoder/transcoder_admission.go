@@ -0,0 +1,125 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"openseawave.com/rasbora/internal/config"
+)
+
+// resourceReadings holds local resource readings used to decide if worker can take new task.
+type resourceReadings struct {
+	FreeDisk        uint64
+	AvailableMemory uint64
+	Load1           float64
+	CPUs            int
+}
+
+// resourceReader read local resources, path is the folder free disk space is read for.
+type resourceReader func(path string) (resourceReadings, error)
+
+// admission decide if worker has enough headroom to take new task.
+type admission struct {
+	enabled            bool
+	path               string
+	minFreeDisk        uint64
+	minAvailableMemory uint64
+	maxLoadPerCpu      float64
+	read               resourceReader
+}
+
+// newAdmission create admission from video transcoder config, zero thresholds are not checked.
+func newAdmission(cfg *config.Config) *admission {
+	return &admission{
+		enabled:            cfg.GetBool("Components.VideoTranscoding.Admission.Enabled"),
+		path:               cfg.GetString("Filesystem.LocalStorage.Folders.TemporaryWorkingPath"),
+		minFreeDisk:        uint64(cfg.GetInt("Components.VideoTranscoding.Admission.MinFreeDisk")) * 1024 * 1024,
+		minAvailableMemory: uint64(cfg.GetInt("Components.VideoTranscoding.Admission.MinAvailableMemory")) * 1024 * 1024,
+		maxLoadPerCpu:      cfg.GetFloat64("Components.VideoTranscoding.Admission.MaxLoadPerCpu"),
+		read:               readLocalResources,
+	}
+}
+
+// check return reason why worker should not take new task now, empty reason means worker has enough headroom.
+func (a *admission) check() (reason string, err error) {
+	if !a.enabled {
+		return "", nil
+	}
+
+	readings, err := a.read(a.path)
+	if err != nil {
+		return "", err
+	}
+
+	if a.minFreeDisk > 0 && readings.FreeDisk < a.minFreeDisk {
+		return fmt.Sprintf("free disk space %d MB is below %d MB", readings.FreeDisk/1024/1024, a.minFreeDisk/1024/1024), nil
+	}
+
+	if a.minAvailableMemory > 0 && readings.AvailableMemory < a.minAvailableMemory {
+		return fmt.Sprintf("available memory %d MB is below %d MB", readings.AvailableMemory/1024/1024, a.minAvailableMemory/1024/1024), nil
+	}
+
+	if a.maxLoadPerCpu > 0 && readings.CPUs > 0 && readings.Load1/float64(readings.CPUs) > a.maxLoadPerCpu {
+		return fmt.Sprintf("load average %.2f is above %.2f per cpu", readings.Load1, a.maxLoadPerCpu), nil
+	}
+
+	return "", nil
+}
+
+// readLocalResources read free disk space of path, available memory and load average using gopsutil like system radar.
+func readLocalResources(path string) (resourceReadings, error) {
+	var readings resourceReadings
+
+	diskUsage, err := disk.Usage(_existingParent(path))
+	if err != nil {
+		return readings, err
+	}
+	readings.FreeDisk = diskUsage.Free
+
+	memoryInfo, err := mem.VirtualMemory()
+	if err != nil {
+		return readings, err
+	}
+	readings.AvailableMemory = memoryInfo.Available
+
+	loadAverage, err := load.Avg()
+	if err != nil {
+		return readings, err
+	}
+	readings.Load1 = loadAverage.Load1
+	readings.CPUs = runtime.NumCPU()
+
+	return readings, nil
+}
+
+// _existingParent return path or its nearest existing parent, working path may not be created yet.
+func _existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			return path
+		}
+		path = filepath.Dir(path)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmission_Check(t *testing.T) {
+	readings := resourceReadings{FreeDisk: 10 << 30, AvailableMemory: 4 << 30, Load1: 2, CPUs: 4}
+	a := &admission{
+		enabled:            true,
+		minFreeDisk:        1 << 30,
+		minAvailableMemory: 1 << 30,
+		maxLoadPerCpu:      1,
+		read: func(path string) (resourceReadings, error) {
+			return readings, nil
+		},
+	}
+
+	reason, err := a.check()
+	assert.NoError(t, err)
+	assert.Empty(t, reason)
+
+	readings.FreeDisk = 512 << 20
+	reason, _ = a.check()
+	assert.Contains(t, reason, "free disk space")
+
+	readings.FreeDisk = 10 << 30
+	readings.AvailableMemory = 512 << 20
+	reason, _ = a.check()
+	assert.Contains(t, reason, "available memory")
+
+	readings.AvailableMemory = 4 << 30
+	readings.Load1 = 8
+	reason, _ = a.check()
+	assert.Contains(t, reason, "load average")
+
+	a.enabled = false
+	reason, _ = a.check()
+	assert.Empty(t, reason)
+}
+
+func TestAdmission_CheckReadError(t *testing.T) {
+	a := &admission{
+		enabled: true,
+		read: func(path string) (resourceReadings, error) {
+			return resourceReadings{}, errors.New("not supported")
+		},
+	}
+
+	reason, err := a.check()
+	assert.Error(t, err)
+	assert.Empty(t, reason)
+}
+
+func TestReadLocalResources(t *testing.T) {
+	readings, err := readLocalResources(t.TempDir() + "/not/created/yet")
+	assert.NoError(t, err)
+	assert.Greater(t, readings.FreeDisk, uint64(0))
+	assert.Greater(t, readings.CPUs, 0)
+}
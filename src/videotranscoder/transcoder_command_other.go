@@ -0,0 +1,33 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !unix
+
+package videotranscoder
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// shellCommand prepare command run by shell, only shell is killed when ctx is done on systems without process groups.
+func shellCommand(ctx context.Context, shell string, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, shell, "-c", command)
+	cmd.WaitDelay = 10 * time.Second
+	return cmd
+}
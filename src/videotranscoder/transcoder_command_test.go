@@ -0,0 +1,63 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+
+package videotranscoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// _processAlive report whether process is running, zombie processes waiting to be reaped are not.
+func _processAlive(pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestShellCommand_KillsChildrenOnTimeout(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	startedAt := time.Now()
+	_, err := shellCommand(ctx, "sh", "sleep 30 & echo $! > "+pidFile+"; wait").CombinedOutput()
+	assert.Error(t, err)
+
+	// output pipe held by child would keep command waiting until wait delay.
+	assert.Less(t, time.Since(startedAt), 5*time.Second)
+
+	pidAsString, err := os.ReadFile(pidFile)
+	assert.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(pidAsString)))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return !_processAlive(pid) }, 2*time.Second, 20*time.Millisecond)
+}
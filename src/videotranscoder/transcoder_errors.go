@@ -0,0 +1,106 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/filesystem"
+)
+
+// ffmpegErrorPatterns ffmpeg output patterns and code of failure they mean, checked in order.
+// Patterns only about input are matched on lines naming input path, same message about other files is usually transient.
+var ffmpegErrorPatterns = []struct {
+	pattern   string
+	code      data.ErrorCode
+	inputOnly bool
+}{
+	{"no space left on device", data.ResourceExhaustedErrorCode, false},
+	{"cannot allocate memory", data.ResourceExhaustedErrorCode, false},
+	{"out of memory", data.ResourceExhaustedErrorCode, false},
+	{"no such file or directory", data.InputNotFoundErrorCode, true},
+	{"unknown encoder", data.UnsupportedCodecErrorCode, false},
+	{"unknown decoder", data.UnsupportedCodecErrorCode, false},
+	{"encoder not found", data.UnsupportedCodecErrorCode, false},
+	{"decoder not found", data.UnsupportedCodecErrorCode, false},
+	{"codec not currently supported", data.UnsupportedCodecErrorCode, false},
+	{"unsupported codec", data.UnsupportedCodecErrorCode, false},
+	{"invalid data found when processing input", data.InputUnreadableErrorCode, false},
+	{"moov atom not found", data.InputUnreadableErrorCode, false},
+	{"could not find codec parameters", data.InputUnreadableErrorCode, false},
+	{"invalid argument", data.InputUnreadableErrorCode, true},
+	{"unrecognized option", data.HandlerInvalidErrorCode, false},
+}
+
+// classifyFfmpegOutput return code of failure described in ffmpeg output of transcoding input path.
+func classifyFfmpegOutput(output string, inputPath string) data.ErrorCode {
+	output = strings.ToLower(output)
+	inputPath = strings.ToLower(inputPath)
+	for _, ffmpegErrorPattern := range ffmpegErrorPatterns {
+		if !ffmpegErrorPattern.inputOnly {
+			if strings.Contains(output, ffmpegErrorPattern.pattern) {
+				return ffmpegErrorPattern.code
+			}
+			continue
+		}
+
+		if inputPath == "" {
+			continue
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if strings.Contains(line, ffmpegErrorPattern.pattern) && strings.Contains(line, inputPath) {
+				return ffmpegErrorPattern.code
+			}
+		}
+	}
+	return data.TranscodeFailedErrorCode
+}
+
+// _ffmpegError build coded error of failed ffmpeg execution, message is last line of its output.
+func _ffmpegError(err error, output string, inputPath string) error {
+	code := classifyFfmpegOutput(output, inputPath)
+
+	// process killed without timeout is usually killed by oom killer.
+	var exitError *exec.ExitError
+	if code == data.TranscodeFailedErrorCode && errors.As(err, &exitError) &&
+		(exitError.ExitCode() == 137 || strings.Contains(exitError.String(), "signal: killed")) {
+		code = data.ResourceExhaustedErrorCode
+	}
+
+	message := err.Error()
+	if lines := strings.Split(strings.TrimSpace(output), "\n"); lines[len(lines)-1] != "" {
+		message = strings.TrimSpace(lines[len(lines)-1])
+	}
+
+	return data.NewTaskError(code, fmt.Errorf("ffmpeg failed: %v", message))
+}
+
+// _inputError classify error of reading input video from its file system.
+func _inputError(err error) error {
+	if errors.Is(err, ErrInsufficientDiskSpace) {
+		return data.ClassifyError(data.ResourceExhaustedErrorCode, err)
+	}
+	if filesystem.IsNotFound(err) {
+		return data.ClassifyError(data.InputNotFoundErrorCode, err)
+	}
+	return data.ClassifyError(data.StorageUnavailableErrorCode, err)
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/data"
+)
+
+func TestClassifyFfmpegOutput(t *testing.T) {
+	for output, expected := range map[string]data.ErrorCode{
+		"/tmp/input.mp4: No such file or directory":                               data.InputNotFoundErrorCode,
+		"[mov,mp4,m4a,3gp,3g2,mj2 @ 0x5581] moov atom not found":                  data.InputUnreadableErrorCode,
+		"/tmp/input.mp4: Invalid data found when processing input":                data.InputUnreadableErrorCode,
+		"/tmp/input.mp4: Invalid argument":                                        data.InputUnreadableErrorCode,
+		"Unknown encoder 'libsvtav1'":                                             data.UnsupportedCodecErrorCode,
+		"av_interleaved_write_frame(): No space left on device":                   data.ResourceExhaustedErrorCode,
+		"Unrecognized option 'crff'.\nError splitting the argument list":          data.HandlerInvalidErrorCode,
+		"Conversion failed!":                                                      data.TranscodeFailedErrorCode,
+		"Error while decoding stream #0:0: Cannot allocate memory\nConversion...": data.ResourceExhaustedErrorCode,
+		// same messages about other files are transient and retried.
+		"/tmp/output/720p.mp4: No such file or directory":                            data.TranscodeFailedErrorCode,
+		"[tcp @ 0x5581] Connection to tcp://127.0.0.1:9090 failed: Invalid argument": data.TranscodeFailedErrorCode,
+		"Error writing trailer of /tmp/output/720p.mp4: Invalid argument":            data.TranscodeFailedErrorCode,
+	} {
+		assert.Equal(t, expected, classifyFfmpegOutput(output, "/tmp/input.mp4"), output)
+	}
+
+	assert.Equal(t, data.TranscodeFailedErrorCode, classifyFfmpegOutput("/tmp/input.mp4: No such file or directory", ""))
+}
+
+func TestFfmpegError(t *testing.T) {
+	err := _ffmpegError(errors.New("exit status 1"), "ffmpeg version 6.0\nUnknown encoder 'libsvtav1'\n", "/tmp/input.mp4")
+	assert.Equal(t, data.UnsupportedCodecErrorCode, data.ErrorCodeOf(err))
+	assert.Equal(t, "ffmpeg failed: Unknown encoder 'libsvtav1'", err.Error())
+
+	err = _ffmpegError(errors.New("exit status 1"), "", "/tmp/input.mp4")
+	assert.Equal(t, data.TranscodeFailedErrorCode, data.ErrorCodeOf(err))
+	assert.Equal(t, "ffmpeg failed: exit status 1", err.Error())
+}
+
+func TestInputError(t *testing.T) {
+	_, statErr := os.Stat("/nonexistent/input.mp4")
+	assert.Equal(t, data.InputNotFoundErrorCode, data.ErrorCodeOf(_inputError(fmt.Errorf("cannot copy %w", statErr))))
+	assert.Equal(t, data.ResourceExhaustedErrorCode, data.ErrorCodeOf(_inputError(ErrInsufficientDiskSpace)))
+	assert.Equal(t, data.StorageUnavailableErrorCode, data.ErrorCodeOf(_inputError(errors.New("connection reset"))))
+
+	coded := data.NewTaskError(data.InvalidTaskErrorCode, errors.New("unknown filesystem type"))
+	assert.Equal(t, data.InvalidTaskErrorCode, data.ErrorCodeOf(_inputError(coded)))
+}
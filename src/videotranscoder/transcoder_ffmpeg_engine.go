@@ -24,19 +24,23 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime/debug"
 	"strings"
 	"time"
 
 	"github.com/flosch/pongo2/v6"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/vansante/go-ffprobe.v2"
 	"openseawave.com/rasbora/internal/config"
 	"openseawave.com/rasbora/internal/data"
 	"openseawave.com/rasbora/internal/database"
 	"openseawave.com/rasbora/internal/filesystem"
+	"openseawave.com/rasbora/internal/health"
 	"openseawave.com/rasbora/internal/logger"
+	"openseawave.com/rasbora/internal/metrics"
+	"openseawave.com/rasbora/internal/tracing"
 	"openseawave.com/rasbora/internal/utilities"
 )
 
@@ -52,13 +56,19 @@ type FfmpegTranscoderEngine struct {
 	_queueable                  *data.Queueable
 	_taskPayload                *data.Task
 	_videoTranscoderWorkerID    string
+	_capabilities               []string
+	_admission                  *admission
 	_temporaryWorkingPath       string
 	_temporaryInputVideoFile    *data.File
 	_temporaryProcessingLogFile *data.File
 	_temporaryOutputVideoFiles  *[]data.File
 	_sourceInputVideoFile       *data.File
+	_sourceInputVideoSize       int64
+	_outputsPreflighted         bool
 	_finalProcessingLogFile     *data.File
 	_finalOutputVideoFiles      *[]data.File
+	_traceContext               context.Context
+	_span                       trace.Span
 }
 
 //go:embed handlers/*
@@ -76,18 +86,52 @@ func (fte *FfmpegTranscoderEngine) StarTranscoderEngine(ctx context.Context) {
 	// get video transcoder worker id
 	fte._videoTranscoderWorkerID = fte.Config.GetString("Components.VideoTranscoding.UniqueID")
 
+	// get capabilities of this worker, tasks requiring other capabilities are left for other workers
+	fte._capabilities = utilities.MergeCapabilities(fte.Config.GetStringSlice("Components.VideoTranscoding.Capabilities"))
+
+	// prepare resource thresholds checked before taking new task
+	fte._admission = newAdmission(fte.Config)
+
+	// remove workspaces left by crashed runs
+	fte._sweepStaleWorkspaces()
+
 	// get video transcoder time interval for pooling new tasks.
 	checkNewTaskInterval := fte.Config.GetInt("Components.VideoTranscoding.CheckNewTaskInterval")
 
+	health.WatchLoop(Name, time.Duration(checkNewTaskInterval)*time.Second)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
+			health.Beat(Name)
 
 			time.Sleep(time.Duration(checkNewTaskInterval) * time.Second)
 
-			queueableItem, err := fte.Database.Dequeue(fte._videoTranscoderQueue, fte._videoTranscoderWorkerID)
+			// take new task only when this node has enough headroom to finish it.
+			reason, err := fte._admission.check()
+			if err != nil {
+				fte.Logger.Warn(
+					"ffmpeg_transcoder_engine",
+					fmt.Sprintf("cannot read local resources: %v", err.Error()),
+					map[string]interface{}{
+						"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+					},
+				)
+			}
+			if reason != "" {
+				fte.Logger.Info(
+					"ffmpeg_transcoder_engine",
+					fmt.Sprintf("skip taking new task: %v", reason),
+					map[string]interface{}{
+						"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+					},
+				)
+				continue
+			}
+
+			queueableItem, err := fte.Database.Dequeue(fte._videoTranscoderQueue, fte._videoTranscoderWorkerID, fte._capabilities)
 			fte._queueable = &queueableItem
 
 			if err != nil {
@@ -101,6 +145,10 @@ func (fte *FfmpegTranscoderEngine) StarTranscoderEngine(ctx context.Context) {
 				continue
 			}
 
+			if queueableItem.EnqueuedAt > 0 {
+				metrics.DequeueWait.WithLabelValues(fte._videoTranscoderQueue).Observe(time.Since(time.UnixMilli(queueableItem.EnqueuedAt)).Seconds())
+			}
+
 			fte.Logger.Success(
 				"ffmpeg_transcoder_engine",
 				"received new task",
@@ -126,14 +174,47 @@ func (fte *FfmpegTranscoderEngine) StarTranscoderEngine(ctx context.Context) {
 	}
 }
 
+// _sweepStaleWorkspaces remove workspaces of this worker older than configured age left by its crashed runs.
+func (fte *FfmpegTranscoderEngine) _sweepStaleWorkspaces() {
+	staleAfter := time.Duration(fte.Config.GetInt("Components.VideoTranscoding.Workspace.StaleAfter")) * time.Second
+	if staleAfter <= 0 {
+		return
+	}
+
+	removed, err := sweepStaleWorkspaces(fte.Config.GetString("Filesystem.LocalStorage.Folders.TemporaryWorkingPath"), fte._videoTranscoderWorkerID, staleAfter)
+	if err != nil {
+		fte.Logger.Warn(
+			"ffmpeg_transcoder_engine.sweep_stale_workspaces",
+			fmt.Sprintf("cannot remove stale workspaces: %v", err.Error()),
+			map[string]interface{}{
+				"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+			},
+		)
+	}
+
+	if len(removed) > 0 {
+		fte.Logger.Info(
+			"ffmpeg_transcoder_engine.sweep_stale_workspaces",
+			"stale workspaces removed",
+			map[string]interface{}{
+				"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+				"removed_workspaces":         removed,
+			},
+		)
+	}
+}
+
 // _prepareForProcessingTask prepare ffmpeg to transcode video base on task settings.
 func (fte *FfmpegTranscoderEngine) _prepareForProcessingTask() {
 
+	// continue trace started when task was created.
+	fte._startTrace()
+
 	//recover from panic
 	defer func() {
 		if r := recover(); r != nil {
 			jsonData, _ := json.Marshal(r)
-			fte._failedTask(errors.New(string(jsonData)))
+			fte._failedTask(data.NewTaskError(data.InternalErrorCode, errors.New(string(jsonData))))
 			fte.Logger.Error(
 				"ffmpeg_transcoder_engine.prepare_for_processing_task",
 				fmt.Sprintf("we got panic: %v", string(jsonData)),
@@ -156,7 +237,7 @@ func (fte *FfmpegTranscoderEngine) _prepareForProcessingTask() {
 				"task_id":                    fte._queueable.ID,
 			},
 		)
-		fte._failedTask(errJ)
+		fte._failedTask(data.NewTaskError(data.InvalidTaskErrorCode, errJ))
 		return
 	}
 
@@ -171,12 +252,17 @@ func (fte *FfmpegTranscoderEngine) _prepareForProcessingTask() {
 				"task_id":                    fte._queueable.ID,
 			},
 		)
-		fte._failedTask(errU)
+		fte._failedTask(data.NewTaskError(data.InvalidTaskErrorCode, errU))
 		return
 	}
 
+	metrics.TasksStarted.WithLabelValues(fte._handler()).Inc()
+	fte._span.SetAttributes(attribute.String("ffmpeg_handler", fte._handler()))
+
 	// update task starting time.
 	fte._taskPayload.StartedAt = time.Now().UnixMilli()
+	fte._sourceInputVideoSize = 0
+	fte._outputsPreflighted = false
 
 	// prepare a temporary working path.
 	if err := fte._prepareTemporaryWorkingPath(); err != nil {
@@ -221,7 +307,12 @@ func (fte *FfmpegTranscoderEngine) _prepareForProcessingTask() {
 	}
 
 	// prepare input video file.
-	if err := fte._prepareInputVideoFile(); err != nil {
+	if err := fte._stage("task.download", fte._prepareInputVideoFile); err != nil {
+		if errors.Is(err, ErrInsufficientDiskSpace) {
+			fte._requeueTask(err)
+			return
+		}
+
 		fte.Logger.Error(
 			"ffmpeg_transcoder_engine.prepare_for_processing_task",
 			fmt.Sprintf("error when prepare a temporary input video file: %v", err.Error()),
@@ -230,12 +321,12 @@ func (fte *FfmpegTranscoderEngine) _prepareForProcessingTask() {
 				"task_id":                    fte._queueable.ID,
 			},
 		)
-		fte._failedTask(errors.New("we cannot make a copy of input video file at to temporary transcoder file"))
+		fte._failedTask(_inputError(err))
 		return
 	}
 
 	// get all video information about input source
-	if err := fte._readInputVideoInformation(); err != nil {
+	if err := fte._stage("task.probe", fte._readInputVideoInformation); err != nil {
 		fte.Logger.Error(
 			"ffmpeg_transcoder_engine.prepare_for_processing_task",
 			fmt.Sprintf("cannot read input video information: %v", err.Error()),
@@ -244,12 +335,31 @@ func (fte *FfmpegTranscoderEngine) _prepareForProcessingTask() {
 				"task_id":                    fte._queueable.ID,
 			},
 		)
-		fte._failedTask(err)
+		fte._failedTask(data.ClassifyError(data.InputUnreadableErrorCode, err))
+		return
+	}
+
+	// check workspace can hold outputs when input duration was not known before download.
+	if err := fte._preflightOutputs(); err != nil {
+		if errors.Is(err, ErrInsufficientDiskSpace) {
+			fte._requeueTask(err)
+			return
+		}
+
+		fte.Logger.Error(
+			"ffmpeg_transcoder_engine.prepare_for_processing_task",
+			fmt.Sprintf("cannot check workspace disk space: %v", err.Error()),
+			map[string]interface{}{
+				"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+				"task_id":                    fte._queueable.ID,
+			},
+		)
+		fte._failedTask(data.ClassifyError(data.InternalErrorCode, err))
 		return
 	}
 
 	// prepare a temporary input video file.
-	if err := fte._transcodingInputVideoFile(); err != nil {
+	if err := fte._stage("task.transcode", fte._transcodingInputVideoFile); err != nil {
 		fte.Logger.Error(
 			"ffmpeg_transcoder_engine.prepare_for_processing_task",
 			fmt.Sprintf("fail to transcode video files: %v", err.Error()),
@@ -271,7 +381,7 @@ func (fte *FfmpegTranscoderEngine) _prepareForProcessingTask() {
 			)
 		}
 
-		fte._failedTask(fmt.Errorf("%v \n %v", err, readLogError))
+		fte._failedTask(data.ClassifyError(data.TranscodeFailedErrorCode, err))
 		return
 	}
 
@@ -279,12 +389,17 @@ func (fte *FfmpegTranscoderEngine) _prepareForProcessingTask() {
 	fte._successTask()
 }
 
-// _prepareTemporaryWorkingPath prepare a temporary working path.
+// _prepareTemporaryWorkingPath prepare a temporary working path only used by this task attempt.
 func (fte *FfmpegTranscoderEngine) _prepareTemporaryWorkingPath() error {
 
-	fte._temporaryWorkingPath = fte.Config.GetString("Filesystem.LocalStorage.Folders.TemporaryWorkingPath")
+	fte._temporaryWorkingPath = workspacePath(
+		fte.Config.GetString("Filesystem.LocalStorage.Folders.TemporaryWorkingPath"),
+		fte._videoTranscoderWorkerID,
+		fte._queueable.ID,
+		fte.Database.TotalRetry(fte._videoTranscoderQueue, *fte._queueable),
+	)
 
-	if err := os.MkdirAll(filepath.Dir(fte._temporaryWorkingPath), os.ModePerm); err != nil {
+	if err := os.MkdirAll(fte._temporaryWorkingPath, os.ModePerm); err != nil {
 		return err
 	}
 
@@ -414,7 +529,7 @@ func (fte *FfmpegTranscoderEngine) _prepareInputVideoFile() error {
 		objectClient, err := filesystem.NewObjectClient(*fte.Config)
 
 		if err != nil {
-			return errors.New("we cannot make a client for object filesystem")
+			return data.NewTaskError(data.StorageUnavailableErrorCode, errors.New("we cannot make a client for object filesystem"))
 		}
 
 		fs = filesystem.NewFileSystem(&filesystem.ObjectFileSystem{
@@ -438,6 +553,10 @@ func (fte *FfmpegTranscoderEngine) _prepareInputVideoFile() error {
 				"filesystem":                 fte._taskPayload.VideoTranscoder.InputVideo.FileSystem.String(),
 			},
 		)
+		return data.NewTaskError(
+			data.InvalidTaskErrorCode,
+			fmt.Errorf("unknown filesystem type: %v", fte._taskPayload.VideoTranscoder.InputVideo.FileSystem),
+		)
 	}
 
 	fte.Logger.Debug(
@@ -452,17 +571,26 @@ func (fte *FfmpegTranscoderEngine) _prepareInputVideoFile() error {
 		},
 	)
 
+	// check workspace can hold input and outputs before downloading input.
+	if err := fte._preflightDiskSpace(fs); err != nil {
+		return err
+	}
+
 	if err := fs.GetFile(
 		*fte._sourceInputVideoFile,
 		*fte._temporaryInputVideoFile,
 	); err != nil {
 		return fmt.Errorf(
-			"%s %s",
+			"%s %w",
 			"we cannot make a copy of input video file to video transcoder temporary file",
-			err.Error(),
+			err,
 		)
 	}
 
+	if info, err := os.Stat(fte._temporaryInputVideoFile.FullPath()); err == nil {
+		metrics.BytesDownloaded.WithLabelValues(fte._taskPayload.VideoTranscoder.InputVideo.FileSystem.String()).Add(float64(info.Size()))
+	}
+
 	fte.Logger.Debug(
 		"ffmpeg_transcoder_engine.prepare_input_video_file",
 		"input source video file successfully copied to video transcoder working path",
@@ -494,7 +622,7 @@ func (fte *FfmpegTranscoderEngine) _transcodingInputVideoFile() (err error) {
 				selectedHandler[1],
 			))
 			if err != nil {
-				return err
+				return data.NewTaskError(data.HandlerUnknownErrorCode, err)
 			}
 		}
 
@@ -503,26 +631,26 @@ func (fte *FfmpegTranscoderEngine) _transcodingInputVideoFile() (err error) {
 			selectedHandler := strings.Split(fte._taskPayload.VideoTranscoder.Output.Handler, "custom:")
 			ffmpegHandlerFile, err = os.ReadFile(selectedHandler[1])
 			if err != nil {
-				return err
+				return data.NewTaskError(data.HandlerUnknownErrorCode, err)
 			}
 		}
 	}
 
 	// if there is no handlers should return error
 	if ffmpegHandlerFile == nil {
-		return fmt.Errorf(
+		return data.NewTaskError(data.HandlerUnknownErrorCode, fmt.Errorf(
 			"%v [task_id=%v] [video_trancoder_worker_id=%v] [ffmpeg_handler=%v] ",
 			"unknown rasbora ffmpeg handler",
 			fte._taskPayload.ID,
 			fte._videoTranscoderWorkerID,
 			fte._taskPayload.VideoTranscoder.Output.Handler,
-		)
+		))
 	}
 
 	// parse handler template should have django template style
 	ffmpegHandler, err := pongo2.FromBytes(ffmpegHandlerFile.([]byte))
 	if err != nil {
-		return err
+		return data.NewTaskError(data.HandlerInvalidErrorCode, err)
 	}
 
 	fte.Logger.Debug(
@@ -562,7 +690,7 @@ func (fte *FfmpegTranscoderEngine) _transcodingInputVideoFile() (err error) {
 	// prepare to execute ffmpeg handler
 	ffmpegHandlerCmd, err := ffmpegHandler.Execute(handlerData)
 	if err != nil {
-		return err
+		return data.NewTaskError(data.HandlerInvalidErrorCode, err)
 	}
 	ffmpegHandlerCmd = strings.Join(strings.Fields(strings.ReplaceAll(ffmpegHandlerCmd, "\n", " ")), " ")
 
@@ -577,15 +705,46 @@ func (fte *FfmpegTranscoderEngine) _transcodingInputVideoFile() (err error) {
 		},
 	)
 
+	// kill ffmpeg when task timeout passes or progress stalls
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	if timeout := fte._taskTimeout(); timeout > 0 {
+		timer := time.AfterFunc(timeout, func() { cancel(ErrTaskTimeout) })
+		defer timer.Stop()
+	}
+
 	// execute ffmpeg handler and start ffmpeg processing events listener server
-	cmd := exec.Command(fte.Config.GetString("Components.VideoTranscoding.Shell"), "-c", ffmpegHandlerCmd)
+	cmd := shellCommand(ctx, fte.Config.GetString("Components.VideoTranscoding.Shell"), ffmpegHandlerCmd)
 	monitor := NewFfmpegProgressingMonitor(*fte)
+	monitor.WatchForStall(
+		time.Duration(fte.Config.GetInt("Components.VideoTranscoding.Timeout.StallAfter"))*time.Second,
+		func() { cancel(ErrTaskStalled) },
+	)
+	transcodeStartedAt := time.Now()
 	out, err := cmd.CombinedOutput()
+	monitor.StopMonitoringFfmpegProgress()
 	if err != nil {
-		monitor.StopMonitoringFfmpegProgress()
-		return errors.New(string(out))
+		fte.Logger.Debug(
+			"ffmpeg_transcoder_engine.transcoding_input_video_file",
+			fmt.Sprintf("ffmpeg output: %v", string(out)),
+			map[string]interface{}{
+				"task_id":                    fte._queueable.ID,
+				"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+				"ffmpeg_handler":             fte._taskPayload.VideoTranscoder.Output.Handler,
+			},
+		)
+		if cause := context.Cause(ctx); cause != nil {
+			return _timeoutError(cause)
+		}
+		return _ffmpegError(err, string(out), fte._temporaryInputVideoFile.FullPath())
+	}
+
+	transcodeDuration := time.Since(transcodeStartedAt)
+	metrics.TranscodeDuration.WithLabelValues(fte._handler()).Observe(transcodeDuration.Seconds())
+	if fte._inputVideoInformation.Format != nil && transcodeDuration > 0 {
+		metrics.TranscodeSpeed.WithLabelValues(fte._handler()).Observe(fte._inputVideoInformation.Format.DurationSeconds / transcodeDuration.Seconds())
 	}
-	monitor.StopMonitoringFfmpegProgress()
 
 	fte.Logger.Success(
 		"ffmpeg_transcoder_engine.transcoding_input_video_file",
@@ -689,6 +848,11 @@ func (fte *FfmpegTranscoderEngine) _moveTranscoderOutputVideos() error {
 
 		finalOutputVideoFiles = append(finalOutputVideoFiles, finalOutputVideoFile)
 
+		var outputSize int64
+		if info, err := os.Stat(temporaryVideoOutputFile.FullPath()); err == nil {
+			outputSize = info.Size()
+		}
+
 		if err := fte.FileSystem.PutFile(
 			temporaryVideoOutputFile,
 			finalOutputVideoFile,
@@ -696,6 +860,8 @@ func (fte *FfmpegTranscoderEngine) _moveTranscoderOutputVideos() error {
 			return err
 		}
 
+		metrics.BytesUploaded.WithLabelValues(fte.Config.GetString("Filesystem.Type")).Add(float64(outputSize))
+
 		fte.Logger.Debug(
 			"ffmpeg_transcoder_engine.move_transcoder_output_videos",
 			"moving output video file",
@@ -798,6 +964,9 @@ func (fte *FfmpegTranscoderEngine) _moveTranscoderProcessingLog() error {
 // _cleanAndPrepareForNextTask clean up after finish transcoding
 func (fte *FfmpegTranscoderEngine) _cleanAndPrepareForNextTask() {
 
+	// processing of task attempt ends here whatever its result.
+	defer fte._span.End()
+
 	// remove temporary input video file
 	_ = os.RemoveAll(fte._temporaryInputVideoFile.FullPath())
 
@@ -839,7 +1008,7 @@ func (fte *FfmpegTranscoderEngine) _cleanAndPrepareForNextTask() {
 		},
 	)
 
-	// remove temporary transcoding working space
+	// remove temporary working path of this task attempt
 	_ = os.RemoveAll(fte._temporaryWorkingPath)
 
 	fte.Logger.Debug(
@@ -869,6 +1038,7 @@ func (fte *FfmpegTranscoderEngine) _createNewCallback(err error) {
 	var callback = new(data.Callback)
 
 	callback.TaskId = fte._taskPayload.ID
+	callback.Label = fte._taskPayload.Label
 	callback.Priority = fte._taskPayload.Priority
 	callback.URL = fte._taskPayload.Callback.URL
 	callback.Data = fte._taskPayload.Callback.Data
@@ -880,6 +1050,8 @@ func (fte *FfmpegTranscoderEngine) _createNewCallback(err error) {
 		callback.ProcessingLogFile = *fte._finalProcessingLogFile
 	} else {
 		callback.Error = true
+		callback.ErrorCode = data.ErrorCodeOf(err)
+		callback.Retryable = callback.ErrorCode.Retryable()
 		callback.Message = err.Error()
 	}
 
@@ -891,6 +1063,7 @@ func (fte *FfmpegTranscoderEngine) _createNewCallback(err error) {
 	queueable.ID = fte._queueable.ID
 	queueable.Payload = fte._queueable.Priority
 	queueable.Payload = callback
+	queueable.TraceContext = tracing.Inject(fte._traceContext)
 
 	_ = fte.Database.Enqueue(fte._callbackManagerQueue, *queueable)
 
@@ -939,6 +1112,13 @@ func (fte *FfmpegTranscoderEngine) _failedTask(err error) {
 		},
 	)
 
+	// failures without more specific code are internal errors.
+	err = data.ClassifyError(data.InternalErrorCode, err)
+	fte._queueable.ErrorCode = data.ErrorCodeOf(err)
+	tracing.Fail(fte._span, err)
+	fte._span.SetAttributes(attribute.String("error_code", fte._queueable.ErrorCode.String()))
+	metrics.TasksFailed.WithLabelValues(fte._handler(), fte._queueable.ErrorCode.String()).Inc()
+
 	customError := map[string]interface{}{
 		"msg":   err.Error(),
 		"debug": debug.Stack(),
@@ -972,6 +1152,34 @@ func (fte *FfmpegTranscoderEngine) _failedTask(err error) {
 		return
 	}
 
+	//make it fail without retry when task fails again as it is
+	if !fte._queueable.ErrorCode.Retryable() {
+		fte.Logger.Debug(
+			"ffmpeg_transcoder_engine.failed_task",
+			"failed to transcode task with error that is not retryable",
+			map[string]interface{}{
+				"task_id":                    fte._queueable.ID,
+				"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+				"transcoder_retry_count":     retryCount,
+				"error_code":                 fte._queueable.ErrorCode,
+			},
+		)
+		fte._createNewCallback(err)
+		_ = fte.Database.Failed(fte._videoTranscoderQueue, *fte._queueable, errors.New(string(jsonError)))
+		fte._cleanAndPrepareForNextTask()
+		return
+	}
+
+	// wait longer after each retry, and let other nodes take task first for a while after waiting.
+	backoff := retryBackoff(
+		retryCount,
+		time.Duration(fte.Config.GetInt("Components.VideoTranscoding.Retry.BaseDelay"))*time.Second,
+		time.Duration(fte.Config.GetInt("Components.VideoTranscoding.Retry.MaxDelay"))*time.Second,
+	)
+	runAt := time.Now().Add(backoff)
+	fte._queueable.RunAt = runAt.UnixMilli()
+	fte._excludeWorker(runAt.Add(time.Duration(fte.Config.GetInt("Components.VideoTranscoding.Retry.PreferOtherWorkerFor")) * time.Second))
+
 	fte.Logger.Debug(
 		"ffmpeg_transcoder_engine.failed_task",
 		"sending back task to waiting queue again to retry transcoding one more time",
@@ -980,10 +1188,65 @@ func (fte *FfmpegTranscoderEngine) _failedTask(err error) {
 			"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
 			"transcoder_retry_count":     retryCount,
 			"transcoder_max_retry":       retryLimit,
+			"error_code":                 fte._queueable.ErrorCode,
+			"retry_backoff":              backoff.String(),
+		},
+	)
+
+	if err := fte.Database.Requeue(fte._videoTranscoderQueue, *fte._queueable, errors.New(string(jsonError)), true); err != nil {
+		fte.Logger.Error(
+			"ffmpeg_transcoder_engine.failed_task",
+			fmt.Sprintf("error when sending task back to waiting queue: %v", err.Error()),
+			map[string]interface{}{
+				"task_id":                    fte._queueable.ID,
+				"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+			},
+		)
+	}
+	fte._cleanAndPrepareForNextTask()
+}
+
+// _requeueTask send task back to waiting queue for another node, this worker skips it for a while.
+func (fte *FfmpegTranscoderEngine) _requeueTask(reason error) {
+
+	fte.Logger.Warn(
+		"ffmpeg_transcoder_engine.requeue_task",
+		fmt.Sprintf("sending task back to waiting queue for another node: %v", reason.Error()),
+		map[string]interface{}{
+			"task_id":                    fte._queueable.ID,
+			"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
 		},
 	)
 
-	_ = fte.Database.Enqueue(fte._videoTranscoderQueue, *fte._queueable)
+	// requeues are counted separately, so tasks waiting for a node with enough space keep their retries.
+	requeueLimit := fte.Config.GetInt("Components.VideoTranscoding.Preflight.MaxRequeues")
+	if requeueLimit <= 0 {
+		requeueLimit = fte.Config.GetInt("Components.VideoTranscoding.MakeAsFailedAfterRetry")
+	}
+
+	// no node had enough space after too many requeues.
+	if fte._queueable.Requeues >= requeueLimit {
+		fte._failedTask(data.ClassifyError(data.ResourceExhaustedErrorCode, reason))
+		return
+	}
+
+	fte._queueable.Requeues++
+	fte._excludeWorker(time.Now().Add(time.Duration(fte.Config.GetInt("Components.VideoTranscoding.Preflight.ExcludeWorkerFor")) * time.Second))
+
+	fte._queueable.Payload = fte._taskPayload
+	fte._span.AddEvent("requeued", trace.WithAttributes(attribute.String("reason", reason.Error())))
+
+	if err := fte.Database.Requeue(fte._videoTranscoderQueue, *fte._queueable, reason, false); err != nil {
+		fte.Logger.Error(
+			"ffmpeg_transcoder_engine.requeue_task",
+			fmt.Sprintf("error when sending task back to waiting queue: %v", err.Error()),
+			map[string]interface{}{
+				"task_id":                    fte._queueable.ID,
+				"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+			},
+		)
+	}
+
 	fte._cleanAndPrepareForNextTask()
 }
 
@@ -1003,7 +1266,7 @@ func (fte *FfmpegTranscoderEngine) _successTask() {
 	}
 
 	// move output files to main filesystem.
-	if err := fte._moveTranscoderOutputVideos(); err != nil {
+	if err := fte._stage("task.upload", fte._moveTranscoderOutputVideos); err != nil {
 		fte.Logger.Error("ffmpeg_transcoder_engine.success_task",
 			fmt.Sprintf("error when move output files to main filesystem: %v", err.Error()),
 			map[string]interface{}{
@@ -1011,7 +1274,7 @@ func (fte *FfmpegTranscoderEngine) _successTask() {
 				"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
 			},
 		)
-		fte._failedTask(err)
+		fte._failedTask(data.NewTaskError(data.UploadFailedErrorCode, err))
 		return
 	}
 
@@ -1027,9 +1290,30 @@ func (fte *FfmpegTranscoderEngine) _successTask() {
 	fte._taskPayload.FinishedAt = time.Now().UnixMilli()
 
 	fte._queueable.Payload = fte._taskPayload
+	fte._queueable.ErrorCode = ""
+	metrics.TasksFinished.WithLabelValues(fte._handler()).Inc()
 
 	_ = fte.Database.Finished(fte._videoTranscoderQueue, *fte._queueable)
 
+	// count transcoded minutes against tenant daily quota.
+	if fte._queueable.Tenant != "" && fte._inputVideoInformation != nil && fte._inputVideoInformation.Format != nil {
+		if err := fte.Database.AddTenantTranscodeSeconds(
+			fte._videoTranscoderQueue,
+			fte._queueable.Tenant,
+			fte._inputVideoInformation.Format.DurationSeconds,
+		); err != nil {
+			fte.Logger.Error(
+				"ffmpeg_transcoder_engine.success_task",
+				fmt.Sprintf("error when saving tenant usage: %v", err.Error()),
+				map[string]interface{}{
+					"task_id":                    fte._queueable.ID,
+					"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+					"tenant":                     fte._queueable.Tenant,
+				},
+			)
+		}
+	}
+
 	fte.Logger.Success(
 		"ffmpeg_transcoder_engine.success_task",
 		"task finished processing without any problems",
@@ -1043,3 +1327,11 @@ func (fte *FfmpegTranscoderEngine) _successTask() {
 
 	fte._cleanAndPrepareForNextTask()
 }
+
+// _handler return ffmpeg handler of current task, used as metrics label.
+func (fte *FfmpegTranscoderEngine) _handler() string {
+	if fte._taskPayload == nil {
+		return ""
+	}
+	return fte._taskPayload.VideoTranscoder.Output.Handler
+}
@@ -24,6 +24,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"openseawave.com/rasbora/internal/health"
 )
 
 // FfmpegProgressingMonitor hold an instance.
@@ -32,6 +35,9 @@ type FfmpegProgressingMonitor struct {
 	stopSignal               chan interface{}
 	waitGroup                sync.WaitGroup
 	engine                   *FfmpegTranscoderEngine
+	progressMutex            sync.Mutex
+	lastOutTime              int64
+	lastProgressAt           time.Time
 }
 
 // NewFfmpegProgressingMonitor start listen for ffmpeg processing events.
@@ -48,8 +54,10 @@ func NewFfmpegProgressingMonitor(ffmpegTranscoderEngine FfmpegTranscoderEngine)
 	)
 
 	fpm := &FfmpegProgressingMonitor{
-		stopSignal: make(chan interface{}),
-		engine:     &ffmpegTranscoderEngine,
+		stopSignal:     make(chan interface{}),
+		engine:         &ffmpegTranscoderEngine,
+		lastOutTime:    -1,
+		lastProgressAt: time.Now(),
 	}
 
 	addr := fpm.engine.Config.GetString("Components.VideoTranscoding.Engine.Ffmpeg.ProgressListener")
@@ -92,6 +100,50 @@ func (fpm *FfmpegProgressingMonitor) _listenForFfmpegConnections() {
 	}
 }
 
+// WatchForStall call stalled once when out_time_ms of ffmpeg did not advance for stallAfter, zero disables it.
+func (fpm *FfmpegProgressingMonitor) WatchForStall(stallAfter time.Duration, stalled func()) {
+	if stallAfter <= 0 {
+		return
+	}
+
+	fpm.waitGroup.Add(1)
+
+	go func() {
+		defer fpm.waitGroup.Done()
+
+		ticker := time.NewTicker(stallAfter / 4)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-fpm.stopSignal:
+				return
+			case <-ticker.C:
+				fpm.progressMutex.Lock()
+				stalledFor := time.Since(fpm.lastProgressAt)
+				fpm.progressMutex.Unlock()
+
+				if stalledFor >= stallAfter {
+					stalled()
+					return
+				}
+			}
+		}
+	}()
+}
+
+// _progressed record out time reported by ffmpeg, stall timer restarts when it advances.
+func (fpm *FfmpegProgressingMonitor) _progressed(outTime int64) {
+	fpm.progressMutex.Lock()
+	defer fpm.progressMutex.Unlock()
+
+	if outTime > fpm.lastOutTime {
+		fpm.lastOutTime = outTime
+		fpm.lastProgressAt = time.Now()
+		health.Beat(Name)
+	}
+}
+
 // StopMonitoringFfmpegProgress stops the listener and waits for all goroutines to finish.
 func (fpm *FfmpegProgressingMonitor) StopMonitoringFfmpegProgress() {
 	close(fpm.stopSignal)
@@ -188,6 +240,8 @@ func (fpm *FfmpegProgressingMonitor) _handleFfmpegEvents(ffmpegProcessingConnect
 			return
 		}
 
+		fpm._progressed(int64(processedTime))
+
 		duration := fpm.engine._inputVideoInformation.Format.Duration()
 
 		data["task_id"] = fpm.engine._queueable.ID
@@ -0,0 +1,171 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"gopkg.in/vansante/go-ffprobe.v2"
+	"openseawave.com/rasbora/internal/data"
+	"openseawave.com/rasbora/internal/filesystem"
+)
+
+// ErrInsufficientDiskSpace returned when workspace of this node cannot hold task input and outputs.
+var ErrInsufficientDiskSpace = errors.New("not enough disk space on this node for task input and outputs")
+
+// freeDiskSpace read free disk space of path, replaced in tests.
+var freeDiskSpace = func(path string) (uint64, error) {
+	usage, err := disk.Usage(_existingParent(path))
+	if err != nil {
+		return 0, err
+	}
+	return usage.Free, nil
+}
+
+// _preflightDiskSpace check workspace can hold input video and estimated outputs before downloading input.
+// Duration of input is known only for local files, outputs of other files are checked after input is probed.
+func (fte *FfmpegTranscoderEngine) _preflightDiskSpace(fs *filesystem.FileSystem) error {
+	if !fte.Config.GetBool("Components.VideoTranscoding.Preflight.Enabled") {
+		return nil
+	}
+
+	stat, err := fs.Stat(*fte._sourceInputVideoFile)
+	if err != nil {
+		return err
+	}
+	fte._sourceInputVideoSize = stat.Size
+
+	var durationSeconds float64
+	if fte._taskPayload.VideoTranscoder.InputVideo.FileSystem == data.LocalFileSystemType {
+		probeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if probeData, err := ffprobe.ProbeURL(probeCtx, fte._sourceInputVideoFile.FullPath()); err == nil && probeData.Format != nil {
+			durationSeconds = probeData.Format.DurationSeconds
+		}
+	}
+
+	required := stat.Size
+	if durationSeconds > 0 {
+		required += estimateOutputSize(fte._taskPayload.VideoTranscoder.Output.Args, durationSeconds, stat.Size)
+		fte._outputsPreflighted = true
+	}
+
+	return fte._checkDiskSpace(required)
+}
+
+// _preflightOutputs check workspace can hold estimated outputs once input duration is known.
+func (fte *FfmpegTranscoderEngine) _preflightOutputs() error {
+	if !fte.Config.GetBool("Components.VideoTranscoding.Preflight.Enabled") || fte._outputsPreflighted {
+		return nil
+	}
+
+	if fte._inputVideoInformation == nil || fte._inputVideoInformation.Format == nil {
+		return nil
+	}
+
+	return fte._checkDiskSpace(estimateOutputSize(
+		fte._taskPayload.VideoTranscoder.Output.Args,
+		fte._inputVideoInformation.Format.DurationSeconds,
+		fte._sourceInputVideoSize,
+	))
+}
+
+// _checkDiskSpace compare required bytes with margin against free space of working path.
+func (fte *FfmpegTranscoderEngine) _checkDiskSpace(required int64) error {
+	margin := fte.Config.GetFloat64("Components.VideoTranscoding.Preflight.SizeMargin")
+	if margin < 1 {
+		margin = 1
+	}
+	required = int64(float64(required) * margin)
+
+	free, err := freeDiskSpace(fte._temporaryWorkingPath)
+	if err != nil {
+		return err
+	}
+
+	fte.Logger.Debug(
+		"ffmpeg_transcoder_engine.check_disk_space",
+		"checking workspace disk space",
+		map[string]interface{}{
+			"task_id":                    fte._queueable.ID,
+			"video_transcoder_worker_id": fte._videoTranscoderWorkerID,
+			"required_disk_space":        required,
+			"free_disk_space":            free,
+		},
+	)
+
+	if uint64(required) > free {
+		return fmt.Errorf("%w: required %d MB, free %d MB", ErrInsufficientDiskSpace, required/1024/1024, free/1024/1024)
+	}
+
+	return nil
+}
+
+// estimateOutputSize estimate size of all outputs from their video and audio bitrates and input duration.
+// Outputs without bitrates are expected to be as large as input.
+func estimateOutputSize(args []map[string]interface{}, durationSeconds float64, inputSize int64) int64 {
+	var total int64
+	for _, arg := range args {
+		videoBitrate, videoOk := parseBitrate(arg["bv"])
+		audioBitrate, audioOk := parseBitrate(arg["ba"])
+		if !videoOk {
+			total += inputSize
+			continue
+		}
+		if !audioOk {
+			audioBitrate = 0
+		}
+		total += int64((videoBitrate + audioBitrate) * durationSeconds / 8)
+	}
+	return total
+}
+
+// parseBitrate parse ffmpeg bitrate like "828k" or "2M" into bits per second.
+func parseBitrate(value interface{}) (float64, bool) {
+	bitrate, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+
+	bitrate = strings.TrimSpace(bitrate)
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(bitrate, "k"), strings.HasSuffix(bitrate, "K"):
+		multiplier = 1e3
+	case strings.HasSuffix(bitrate, "M"):
+		multiplier = 1e6
+	case strings.HasSuffix(bitrate, "G"):
+		multiplier = 1e9
+	}
+	if multiplier != 1 {
+		bitrate = bitrate[:len(bitrate)-1]
+	}
+
+	number, err := strconv.ParseFloat(bitrate, 64)
+	if err != nil || number <= 0 {
+		return 0, false
+	}
+
+	return number * multiplier, true
+}
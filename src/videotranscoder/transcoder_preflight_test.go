@@ -0,0 +1,54 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBitrate(t *testing.T) {
+	for value, expected := range map[string]float64{"828k": 828e3, "2M": 2e6, "1.5G": 1.5e9, "64000": 64000} {
+		bitrate, ok := parseBitrate(value)
+		assert.True(t, ok, value)
+		assert.Equal(t, expected, bitrate, value)
+	}
+
+	for _, value := range []interface{}{"", "k", "fast", nil, 128} {
+		_, ok := parseBitrate(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestEstimateOutputSize(t *testing.T) {
+	args := []map[string]interface{}{
+		{"bv": "800k", "ba": "200k"},
+		{"bv": "1M"},
+		{"crf": "23"},
+	}
+
+	// 100 seconds of 1 Mbit/s is 12.5 MB for each of first two outputs, last output is expected as large as input.
+	assert.Equal(t, int64(12_500_000+12_500_000+40_000_000), estimateOutputSize(args, 100, 40_000_000))
+}
+
+func TestFreeDiskSpace(t *testing.T) {
+	free, err := freeDiskSpace(t.TempDir() + "/workspace/")
+	assert.NoError(t, err)
+	assert.Greater(t, free, uint64(0))
+}
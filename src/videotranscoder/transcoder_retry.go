@@ -0,0 +1,42 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"time"
+)
+
+// retryBackoff return wait before retry of task failed retryCount times, doubled for each retry up to maxDelay.
+func retryBackoff(retryCount int, baseDelay time.Duration, maxDelay time.Duration) time.Duration {
+	backoff := baseDelay
+	for i := 1; i < retryCount && backoff < maxDelay; i++ {
+		backoff *= 2
+	}
+	if maxDelay > 0 && backoff > maxDelay {
+		return maxDelay
+	}
+	return backoff
+}
+
+// _excludeWorker make this worker skip current task until given time, so other nodes take it first.
+func (fte *FfmpegTranscoderEngine) _excludeWorker(until time.Time) {
+	if fte._queueable.ExcludedWorkers == nil {
+		fte._queueable.ExcludedWorkers = map[string]int64{}
+	}
+	fte._queueable.ExcludedWorkers[fte._videoTranscoderWorkerID] = until.UnixMilli()
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	for retryCount, expected := range map[int]time.Duration{
+		0: 10 * time.Second,
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		4: 80 * time.Second,
+		8: 10 * time.Minute,
+	} {
+		assert.Equal(t, expected, retryBackoff(retryCount, 10*time.Second, 10*time.Minute), retryCount)
+	}
+
+	assert.Equal(t, time.Duration(0), retryBackoff(3, 0, 10*time.Minute))
+}
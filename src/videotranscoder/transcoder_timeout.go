@@ -0,0 +1,63 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"errors"
+	"time"
+
+	"openseawave.com/rasbora/internal/data"
+)
+
+var (
+	// ErrTaskTimeout returned when transcoding takes longer than task timeout.
+	ErrTaskTimeout = errors.New("transcoding took longer than task timeout")
+	// ErrTaskStalled returned when ffmpeg progress did not advance for stall timeout.
+	ErrTaskStalled = errors.New("transcoding progress stalled")
+)
+
+// taskTimeout return time allowed to transcode input of duration, zero means no limit.
+func taskTimeout(duration time.Duration, multiplier float64, minimum time.Duration) time.Duration {
+	timeout := time.Duration(float64(duration) * multiplier)
+	if timeout < minimum {
+		return minimum
+	}
+	return timeout
+}
+
+// _taskTimeout return time allowed to transcode input video of current task.
+func (fte *FfmpegTranscoderEngine) _taskTimeout() time.Duration {
+	var duration time.Duration
+	if fte._inputVideoInformation != nil && fte._inputVideoInformation.Format != nil {
+		duration = fte._inputVideoInformation.Format.Duration()
+	}
+
+	return taskTimeout(
+		duration,
+		fte.Config.GetFloat64("Components.VideoTranscoding.Timeout.DurationMultiplier"),
+		time.Duration(fte.Config.GetInt("Components.VideoTranscoding.Timeout.Minimum"))*time.Second,
+	)
+}
+
+// _timeoutError wrap err with code of timeout kind sent in callback.
+func _timeoutError(err error) error {
+	if errors.Is(err, ErrTaskStalled) {
+		return data.NewTaskError(data.StalledErrorCode, err)
+	}
+	return data.NewTaskError(data.TimeoutErrorCode, err)
+}
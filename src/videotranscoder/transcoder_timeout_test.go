@@ -0,0 +1,71 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"openseawave.com/rasbora/internal/data"
+)
+
+func TestTaskTimeout(t *testing.T) {
+	assert.Equal(t, 20*time.Minute, taskTimeout(2*time.Minute, 10, 10*time.Minute))
+	assert.Equal(t, 10*time.Minute, taskTimeout(30*time.Second, 10, 10*time.Minute))
+	assert.Equal(t, 10*time.Minute, taskTimeout(0, 10, 10*time.Minute))
+	assert.Equal(t, time.Duration(0), taskTimeout(2*time.Minute, 0, 0))
+}
+
+func TestTimeoutErrorCode(t *testing.T) {
+	assert.Equal(t, data.TimeoutErrorCode, data.ErrorCodeOf(_timeoutError(ErrTaskTimeout)))
+	assert.Equal(t, data.StalledErrorCode, data.ErrorCodeOf(_timeoutError(ErrTaskStalled)))
+
+	err := _timeoutError(ErrTaskStalled)
+	assert.True(t, errors.Is(err, ErrTaskStalled))
+	assert.Equal(t, data.ErrorCode(""), data.ErrorCodeOf(errors.New("ffmpeg failed")))
+}
+
+func TestFfmpegProgressingMonitor_WatchForStall(t *testing.T) {
+	newMonitor := func() *FfmpegProgressingMonitor {
+		return &FfmpegProgressingMonitor{stopSignal: make(chan interface{}), lastOutTime: -1, lastProgressAt: time.Now()}
+	}
+
+	// progress never advances
+	stalled := make(chan struct{})
+	monitor := newMonitor()
+	monitor.WatchForStall(40*time.Millisecond, func() { close(stalled) })
+	select {
+	case <-stalled:
+	case <-time.After(time.Second):
+		t.Fatal("stall was not detected")
+	}
+	close(monitor.stopSignal)
+	monitor.waitGroup.Wait()
+
+	// progress keeps advancing
+	monitor = newMonitor()
+	monitor.WatchForStall(80*time.Millisecond, func() { t.Error("stall detected while progressing") })
+	for outTime := int64(0); outTime < 10; outTime++ {
+		monitor._progressed(outTime * 1000)
+		time.Sleep(20 * time.Millisecond)
+	}
+	close(monitor.stopSignal)
+	monitor.waitGroup.Wait()
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"openseawave.com/rasbora/internal/health"
+	"openseawave.com/rasbora/internal/tracing"
+)
+
+// _startTrace continue trace carried inside current task, time waited in queue is recorded as its own span.
+func (fte *FfmpegTranscoderEngine) _startTrace() {
+	ctx := tracing.Extract(context.Background(), fte._queueable.TraceContext)
+
+	if fte._queueable.EnqueuedAt > 0 {
+		_, wait := tracing.Tracer().Start(ctx, "queue.wait", trace.WithTimestamp(time.UnixMilli(fte._queueable.EnqueuedAt)))
+		wait.End()
+	}
+
+	fte._traceContext, fte._span = tracing.Tracer().Start(ctx, "task.process", trace.WithAttributes(
+		attribute.String("task.id", fte._queueable.ID),
+		attribute.String("video_transcoder_worker_id", fte._videoTranscoderWorkerID),
+	))
+}
+
+// _stage run stage of current task inside its own span.
+func (fte *FfmpegTranscoderEngine) _stage(name string, run func() error) (err error) {
+	_, span := tracing.Tracer().Start(fte._traceContext, name)
+	defer func() { tracing.End(span, err) }()
+
+	// long stages keep the loop alive, ffmpeg progress beats during transcode.
+	health.Beat(Name)
+	defer health.Beat(Name)
+
+	return run()
+}
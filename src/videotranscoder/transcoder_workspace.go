@@ -0,0 +1,77 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// _unsafeWorkspaceChars characters replaced in workspace directory names.
+var _unsafeWorkspaceChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// workerWorkspaces return directory holding workspaces of worker inside base working path.
+// Base working path can be shared by many workers, so each worker only creates and sweeps workspaces inside its own directory.
+func workerWorkspaces(base string, workerId string) string {
+	return filepath.Join(base, _unsafeWorkspaceChars.ReplaceAllString(workerId, "_"))
+}
+
+// workspacePath return unique workspace directory of task attempt inside workspaces of worker.
+func workspacePath(base string, workerId string, taskId string, attempt int) string {
+	return filepath.Join(workerWorkspaces(base, workerId), fmt.Sprintf("%v_attempt%d", _unsafeWorkspaceChars.ReplaceAllString(taskId, "_"), attempt))
+}
+
+// sweepStaleWorkspaces remove workspace directories of worker not modified since older than, they are left by crashed runs of worker.
+// Workspaces of other workers sharing base working path are never touched.
+func sweepStaleWorkspaces(base string, workerId string, olderThan time.Duration) (removed []string, err error) {
+	base = workerWorkspaces(base, workerId)
+
+	entries, err := os.ReadDir(base)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		if time.Since(info.ModTime()) < olderThan {
+			continue
+		}
+
+		workspace := filepath.Join(base, entry.Name())
+		if err := os.RemoveAll(workspace); err != nil {
+			return removed, err
+		}
+		removed = append(removed, workspace)
+	}
+
+	return removed, nil
+}
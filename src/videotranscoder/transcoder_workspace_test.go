@@ -0,0 +1,65 @@
+// Copyright (c) 2022-2023 https://rasbora.openseawave.com
+//
+// This file is part of Rasbora Distributed Video Transcoding
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package videotranscoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspacePath(t *testing.T) {
+	assert.Equal(t, filepath.Join("/tmp/rasbora-workspace", "worker-1", "task-1_attempt2"), workspacePath("/tmp/rasbora-workspace/", "worker-1", "task-1", 2))
+	assert.Equal(t, filepath.Join("/tmp/rasbora-workspace", ".._worker", ".._etc_attempt0"), workspacePath("/tmp/rasbora-workspace/", "../worker", "../etc", 0))
+}
+
+func TestSweepStaleWorkspaces(t *testing.T) {
+	base := t.TempDir()
+
+	stale := workspacePath(base, "worker", "stale", 0)
+	fresh := workspacePath(base, "worker", "fresh", 0)
+	other := workspacePath(base, "other", "long", 0)
+	file := filepath.Join(workerWorkspaces(base, "worker"), "file")
+	for _, dir := range []string{stale, fresh, other} {
+		assert.NoError(t, os.MkdirAll(dir, os.ModePerm))
+	}
+	assert.NoError(t, os.WriteFile(file, nil, os.ModePerm))
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, path := range []string{stale, other, file} {
+		assert.NoError(t, os.Chtimes(path, old, old))
+	}
+
+	removed, err := sweepStaleWorkspaces(base, "worker", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{stale}, removed)
+
+	assert.NoDirExists(t, stale)
+	assert.DirExists(t, fresh)
+	assert.FileExists(t, file)
+
+	// workspace of another worker sharing base working path is kept however old it is.
+	assert.DirExists(t, other)
+
+	removed, err = sweepStaleWorkspaces(base, "missing", time.Hour)
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+}